@@ -17,6 +17,9 @@ func main() {
 		log.Fatalf("Failed to initialize server: %v", err)
 	}
 	defer server.DB.Close()
+	defer server.Fetcher.Stop()
+	defer server.Scheduler.Stop()
+	defer server.WebSub.Stop()
 
 	server.InitializeRoutes()
 
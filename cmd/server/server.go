@@ -8,17 +8,36 @@ import (
 	"github.com/matheusvidal21/smart-news-fetcher/internal/auth"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/di"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/email"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/fetcher"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/infra/handler"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/infra/service"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/middleware"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/scheduler"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/syndication"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/websub"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Server struct {
 	Config        *configs.Conf
 	DB            *sql.DB
 	JWTService    auth.JWTServiceInterface
+	Sessions      interfaces.SessionServiceInterface
+	Accounts      interfaces.AccountServiceInterface
+	APIKeys       interfaces.APIKeyServiceInterface
+	TwoFactor     interfaces.TwoFactorServiceInterface
+	LoginThrottle interfaces.LoginThrottleInterface
 	EmailService  interfaces.EmailService
+	Fetcher       interfaces.FetcherInterface
+	Scheduler     interfaces.SchedulerInterface
+	WebSub        interfaces.WebSubSubscriberInterface
 	SourceService interfaces.SourceServiceInterface
+	Articles      interfaces.ArticleServiceInterface
+	SavedSearches interfaces.SavedSearchServiceInterface
 	Router        *gin.Engine
 }
 
@@ -34,63 +53,246 @@ func NewServer() (*Server, error) {
 	}
 
 	jwtExpiration, _ := strconv.Atoi(conf.JWTExpirationMinutes)
+	refreshExpiration, _ := strconv.Atoi(conf.RefreshTokenExpirationDays)
+	loginMaxAttempts, _ := strconv.Atoi(conf.LoginMaxAttempts)
+	loginIPMaxAttempts, _ := strconv.Atoi(conf.LoginIPMaxAttempts)
+	loginLockout, _ := strconv.Atoi(conf.LoginLockoutMinutes)
 	smtpPort, _ := strconv.Atoi(conf.SMTP_PORT)
+	schedulerWorkers, _ := strconv.Atoi(conf.SchedulerWorkers)
+	fetchFailureLimit, _ := strconv.Atoi(conf.FetchFailureLimit)
+	fetchBackoffBase, _ := strconv.Atoi(conf.FetchBackoffBase)
+	fetchBackoffMax, _ := strconv.Atoi(conf.FetchBackoffMax)
+	fetchHostRate, _ := strconv.Atoi(conf.FetchHostRate)
+	fetchHostBurst, _ := strconv.Atoi(conf.FetchHostBurst)
+	fetchHostMaxInFlight, _ := strconv.Atoi(conf.FetchHostMaxInFlight)
+	fetchConnectTimeout, _ := strconv.Atoi(conf.FetchConnectTimeout)
+	fetchReadTimeout, _ := strconv.Atoi(conf.FetchReadTimeout)
+	fetchMaxBodyBytes, _ := strconv.ParseInt(conf.FetchMaxBodyBytes, 10, 64)
+	fetchMaxRedirects, _ := strconv.Atoi(conf.FetchMaxRedirects)
+	webSubLeaseSeconds, _ := strconv.Atoi(conf.WebSubLeaseSeconds)
 	jwtService := auth.NewJWTService(conf.JWTSecretKey, jwtExpiration)
+	sessionService := di.NewSessionService(db, jwtService, service.SessionOptions{
+		RefreshTokenTTL: time.Duration(refreshExpiration) * 24 * time.Hour,
+	})
+	apiKeyService := di.NewAPIKeyService(db)
+	twoFactorService := di.NewTwoFactorService(db, jwtService)
 	emailService := email.NewEmailService(conf.SMTP_HOST, smtpPort, conf.SMTP_USER, conf.SMTP_PASSWORD, conf.SMTP_FROM_EMAIL)
-	sourceService := di.NewSourceService(db, jwtService, emailService)
+	loginThrottle := di.NewLoginThrottle(db, emailService, service.LoginThrottleOptions{
+		MaxAttempts:   loginMaxAttempts,
+		IPMaxAttempts: loginIPMaxAttempts,
+		Lockout:       time.Duration(loginLockout) * time.Minute,
+	})
+	accountService := di.NewAccountService(db, jwtService, emailService, service.AccountOptions{
+		BaseURL: conf.AppBaseURL,
+	})
+	fetcherService, err := di.NewFetcher(db, fetcher.Options{
+		HostRequestsPerMinute: fetchHostRate,
+		HostBurst:             fetchHostBurst,
+		HostMaxInFlight:       fetchHostMaxInFlight,
+		ConnectTimeout:        time.Duration(fetchConnectTimeout) * time.Second,
+		ReadTimeout:           time.Duration(fetchReadTimeout) * time.Second,
+		UserAgent:             conf.FetchUserAgent,
+		ProxyURL:              conf.FetchProxyURL,
+		MaxBodyBytes:          fetchMaxBodyBytes,
+		MaxRedirects:          fetchMaxRedirects,
+		CABundlePath:          conf.FetchCABundle,
+	})
+	if err != nil {
+		return nil, errors.New("failed to initialize fetcher: " + err.Error())
+	}
+	webSubSubscriber := di.NewWebSubSubscriber(db, fetcherService, websub.Options{
+		CallbackBaseURL: conf.WebSubCallbackBaseURL,
+		LeaseDuration:   time.Duration(webSubLeaseSeconds) * time.Second,
+	})
+	fetcherService.SetHubObserver(webSubSubscriber)
+	feedScheduler := di.NewScheduler(db, emailService, fetcherService, webSubSubscriber, scheduler.Options{
+		Workers:          schedulerWorkers,
+		FailureThreshold: fetchFailureLimit,
+		BackoffBase:      time.Duration(fetchBackoffBase) * time.Minute,
+		BackoffMax:       time.Duration(fetchBackoffMax) * time.Minute,
+	})
+	sourceService := di.NewSourceService(db, emailService, fetcherService, feedScheduler)
+	savedSearchService := di.NewSavedSearchService(db, emailService)
 
 	router := gin.Default()
+	err = router.SetTrustedProxies(trustedProxies(conf.TrustedProxies))
+	if err != nil {
+		return nil, errors.New("failed to set trusted proxies: " + err.Error())
+	}
 	server := &Server{
 		Config:        conf,
 		DB:            db,
 		JWTService:    jwtService,
+		Sessions:      sessionService,
+		Accounts:      accountService,
+		APIKeys:       apiKeyService,
+		TwoFactor:     twoFactorService,
+		LoginThrottle: loginThrottle,
 		EmailService:  emailService,
+		Fetcher:       fetcherService,
+		Scheduler:     feedScheduler,
+		WebSub:        webSubSubscriber,
 		SourceService: sourceService,
+		Articles:      di.NewArticleService(db),
+		SavedSearches: savedSearchService,
 		Router:        router,
 	}
 	return server, nil
 }
 
-func (s *Server) InitializeRoutes() {
-	articleHandler := di.NewArticleHandler(s.DB)
-	sourceHandler := di.NewSourceHandler(s.DB, s.JWTService, s.EmailService)
-	userHandler := di.NewUserHandler(s.DB, s.JWTService, s.EmailService)
+// routeHandlers are the handlers the routes are registered with.
+type routeHandlers struct {
+	articles      *handler.ArticleHandler
+	sources       *handler.SourceHandler
+	users         *handler.UserHandler
+	savedSearches *handler.SavedSearchHandler
+	feeds         *handler.FeedHandler
+	webSub        *handler.WebSubHandler
+	admin         *handler.AdminHandler
+	apiKeys       *handler.APIKeyHandler
+	twoFactor     *handler.TwoFactorHandler
+}
 
+func (s *Server) InitializeRoutes() {
 	s.SourceService.InitializeSubscription()
+	s.SourceService.InitializeScheduler()
+	s.SavedSearches.StartDigest()
+	go s.Articles.BackfillPlainText()
+	s.Fetcher.Start()
+	s.Scheduler.Start()
+	s.WebSub.Start()
+
+	s.registerRoutes(routeHandlers{
+		articles:      di.NewArticleHandler(s.DB, s.Fetcher),
+		sources:       di.NewSourceHandler(s.DB, s.EmailService, s.Fetcher, s.Scheduler),
+		users:         di.NewUserHandler(s.DB, s.EmailService, s.Sessions, s.Accounts, s.TwoFactor, s.LoginThrottle),
+		savedSearches: di.NewSavedSearchHandler(s.DB, s.EmailService),
+		feeds:         di.NewFeedHandler(s.DB),
+		webSub:        di.NewWebSubHandler(s.WebSub),
+		admin:         di.NewAdminHandler(s.DB, s.Scheduler),
+		apiKeys:       di.NewAPIKeyHandler(s.APIKeys),
+		twoFactor:     di.NewTwoFactorHandler(s.TwoFactor, s.Sessions),
+	})
+}
 
+func (s *Server) registerRoutes(handlers routeHandlers) {
+	articleHandler := handlers.articles
+	sourceHandler := handlers.sources
+	userHandler := handlers.users
+	savedSearchHandler := handlers.savedSearches
+	feedHandler := handlers.feeds
+	webSubHandler := handlers.webSub
+	adminHandler := handlers.admin
+	apiKeyHandler := handlers.apiKeys
+	twoFactorHandler := handlers.twoFactor
+	authMiddleware := middleware.AuthMiddleware(s.JWTService, s.Sessions, s.APIKeys)
+	readArticles := middleware.RequireScope(models.ScopeArticlesRead)
+	manageSources := middleware.RequireScope(models.ScopeSourcesManage)
+	fullAccess := middleware.RequireScope()
+	sessionOnly := middleware.RequireSession()
+
+	// API keys limited by scopes only reach the routes of their scopes, and
+	// no API key can manage the account or reach the administration.
 	articles := s.Router.Group("/articles")
 	{
-		articles.Use(middleware.AuthMiddleware(s.JWTService))
-		articles.GET("/", articleHandler.FindAll)
-		articles.GET("/:id", articleHandler.FindOne)
-		articles.POST("/", articleHandler.Create)
-		articles.PUT("/:id", articleHandler.Update)
-		articles.DELETE("/:id", articleHandler.Delete)
-		articles.GET("/source/:id", articleHandler.FindBySourceID)
+		articles.Use(authMiddleware)
+		articles.GET("/", readArticles, articleHandler.FindAll)
+		articles.GET("/search", readArticles, articleHandler.Search)
+		articles.POST("/mark_read", fullAccess, articleHandler.MarkRead)
+		articles.GET("/:id", readArticles, articleHandler.FindOne)
+		articles.GET("/:id/revisions", readArticles, articleHandler.FindRevisions)
+		articles.GET("/:id/state", readArticles, articleHandler.FindState)
+		articles.PUT("/:id/state", fullAccess, articleHandler.UpdateState)
+		articles.POST("/:id/extract", fullAccess, articleHandler.Extract)
+		articles.POST("/", fullAccess, articleHandler.Create)
+		articles.PUT("/:id", fullAccess, articleHandler.Update)
+		articles.DELETE("/:id", fullAccess, articleHandler.Delete)
+		articles.GET("/source/:id", readArticles, articleHandler.FindBySourceID)
 	}
 
 	sources := s.Router.Group("/sources")
 	{
-		sources.Use(middleware.AuthMiddleware(s.JWTService))
+		sources.Use(authMiddleware, manageSources)
 		sources.GET("/", sourceHandler.FindAll)
+		sources.GET("/discover", sourceHandler.Discover)
+		sources.POST("/import/opml", sourceHandler.ImportOPML)
+		sources.GET("/export/opml", sourceHandler.ExportOPML)
 		sources.GET("/:id", sourceHandler.FindOne)
 		sources.POST("/", sourceHandler.Create)
 		sources.PUT("/:id", sourceHandler.Update)
 		sources.DELETE("/:id", sourceHandler.Delete)
 		sources.GET("/load_feed/:id", sourceHandler.LoadFeed)
+		sources.GET("/:id/fetch_runs", sourceHandler.FindFetchRuns)
+		sources.POST("/:id/resume", sourceHandler.Resume)
 		sources.GET("/find_by_user/:id", sourceHandler.FindByUserId)
 		sources.GET("/subscribe/:id", sourceHandler.SubscribeToNewsletter)
 		sources.GET("/unsubscribe/:id", sourceHandler.UnsubscribeFromNewsletter)
 	}
 
+	savedSearches := s.Router.Group("/saved_searches")
+	{
+		savedSearches.Use(authMiddleware, fullAccess)
+		savedSearches.GET("/", savedSearchHandler.FindAll)
+		savedSearches.GET("/:id", savedSearchHandler.FindOne)
+		savedSearches.GET("/:id/results", savedSearchHandler.Results)
+		savedSearches.POST("/", savedSearchHandler.Create)
+		savedSearches.PUT("/:id", savedSearchHandler.Update)
+		savedSearches.DELETE("/:id", savedSearchHandler.Delete)
+	}
+
 	users := s.Router.Group("/users")
 	{
 		users.POST("/", userHandler.CreateUser)
-		users.GET("/find_by_email/:email", userHandler.FindByEmail)
-		users.GET("/:id", userHandler.FindById)
-		users.DELETE("/:email", userHandler.DeleteUser)
+		users.GET("/find_by_email/:email", authMiddleware, fullAccess, userHandler.FindByEmail)
+		users.GET("/:id", authMiddleware, fullAccess, userHandler.FindById)
+		users.DELETE("/:email", authMiddleware, sessionOnly, userHandler.DeleteUser)
 		users.POST("/login", userHandler.Login)
-		users.POST("/update_password", userHandler.UpdatePassword)
+		users.POST("/login/2fa", twoFactorHandler.Login)
+		users.POST("/refresh", userHandler.Refresh)
+		users.POST("/logout", authMiddleware, sessionOnly, userHandler.Logout)
+		users.POST("/update_password", authMiddleware, sessionOnly, userHandler.UpdatePassword)
+		users.POST("/password_reset/request", userHandler.RequestPasswordReset)
+		users.POST("/password_reset/confirm", userHandler.ConfirmPasswordReset)
+		users.GET("/verify_email", userHandler.VerifyEmail)
+		users.POST("/me/verify_email", authMiddleware, sessionOnly, userHandler.ResendVerification)
+		users.GET("/me/feed_token", authMiddleware, sessionOnly, userHandler.FeedToken)
+		users.POST("/me/feed_token", authMiddleware, sessionOnly, userHandler.RegenerateFeedToken)
+		users.GET("/me/sessions", authMiddleware, sessionOnly, userHandler.FindSessions)
+		users.DELETE("/me/sessions/:id", authMiddleware, sessionOnly, userHandler.RevokeSession)
+		users.GET("/me/api_keys", authMiddleware, sessionOnly, apiKeyHandler.FindAll)
+		users.POST("/me/api_keys", authMiddleware, sessionOnly, apiKeyHandler.Create)
+		users.DELETE("/me/api_keys/:id", authMiddleware, sessionOnly, apiKeyHandler.Revoke)
+		users.POST("/me/2fa/enroll", authMiddleware, sessionOnly, twoFactorHandler.Enroll)
+		users.POST("/me/2fa/confirm", authMiddleware, sessionOnly, twoFactorHandler.Confirm)
+		users.POST("/me/2fa/disable", authMiddleware, sessionOnly, twoFactorHandler.Disable)
+		users.POST("/me/2fa/recovery_codes", authMiddleware, sessionOnly, twoFactorHandler.RegenerateRecoveryCodes)
+	}
+
+	admin := s.Router.Group("/admin")
+	{
+		admin.Use(authMiddleware, sessionOnly, middleware.RequireRole(models.RoleAdmin))
+		admin.GET("/users", adminHandler.FindUsers)
+		admin.PUT("/users/:id/role", adminHandler.UpdateUserRole)
+		admin.POST("/users/:id/disable", adminHandler.DisableUser)
+		admin.POST("/users/:id/enable", adminHandler.EnableUser)
+		admin.POST("/users/:id/2fa/reset", adminHandler.ResetTwoFactor)
+		admin.GET("/sources", adminHandler.FindSources)
+		admin.GET("/sources/health", adminHandler.FetchHealth)
+		admin.POST("/sources/:id/fetch", adminHandler.FetchSource)
+	}
+
+	webSub := s.Router.Group("/websub")
+	{
+		webSub.GET("/callback/:id/:token", webSubHandler.Verify)
+		webSub.POST("/callback/:id/:token", webSubHandler.Receive)
+	}
+
+	// Published feeds are authorized by the feed token in the query string so
+	// readers without JWT support can subscribe to them.
+	for _, format := range []string{syndication.FormatRSS, syndication.FormatAtom, syndication.FormatJSON} {
+		s.Router.GET("/users/:id/feed."+format, feedHandler.UserFeed)
+		s.Router.GET("/sources/:id/feed."+format, feedHandler.SourceFeed)
+		s.Router.GET("/saved_searches/:id/feed."+format, feedHandler.SavedSearchFeed)
 	}
 }
 
@@ -98,6 +300,18 @@ func (s *Server) Run() error {
 	return s.Router.Run(s.Config.WebServerPort)
 }
 
+// trustedProxies lists the proxies whose X-Forwarded-For header is believed.
+// None are trusted by default, so the client IP is the one of the connection.
+func trustedProxies(value string) []string {
+	var proxies []string
+	for _, proxy := range strings.Split(value, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
 func initDB(driver, source string) (*sql.DB, error) {
 	db, err := sql.Open(driver, source)
 	if err != nil {
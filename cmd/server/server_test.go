@@ -0,0 +1,269 @@
+package main
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/auth"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/infra/handler"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/infra/service"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	ownerID     = 1
+	strangerID  = 2
+	ownerEmail  = "owner@example.com"
+	ownSourceID = "10"
+	ownArticle  = "article-1"
+)
+
+var errNotFound = errors.New("not found")
+
+type stubUserRepository struct {
+	interfaces.UserRepositoryInterface
+	users   map[int]models.User
+	deleted []string
+}
+
+func (r *stubUserRepository) FindById(id int) (*models.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, errNotFound
+	}
+	return &user, nil
+}
+
+func (r *stubUserRepository) FindByEmail(email string) (*models.User, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, errNotFound
+}
+
+func (r *stubUserRepository) Delete(email string) error {
+	r.deleted = append(r.deleted, email)
+	return nil
+}
+
+type stubSourceRepository struct {
+	interfaces.SourceRepositoryInterface
+	sources map[int]models.Source
+	deleted []int
+	updated []int
+}
+
+func (r *stubSourceRepository) FindOne(id int) (models.Source, error) {
+	source, ok := r.sources[id]
+	if !ok {
+		return models.Source{}, errNotFound
+	}
+	return source, nil
+}
+
+func (r *stubSourceRepository) FindByUserId(userID int) ([]models.Source, error) {
+	var sources []models.Source
+	for _, source := range r.sources {
+		if source.UserID == userID {
+			sources = append(sources, source)
+		}
+	}
+	return sources, nil
+}
+
+func (r *stubSourceRepository) Update(id int, source models.Source) (models.Source, error) {
+	r.updated = append(r.updated, id)
+	return source, nil
+}
+
+func (r *stubSourceRepository) Delete(id int) error {
+	r.deleted = append(r.deleted, id)
+	return nil
+}
+
+type stubArticleRepository struct {
+	interfaces.ArticleRepositoryInterface
+	articles map[string]models.Article
+	deleted  []string
+}
+
+func (r *stubArticleRepository) FindOne(id string) (models.Article, error) {
+	article, ok := r.articles[id]
+	if !ok {
+		return models.Article{}, errNotFound
+	}
+	return article, nil
+}
+
+func (r *stubArticleRepository) Delete(id string) error {
+	r.deleted = append(r.deleted, id)
+	return nil
+}
+
+type stubStateRepository struct {
+	interfaces.ArticleStateRepositoryInterface
+}
+
+func (stubStateRepository) CountUnread(userID int, sourceIDs []int) (map[int]int, error) {
+	return map[int]int{}, nil
+}
+
+type openSessions struct {
+	interfaces.SessionServiceInterface
+}
+
+func (openSessions) Validate(identity auth.Identity) error {
+	return nil
+}
+
+type noAPIKeys struct {
+	interfaces.APIKeyServiceInterface
+}
+
+func (noAPIKeys) Authenticate(key string) (auth.Identity, []string, error) {
+	return auth.Identity{}, nil, models.ErrInvalidAPIKey
+}
+
+type routeFixture struct {
+	router   *gin.Engine
+	jwt      *auth.JWTService
+	users    *stubUserRepository
+	sources  *stubSourceRepository
+	articles *stubArticleRepository
+}
+
+// newRouteFixture registers the routes of the server over repositories holding
+// a source and an article of the owner, with the services doing the ownership
+// checks.
+func newRouteFixture() *routeFixture {
+	gin.SetMode(gin.TestMode)
+	fixture := &routeFixture{
+		jwt: auth.NewJWTService("test-secret", 15),
+		users: &stubUserRepository{users: map[int]models.User{
+			ownerID:    {ID: ownerID, Email: ownerEmail, Role: models.RoleUser},
+			strangerID: {ID: strangerID, Email: "stranger@example.com", Role: models.RoleUser},
+		}},
+		sources: &stubSourceRepository{sources: map[int]models.Source{
+			10: {ID: 10, UserID: ownerID, Name: "Blog", Url: "https://blog.example.com/feed.xml", UpdateInterval: 1},
+		}},
+		articles: &stubArticleRepository{articles: map[string]models.Article{
+			ownArticle: {ID: ownArticle, SourceID: 10, Title: "Post"},
+		}},
+	}
+
+	userService := service.NewUserService(fixture.users, nil)
+	sourceService := service.NewSourceService(fixture.sources, nil, stubStateRepository{}, userService, nil, nil, nil)
+	articleService := service.NewArticleService(fixture.articles, nil, nil, fixture.sources)
+
+	server := &Server{
+		JWTService: fixture.jwt,
+		Sessions:   openSessions{},
+		APIKeys:    noAPIKeys{},
+		Router:     gin.New(),
+	}
+	server.registerRoutes(routeHandlers{
+		articles: handler.NewArticleHandler(articleService, nil),
+		sources:  handler.NewSourceHandler(sourceService),
+		users:    handler.NewUserHandler(userService, nil, nil, nil, nil),
+	})
+	fixture.router = server.Router
+	return fixture
+}
+
+func (f *routeFixture) request(t *testing.T, userID int, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	token, err := f.jwt.GenerateToken(auth.Identity{UserID: userID, Role: models.RoleUser, SessionID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	f.router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestRoutesHideResourcesOfOtherUsers(t *testing.T) {
+	sourceBody := `{"name":"Mine now","url":"https://blog.example.com/feed.xml","update_interval":1}`
+	articleBody := `{"id":"` + ownArticle + `","title":"Mine now","source_id":10}`
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{http.MethodGet, "/sources/" + ownSourceID, "", http.StatusNotFound},
+		{http.MethodPut, "/sources/" + ownSourceID, sourceBody, http.StatusNotFound},
+		{http.MethodDelete, "/sources/" + ownSourceID, "", http.StatusNotFound},
+		{http.MethodPost, "/sources/" + ownSourceID + "/resume", "", http.StatusNotFound},
+		{http.MethodGet, "/sources/load_feed/" + ownSourceID, "", http.StatusNotFound},
+		{http.MethodGet, "/sources/subscribe/" + ownSourceID, "", http.StatusNotFound},
+		{http.MethodGet, "/sources/find_by_user/1", "", http.StatusForbidden},
+		{http.MethodGet, "/articles/" + ownArticle, "", http.StatusNotFound},
+		{http.MethodPut, "/articles/" + ownArticle, articleBody, http.StatusNotFound},
+		{http.MethodDelete, "/articles/" + ownArticle, "", http.StatusNotFound},
+		{http.MethodGet, "/articles/" + ownArticle + "/revisions", "", http.StatusNotFound},
+		{http.MethodGet, "/users/1", "", http.StatusForbidden},
+		{http.MethodGet, "/users/find_by_email/" + ownerEmail, "", http.StatusForbidden},
+		{http.MethodDelete, "/users/" + ownerEmail, "", http.StatusForbidden},
+	}
+
+	fixture := newRouteFixture()
+	for _, test := range tests {
+		t.Run(test.method+" "+test.path, func(t *testing.T) {
+			recorder := fixture.request(t, strangerID, test.method, test.path, test.body)
+			if recorder.Code != test.want {
+				t.Errorf("status = %d, want %d: %s", recorder.Code, test.want, recorder.Body.String())
+			}
+		})
+	}
+
+	if len(fixture.users.deleted) != 0 || len(fixture.sources.deleted) != 0 || len(fixture.sources.updated) != 0 || len(fixture.articles.deleted) != 0 {
+		t.Errorf("resources of the owner were changed: users %v, sources deleted %v updated %v, articles %v",
+			fixture.users.deleted, fixture.sources.deleted, fixture.sources.updated, fixture.articles.deleted)
+	}
+}
+
+func TestRoutesServeResourcesOfTheOwner(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/sources/" + ownSourceID},
+		{http.MethodGet, "/sources/find_by_user/1"},
+		{http.MethodGet, "/articles/" + ownArticle},
+		{http.MethodGet, "/users/1"},
+		{http.MethodGet, "/users/find_by_email/" + ownerEmail},
+	}
+
+	fixture := newRouteFixture()
+	for _, test := range tests {
+		t.Run(test.method+" "+test.path, func(t *testing.T) {
+			recorder := fixture.request(t, ownerID, test.method, test.path, "")
+			if recorder.Code != http.StatusOK {
+				t.Errorf("status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestRoutesRequireAuthentication(t *testing.T) {
+	fixture := newRouteFixture()
+	for _, path := range []string{"/sources/" + ownSourceID, "/articles/" + ownArticle, "/users/1"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		recorder := httptest.NewRecorder()
+		fixture.router.ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusUnauthorized {
+			t.Errorf("GET %s: status = %d, want %d", path, recorder.Code, http.StatusUnauthorized)
+		}
+	}
+}
@@ -7,21 +7,43 @@ import (
 var cfg *Conf
 
 type Conf struct {
-	DBDriver             string `mapstructure:"DB_DRIVER"`
-	DBHost               string `mapstructure:"DB_HOST"`
-	DBPort               string `mapstructure:"DB_PORT"`
-	DBUser               string `mapstructure:"DB_USER"`
-	DBPassword           string `mapstructure:"DB_PASSWORD"`
-	DBSource             string `mapstructure:"DB_SOURCE"`
-	DBName               string `mapstructure:"DB_NAME"`
-	WebServerPort        string `mapstructure:"WEB_SERVER_PORT"`
-	JWTSecretKey         string `mapstructure:"JWT_SECRET_KEY"`
-	JWTExpirationMinutes string `mapstructure:"JWT_EXPIRATION_MINUTES"`
-	SMTP_HOST            string `mapstructure:"SMTP_HOST"`
-	SMTP_PORT            string `mapstructure:"SMTP_PORT"`
-	SMTP_USER            string `mapstructure:"SMTP_USER"`
-	SMTP_PASSWORD        string `mapstructure:"SMTP_PASSWORD"`
-	SMTP_FROM_EMAIL      string `mapstructure:"SMTP_FROM_EMAIL"`
+	DBDriver                   string `mapstructure:"DB_DRIVER"`
+	DBHost                     string `mapstructure:"DB_HOST"`
+	DBPort                     string `mapstructure:"DB_PORT"`
+	DBUser                     string `mapstructure:"DB_USER"`
+	DBPassword                 string `mapstructure:"DB_PASSWORD"`
+	DBSource                   string `mapstructure:"DB_SOURCE"`
+	DBName                     string `mapstructure:"DB_NAME"`
+	AppBaseURL                 string `mapstructure:"APP_BASE_URL"`
+	WebServerPort              string `mapstructure:"WEB_SERVER_PORT"`
+	TrustedProxies             string `mapstructure:"TRUSTED_PROXIES"`
+	JWTSecretKey               string `mapstructure:"JWT_SECRET_KEY"`
+	JWTExpirationMinutes       string `mapstructure:"JWT_EXPIRATION_MINUTES"`
+	RefreshTokenExpirationDays string `mapstructure:"REFRESH_TOKEN_EXPIRATION_DAYS"`
+	LoginMaxAttempts           string `mapstructure:"LOGIN_MAX_ATTEMPTS"`
+	LoginIPMaxAttempts         string `mapstructure:"LOGIN_IP_MAX_ATTEMPTS"`
+	LoginLockoutMinutes        string `mapstructure:"LOGIN_LOCKOUT_MINUTES"`
+	SMTP_HOST                  string `mapstructure:"SMTP_HOST"`
+	SMTP_PORT                  string `mapstructure:"SMTP_PORT"`
+	SMTP_USER                  string `mapstructure:"SMTP_USER"`
+	SMTP_PASSWORD              string `mapstructure:"SMTP_PASSWORD"`
+	SMTP_FROM_EMAIL            string `mapstructure:"SMTP_FROM_EMAIL"`
+	SchedulerWorkers           string `mapstructure:"SCHEDULER_WORKERS"`
+	FetchFailureLimit          string `mapstructure:"FETCH_FAILURE_THRESHOLD"`
+	FetchBackoffBase           string `mapstructure:"FETCH_BACKOFF_BASE_MINUTES"`
+	FetchBackoffMax            string `mapstructure:"FETCH_BACKOFF_MAX_MINUTES"`
+	FetchHostRate              string `mapstructure:"FETCH_HOST_REQUESTS_PER_MINUTE"`
+	FetchHostBurst             string `mapstructure:"FETCH_HOST_BURST"`
+	FetchHostMaxInFlight       string `mapstructure:"FETCH_HOST_MAX_IN_FLIGHT"`
+	FetchConnectTimeout        string `mapstructure:"FETCH_CONNECT_TIMEOUT_SECONDS"`
+	FetchReadTimeout           string `mapstructure:"FETCH_READ_TIMEOUT_SECONDS"`
+	FetchUserAgent             string `mapstructure:"FETCH_USER_AGENT"`
+	FetchProxyURL              string `mapstructure:"FETCH_PROXY_URL"`
+	FetchMaxBodyBytes          string `mapstructure:"FETCH_MAX_BODY_BYTES"`
+	FetchMaxRedirects          string `mapstructure:"FETCH_MAX_REDIRECTS"`
+	FetchCABundle              string `mapstructure:"FETCH_CA_BUNDLE"`
+	WebSubCallbackBaseURL      string `mapstructure:"WEBSUB_CALLBACK_BASE_URL"`
+	WebSubLeaseSeconds         string `mapstructure:"WEBSUB_LEASE_SECONDS"`
 }
 
 func LoadConfigs(path string) *Conf {
@@ -7,12 +7,39 @@ import (
 )
 
 type JWTServiceInterface interface {
-	GenerateToken(email string) (string, error)
+	GenerateToken(identity Identity) (string, error)
 	ValidateToken(encodedToken string) (*jwt.Token, error)
+	GenerateActionToken(action Action, ttl time.Duration) (string, error)
+	ValidateActionToken(encodedToken, purpose string) (Action, error)
+}
+
+// Identity is what an access token says about its bearer: the user, their
+// role, and the session and token version it was issued for.
+type Identity struct {
+	UserID       int    `json:"user_id"`
+	Email        string `json:"email"`
+	Role         string `json:"role"`
+	SessionID    int    `json:"sid"`
+	TokenVersion int    `json:"ver"`
 }
 
 type authCustomClaims struct {
-	Email string `json:"email"`
+	Identity
+	jwt.RegisteredClaims
+}
+
+// Action is what a token sent by email allows its bearer to do once, such as
+// resetting the password of a user. ID tells apart the tokens of a user so
+// each can be marked as used.
+type Action struct {
+	ID      string
+	UserID  int
+	Purpose string
+}
+
+type actionClaims struct {
+	UserID  int    `json:"user_id"`
+	Purpose string `json:"purpose"`
 	jwt.RegisteredClaims
 }
 
@@ -28,9 +55,9 @@ func NewJWTService(secretKey string, expirationMinutes int) *JWTService {
 	}
 }
 
-func (s *JWTService) GenerateToken(email string) (string, error) {
+func (s *JWTService) GenerateToken(identity Identity) (string, error) {
 	claims := &authCustomClaims{
-		Email: email,
+		Identity: identity,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(s.expirationMinutes) * time.Minute)),
 		},
@@ -52,3 +79,45 @@ func (s *JWTService) ValidateToken(encodedToken string) (*jwt.Token, error) {
 	}
 	return nil, err
 }
+
+// TokenIdentity returns the identity carried by a token returned from
+// ValidateToken.
+func TokenIdentity(token *jwt.Token) (Identity, error) {
+	claims, ok := token.Claims.(*authCustomClaims)
+	if !ok || claims.UserID == 0 || claims.SessionID == 0 {
+		return Identity{}, errors.New("token does not identify a user")
+	}
+	return claims.Identity, nil
+}
+
+func (s *JWTService) GenerateActionToken(action Action, ttl time.Duration) (string, error) {
+	claims := &actionClaims{
+		UserID:  action.UserID,
+		Purpose: action.Purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        action.ID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(s.secretKey))
+}
+
+// ValidateActionToken checks the signature and expiry of a token made by
+// GenerateActionToken for the purpose.
+func (s *JWTService) ValidateActionToken(encodedToken, purpose string) (Action, error) {
+	claims := &actionClaims{}
+	token, err := jwt.ParseWithClaims(encodedToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return []byte(s.secretKey), nil
+	})
+	if err != nil || !token.Valid {
+		return Action{}, errors.New("invalid or expired token")
+	}
+	if claims.Purpose != purpose || claims.UserID == 0 || claims.ID == "" {
+		return Action{}, errors.New("invalid or expired token")
+	}
+	return Action{ID: claims.ID, UserID: claims.UserID, Purpose: claims.Purpose}, nil
+}
@@ -12,11 +12,8 @@ import (
 	"github.com/matheusvidal21/smart-news-fetcher/internal/infra/handler"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/infra/service"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
-)
-
-var setFetcherDependecy = wire.NewSet(
-	fetcher.NewFetcher,
-	wire.Bind(new(interfaces.FetcherInterface), new(*fetcher.Fetcher)),
+	"github.com/matheusvidal21/smart-news-fetcher/internal/scheduler"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/websub"
 )
 
 var setSourceHandlerDependecy = wire.NewSet(
@@ -49,6 +46,21 @@ var setArticleRepositoryDependecy = wire.NewSet(
 	wire.Bind(new(interfaces.ArticleRepositoryInterface), new(*database.ArticleRepository)),
 )
 
+var setArticleRevisionRepositoryDependecy = wire.NewSet(
+	database.NewArticleRevisionRepository,
+	wire.Bind(new(interfaces.ArticleRevisionRepositoryInterface), new(*database.ArticleRevisionRepository)),
+)
+
+var setArticleStateRepositoryDependecy = wire.NewSet(
+	database.NewArticleStateRepository,
+	wire.Bind(new(interfaces.ArticleStateRepositoryInterface), new(*database.ArticleStateRepository)),
+)
+
+var setFetchRunRepositoryDependecy = wire.NewSet(
+	database.NewFetchRunRepository,
+	wire.Bind(new(interfaces.FetchRunRepositoryInterface), new(*database.FetchRunRepository)),
+)
+
 var setUserRepositoryDependecy = wire.NewSet(
 	database.NewUserRepository,
 	wire.Bind(new(interfaces.UserRepositoryInterface), new(*database.UserRepository)),
@@ -64,37 +76,186 @@ var setUserHandlerDependecy = wire.NewSet(
 	wire.Bind(new(interfaces.UserHandlerInterface), new(*handler.UserHandler)),
 )
 
-func NewArticleHandler(db *sql.DB) *handler.ArticleHandler {
+var setFeedServiceDependecy = wire.NewSet(
+	service.NewFeedService,
+	wire.Bind(new(interfaces.FeedServiceInterface), new(*service.FeedService)),
+)
+
+var setFeedHandlerDependecy = wire.NewSet(
+	handler.NewFeedHandler,
+	wire.Bind(new(interfaces.FeedHandlerInterface), new(*handler.FeedHandler)),
+)
+
+var setWebSubRepositoryDependecy = wire.NewSet(
+	database.NewWebSubRepository,
+	wire.Bind(new(interfaces.WebSubRepositoryInterface), new(*database.WebSubRepository)),
+)
+
+var setWebSubHandlerDependecy = wire.NewSet(
+	handler.NewWebSubHandler,
+	wire.Bind(new(interfaces.WebSubHandlerInterface), new(*handler.WebSubHandler)),
+)
+
+var setSavedSearchRepositoryDependecy = wire.NewSet(
+	database.NewSavedSearchRepository,
+	wire.Bind(new(interfaces.SavedSearchRepositoryInterface), new(*database.SavedSearchRepository)),
+)
+
+var setSavedSearchServiceDependecy = wire.NewSet(
+	service.NewSavedSearchService,
+	wire.Bind(new(interfaces.SavedSearchServiceInterface), new(*service.SavedSearchService)),
+)
+
+var setSavedSearchHandlerDependecy = wire.NewSet(
+	handler.NewSavedSearchHandler,
+	wire.Bind(new(interfaces.SavedSearchHandlerInterface), new(*handler.SavedSearchHandler)),
+)
+
+var setSessionRepositoryDependecy = wire.NewSet(
+	database.NewSessionRepository,
+	wire.Bind(new(interfaces.SessionRepositoryInterface), new(*database.SessionRepository)),
+)
+
+var setSessionServiceDependecy = wire.NewSet(
+	service.NewSessionService,
+	wire.Bind(new(interfaces.SessionServiceInterface), new(*service.SessionService)),
+)
+
+var setUserTokenRepositoryDependecy = wire.NewSet(
+	database.NewUserTokenRepository,
+	wire.Bind(new(interfaces.UserTokenRepositoryInterface), new(*database.UserTokenRepository)),
+)
+
+var setAccountServiceDependecy = wire.NewSet(
+	service.NewAccountService,
+	wire.Bind(new(interfaces.AccountServiceInterface), new(*service.AccountService)),
+)
+
+var setAdminServiceDependecy = wire.NewSet(
+	service.NewAdminService,
+	wire.Bind(new(interfaces.AdminServiceInterface), new(*service.AdminService)),
+)
+
+var setAdminHandlerDependecy = wire.NewSet(
+	handler.NewAdminHandler,
+	wire.Bind(new(interfaces.AdminHandlerInterface), new(*handler.AdminHandler)),
+)
+
+var setAPIKeyRepositoryDependecy = wire.NewSet(
+	database.NewAPIKeyRepository,
+	wire.Bind(new(interfaces.APIKeyRepositoryInterface), new(*database.APIKeyRepository)),
+)
+
+var setAPIKeyServiceDependecy = wire.NewSet(
+	service.NewAPIKeyService,
+	wire.Bind(new(interfaces.APIKeyServiceInterface), new(*service.APIKeyService)),
+)
+
+var setAPIKeyHandlerDependecy = wire.NewSet(
+	handler.NewAPIKeyHandler,
+	wire.Bind(new(interfaces.APIKeyHandlerInterface), new(*handler.APIKeyHandler)),
+)
+
+var setRecoveryCodeRepositoryDependecy = wire.NewSet(
+	database.NewRecoveryCodeRepository,
+	wire.Bind(new(interfaces.RecoveryCodeRepositoryInterface), new(*database.RecoveryCodeRepository)),
+)
+
+var setTwoFactorServiceDependecy = wire.NewSet(
+	service.NewTwoFactorService,
+	wire.Bind(new(interfaces.TwoFactorServiceInterface), new(*service.TwoFactorService)),
+)
+
+var setTwoFactorHandlerDependecy = wire.NewSet(
+	handler.NewTwoFactorHandler,
+	wire.Bind(new(interfaces.TwoFactorHandlerInterface), new(*handler.TwoFactorHandler)),
+)
+
+var setLoginThrottleDependecy = wire.NewSet(
+	service.NewLoginThrottle,
+	wire.Bind(new(interfaces.LoginThrottleInterface), new(*service.LoginThrottle)),
+)
+
+func NewArticleService(db *sql.DB) *service.ArticleService {
+	wire.Build(
+		setArticleRepositoryDependecy,
+		setArticleRevisionRepositoryDependecy,
+		setArticleStateRepositoryDependecy,
+		setSourceRepositoryDependecy,
+		setArticleServiceDependecy,
+	)
+	return &service.ArticleService{}
+}
+
+func NewArticleHandler(db *sql.DB, extractor interfaces.ArticleExtractorInterface) *handler.ArticleHandler {
 	wire.Build(
 		setArticleRepositoryDependecy,
+		setArticleRevisionRepositoryDependecy,
+		setArticleStateRepositoryDependecy,
+		setSourceRepositoryDependecy,
 		setArticleServiceDependecy,
 		setArticleHandlerDependecy,
 	)
 	return &handler.ArticleHandler{}
 }
 
-func NewSourceService(db *sql.DB, jwtService auth.JWTServiceInterface, emailService interfaces.EmailService) *service.SourceService {
+func NewFetcher(db *sql.DB, options fetcher.Options) (*fetcher.Fetcher, error) {
 	wire.Build(
-		setUserRepositoryDependecy,
-		setUserServiceDependecy,
 		setSourceRepositoryDependecy,
+		setFetchRunRepositoryDependecy,
 		setArticleRepositoryDependecy,
+		setArticleRevisionRepositoryDependecy,
+		setArticleStateRepositoryDependecy,
 		setArticleServiceDependecy,
-		setFetcherDependecy,
+		fetcher.NewFetcher,
+	)
+	return &fetcher.Fetcher{}, nil
+}
+
+func NewWebSubSubscriber(db *sql.DB, fetcher interfaces.FetcherInterface, options websub.Options) *websub.Subscriber {
+	wire.Build(
+		setWebSubRepositoryDependecy,
+		websub.NewSubscriber,
+	)
+	return &websub.Subscriber{}
+}
+
+func NewWebSubHandler(subscriber interfaces.WebSubSubscriberInterface) *handler.WebSubHandler {
+	wire.Build(
+		setWebSubHandlerDependecy,
+	)
+	return &handler.WebSubHandler{}
+}
+
+func NewScheduler(db *sql.DB, emailService interfaces.EmailService, fetcher interfaces.FetcherInterface, push interfaces.WebSubSubscriberInterface, options scheduler.Options) *scheduler.Scheduler {
+	wire.Build(
+		setSourceRepositoryDependecy,
+		setUserRepositoryDependecy,
+		scheduler.NewScheduler,
+	)
+	return &scheduler.Scheduler{}
+}
+
+func NewSourceService(db *sql.DB, emailService interfaces.EmailService, fetcher interfaces.FetcherInterface, scheduler interfaces.SchedulerInterface) *service.SourceService {
+	wire.Build(
+		setUserRepositoryDependecy,
+		setUserServiceDependecy,
+		setSourceRepositoryDependecy,
+		setFetchRunRepositoryDependecy,
+		setArticleStateRepositoryDependecy,
 		setSourceServiceDependecy,
 	)
 
 	return &service.SourceService{}
 }
 
-func NewSourceHandler(db *sql.DB, jwtService auth.JWTServiceInterface, emailService interfaces.EmailService) *handler.SourceHandler {
+func NewSourceHandler(db *sql.DB, emailService interfaces.EmailService, fetcher interfaces.FetcherInterface, scheduler interfaces.SchedulerInterface) *handler.SourceHandler {
 	wire.Build(
 		setUserRepositoryDependecy,
 		setUserServiceDependecy,
 		setSourceRepositoryDependecy,
-		setArticleRepositoryDependecy,
-		setArticleServiceDependecy,
-		setFetcherDependecy,
+		setFetchRunRepositoryDependecy,
+		setArticleStateRepositoryDependecy,
 		setSourceServiceDependecy,
 		setSourceHandlerDependecy,
 	)
@@ -102,7 +263,48 @@ func NewSourceHandler(db *sql.DB, jwtService auth.JWTServiceInterface, emailServ
 	return &handler.SourceHandler{}
 }
 
-func NewUserHandler(db *sql.DB, jwtService auth.JWTServiceInterface, emailService interfaces.EmailService) *handler.UserHandler {
+func NewFeedHandler(db *sql.DB) *handler.FeedHandler {
+	wire.Build(
+		setArticleRepositoryDependecy,
+		setSourceRepositoryDependecy,
+		setUserRepositoryDependecy,
+		setSavedSearchRepositoryDependecy,
+		setFeedServiceDependecy,
+		setFeedHandlerDependecy,
+	)
+	return &handler.FeedHandler{}
+}
+
+func NewSavedSearchService(db *sql.DB, emailService interfaces.EmailService) *service.SavedSearchService {
+	wire.Build(
+		setSavedSearchRepositoryDependecy,
+		setSourceRepositoryDependecy,
+		setUserRepositoryDependecy,
+		setArticleRepositoryDependecy,
+		setArticleRevisionRepositoryDependecy,
+		setArticleStateRepositoryDependecy,
+		setArticleServiceDependecy,
+		setSavedSearchServiceDependecy,
+	)
+	return &service.SavedSearchService{}
+}
+
+func NewSavedSearchHandler(db *sql.DB, emailService interfaces.EmailService) *handler.SavedSearchHandler {
+	wire.Build(
+		setSavedSearchRepositoryDependecy,
+		setSourceRepositoryDependecy,
+		setUserRepositoryDependecy,
+		setArticleRepositoryDependecy,
+		setArticleRevisionRepositoryDependecy,
+		setArticleStateRepositoryDependecy,
+		setArticleServiceDependecy,
+		setSavedSearchServiceDependecy,
+		setSavedSearchHandlerDependecy,
+	)
+	return &handler.SavedSearchHandler{}
+}
+
+func NewUserHandler(db *sql.DB, emailService interfaces.EmailService, sessions interfaces.SessionServiceInterface, accounts interfaces.AccountServiceInterface, twoFactor interfaces.TwoFactorServiceInterface, loginThrottle interfaces.LoginThrottleInterface) *handler.UserHandler {
 	wire.Build(
 		setUserRepositoryDependecy,
 		setUserServiceDependecy,
@@ -110,3 +312,73 @@ func NewUserHandler(db *sql.DB, jwtService auth.JWTServiceInterface, emailServic
 	)
 	return &handler.UserHandler{}
 }
+
+func NewSessionService(db *sql.DB, jwtService auth.JWTServiceInterface, options service.SessionOptions) *service.SessionService {
+	wire.Build(
+		setSessionRepositoryDependecy,
+		setUserRepositoryDependecy,
+		setSessionServiceDependecy,
+	)
+	return &service.SessionService{}
+}
+
+func NewAccountService(db *sql.DB, jwtService auth.JWTServiceInterface, emailService interfaces.EmailService, options service.AccountOptions) *service.AccountService {
+	wire.Build(
+		setUserRepositoryDependecy,
+		setUserTokenRepositoryDependecy,
+		setAccountServiceDependecy,
+	)
+	return &service.AccountService{}
+}
+
+func NewAdminHandler(db *sql.DB, scheduler interfaces.SchedulerInterface) *handler.AdminHandler {
+	wire.Build(
+		setUserRepositoryDependecy,
+		setRecoveryCodeRepositoryDependecy,
+		setSourceRepositoryDependecy,
+		setAdminServiceDependecy,
+		setAdminHandlerDependecy,
+	)
+	return &handler.AdminHandler{}
+}
+
+func NewAPIKeyService(db *sql.DB) *service.APIKeyService {
+	wire.Build(
+		setAPIKeyRepositoryDependecy,
+		setUserRepositoryDependecy,
+		setAPIKeyServiceDependecy,
+	)
+	return &service.APIKeyService{}
+}
+
+func NewAPIKeyHandler(apiKeys interfaces.APIKeyServiceInterface) *handler.APIKeyHandler {
+	wire.Build(
+		setAPIKeyHandlerDependecy,
+	)
+	return &handler.APIKeyHandler{}
+}
+
+func NewTwoFactorService(db *sql.DB, jwtService auth.JWTServiceInterface) *service.TwoFactorService {
+	wire.Build(
+		setUserRepositoryDependecy,
+		setUserTokenRepositoryDependecy,
+		setRecoveryCodeRepositoryDependecy,
+		setTwoFactorServiceDependecy,
+	)
+	return &service.TwoFactorService{}
+}
+
+func NewTwoFactorHandler(twoFactor interfaces.TwoFactorServiceInterface, sessions interfaces.SessionServiceInterface) *handler.TwoFactorHandler {
+	wire.Build(
+		setTwoFactorHandlerDependecy,
+	)
+	return &handler.TwoFactorHandler{}
+}
+
+func NewLoginThrottle(db *sql.DB, emailService interfaces.EmailService, options service.LoginThrottleOptions) *service.LoginThrottle {
+	wire.Build(
+		setUserRepositoryDependecy,
+		setLoginThrottleDependecy,
+	)
+	return &service.LoginThrottle{}
+}
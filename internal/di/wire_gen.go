@@ -15,50 +15,181 @@ import (
 	"github.com/matheusvidal21/smart-news-fetcher/internal/infra/handler"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/infra/service"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/scheduler"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/websub"
 )
 
 // Injectors from wire.go:
 
-func NewArticleHandler(db *sql.DB) *handler.ArticleHandler {
+func NewArticleService(db *sql.DB) *service.ArticleService {
 	articleRepository := database.NewArticleRepository(db)
-	articleService := service.NewArticleService(articleRepository)
-	articleHandler := handler.NewArticleHandler(articleService)
+	articleRevisionRepository := database.NewArticleRevisionRepository(db)
+	articleStateRepository := database.NewArticleStateRepository(db)
+	sourceRepository := database.NewSourceRepository(db)
+	articleService := service.NewArticleService(articleRepository, articleRevisionRepository, articleStateRepository, sourceRepository)
+	return articleService
+}
+
+func NewArticleHandler(db *sql.DB, extractor interfaces.ArticleExtractorInterface) *handler.ArticleHandler {
+	articleRepository := database.NewArticleRepository(db)
+	articleRevisionRepository := database.NewArticleRevisionRepository(db)
+	articleStateRepository := database.NewArticleStateRepository(db)
+	sourceRepository := database.NewSourceRepository(db)
+	articleService := service.NewArticleService(articleRepository, articleRevisionRepository, articleStateRepository, sourceRepository)
+	articleHandler := handler.NewArticleHandler(articleService, extractor)
 	return articleHandler
 }
 
-func NewSourceService(db *sql.DB, jwtService auth.JWTServiceInterface, emailService interfaces.EmailService) *service.SourceService {
+func NewFetcher(db *sql.DB, options fetcher.Options) (*fetcher.Fetcher, error) {
+	articleRepository := database.NewArticleRepository(db)
+	articleRevisionRepository := database.NewArticleRevisionRepository(db)
+	articleStateRepository := database.NewArticleStateRepository(db)
+	sourceRepository := database.NewSourceRepository(db)
+	articleService := service.NewArticleService(articleRepository, articleRevisionRepository, articleStateRepository, sourceRepository)
+	fetchRunRepository := database.NewFetchRunRepository(db)
+	fetcherFetcher, err := fetcher.NewFetcher(articleService, articleRepository, sourceRepository, fetchRunRepository, options)
+	if err != nil {
+		return nil, err
+	}
+	return fetcherFetcher, nil
+}
+
+func NewWebSubSubscriber(db *sql.DB, fetcher2 interfaces.FetcherInterface, options websub.Options) *websub.Subscriber {
+	webSubRepository := database.NewWebSubRepository(db)
+	subscriber := websub.NewSubscriber(webSubRepository, fetcher2, options)
+	return subscriber
+}
+
+func NewWebSubHandler(subscriber interfaces.WebSubSubscriberInterface) *handler.WebSubHandler {
+	webSubHandler := handler.NewWebSubHandler(subscriber)
+	return webSubHandler
+}
+
+func NewScheduler(db *sql.DB, emailService interfaces.EmailService, fetcher2 interfaces.FetcherInterface, push interfaces.WebSubSubscriberInterface, options scheduler.Options) *scheduler.Scheduler {
 	sourceRepository := database.NewSourceRepository(db)
 	userRepository := database.NewUserRepository(db)
-	userService := service.NewUserService(userRepository, emailService, jwtService)
-	articleRepository := database.NewArticleRepository(db)
-	articleService := service.NewArticleService(articleRepository)
-	fetcherFetcher := fetcher.NewFetcher(articleService)
-	sourceService := service.NewSourceService(sourceRepository, userService, emailService, fetcherFetcher)
+	schedulerScheduler := scheduler.NewScheduler(fetcher2, push, sourceRepository, userRepository, emailService, options)
+	return schedulerScheduler
+}
+
+func NewSourceService(db *sql.DB, emailService interfaces.EmailService, fetcher2 interfaces.FetcherInterface, scheduler interfaces.SchedulerInterface) *service.SourceService {
+	sourceRepository := database.NewSourceRepository(db)
+	fetchRunRepository := database.NewFetchRunRepository(db)
+	articleStateRepository := database.NewArticleStateRepository(db)
+	userRepository := database.NewUserRepository(db)
+	userService := service.NewUserService(userRepository, emailService)
+	sourceService := service.NewSourceService(sourceRepository, fetchRunRepository, articleStateRepository, userService, emailService, fetcher2, scheduler)
 	return sourceService
 }
 
-func NewSourceHandler(db *sql.DB, jwtService auth.JWTServiceInterface, emailService interfaces.EmailService) *handler.SourceHandler {
+func NewSourceHandler(db *sql.DB, emailService interfaces.EmailService, fetcher2 interfaces.FetcherInterface, scheduler interfaces.SchedulerInterface) *handler.SourceHandler {
 	sourceRepository := database.NewSourceRepository(db)
+	fetchRunRepository := database.NewFetchRunRepository(db)
+	articleStateRepository := database.NewArticleStateRepository(db)
 	userRepository := database.NewUserRepository(db)
-	userService := service.NewUserService(userRepository, emailService, jwtService)
-	articleRepository := database.NewArticleRepository(db)
-	articleService := service.NewArticleService(articleRepository)
-	fetcherFetcher := fetcher.NewFetcher(articleService)
-	sourceService := service.NewSourceService(sourceRepository, userService, emailService, fetcherFetcher)
+	userService := service.NewUserService(userRepository, emailService)
+	sourceService := service.NewSourceService(sourceRepository, fetchRunRepository, articleStateRepository, userService, emailService, fetcher2, scheduler)
 	sourceHandler := handler.NewSourceHandler(sourceService)
 	return sourceHandler
 }
 
-func NewUserHandler(db *sql.DB, jwtService auth.JWTServiceInterface, emailService interfaces.EmailService) *handler.UserHandler {
+func NewFeedHandler(db *sql.DB) *handler.FeedHandler {
+	articleRepository := database.NewArticleRepository(db)
+	sourceRepository := database.NewSourceRepository(db)
 	userRepository := database.NewUserRepository(db)
-	userService := service.NewUserService(userRepository, emailService, jwtService)
-	userHandler := handler.NewUserHandler(userService)
+	savedSearchRepository := database.NewSavedSearchRepository(db)
+	feedService := service.NewFeedService(articleRepository, sourceRepository, userRepository, savedSearchRepository)
+	feedHandler := handler.NewFeedHandler(feedService)
+	return feedHandler
+}
+
+func NewSavedSearchService(db *sql.DB, emailService interfaces.EmailService) *service.SavedSearchService {
+	savedSearchRepository := database.NewSavedSearchRepository(db)
+	sourceRepository := database.NewSourceRepository(db)
+	userRepository := database.NewUserRepository(db)
+	articleRepository := database.NewArticleRepository(db)
+	articleRevisionRepository := database.NewArticleRevisionRepository(db)
+	articleStateRepository := database.NewArticleStateRepository(db)
+	articleService := service.NewArticleService(articleRepository, articleRevisionRepository, articleStateRepository, sourceRepository)
+	savedSearchService := service.NewSavedSearchService(savedSearchRepository, sourceRepository, userRepository, articleService, emailService)
+	return savedSearchService
+}
+
+func NewSavedSearchHandler(db *sql.DB, emailService interfaces.EmailService) *handler.SavedSearchHandler {
+	savedSearchRepository := database.NewSavedSearchRepository(db)
+	sourceRepository := database.NewSourceRepository(db)
+	userRepository := database.NewUserRepository(db)
+	articleRepository := database.NewArticleRepository(db)
+	articleRevisionRepository := database.NewArticleRevisionRepository(db)
+	articleStateRepository := database.NewArticleStateRepository(db)
+	articleService := service.NewArticleService(articleRepository, articleRevisionRepository, articleStateRepository, sourceRepository)
+	savedSearchService := service.NewSavedSearchService(savedSearchRepository, sourceRepository, userRepository, articleService, emailService)
+	savedSearchHandler := handler.NewSavedSearchHandler(savedSearchService)
+	return savedSearchHandler
+}
+
+func NewUserHandler(db *sql.DB, emailService interfaces.EmailService, sessions interfaces.SessionServiceInterface, accounts interfaces.AccountServiceInterface, twoFactor interfaces.TwoFactorServiceInterface, loginThrottle interfaces.LoginThrottleInterface) *handler.UserHandler {
+	userRepository := database.NewUserRepository(db)
+	userService := service.NewUserService(userRepository, emailService)
+	userHandler := handler.NewUserHandler(userService, sessions, accounts, twoFactor, loginThrottle)
 	return userHandler
 }
 
-// wire.go:
+func NewSessionService(db *sql.DB, jwtService auth.JWTServiceInterface, options service.SessionOptions) *service.SessionService {
+	sessionRepository := database.NewSessionRepository(db)
+	userRepository := database.NewUserRepository(db)
+	sessionService := service.NewSessionService(sessionRepository, userRepository, jwtService, options)
+	return sessionService
+}
 
-var setFetcherDependecy = wire.NewSet(fetcher.NewFetcher, wire.Bind(new(interfaces.FetcherInterface), new(*fetcher.Fetcher)))
+func NewAccountService(db *sql.DB, jwtService auth.JWTServiceInterface, emailService interfaces.EmailService, options service.AccountOptions) *service.AccountService {
+	userRepository := database.NewUserRepository(db)
+	userTokenRepository := database.NewUserTokenRepository(db)
+	accountService := service.NewAccountService(userRepository, userTokenRepository, jwtService, emailService, options)
+	return accountService
+}
+
+func NewAdminHandler(db *sql.DB, scheduler interfaces.SchedulerInterface) *handler.AdminHandler {
+	userRepository := database.NewUserRepository(db)
+	recoveryCodeRepository := database.NewRecoveryCodeRepository(db)
+	sourceRepository := database.NewSourceRepository(db)
+	adminService := service.NewAdminService(userRepository, recoveryCodeRepository, sourceRepository, scheduler)
+	adminHandler := handler.NewAdminHandler(adminService)
+	return adminHandler
+}
+
+func NewAPIKeyService(db *sql.DB) *service.APIKeyService {
+	apiKeyRepository := database.NewAPIKeyRepository(db)
+	userRepository := database.NewUserRepository(db)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepository, userRepository)
+	return apiKeyService
+}
+
+func NewAPIKeyHandler(apiKeys interfaces.APIKeyServiceInterface) *handler.APIKeyHandler {
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeys)
+	return apiKeyHandler
+}
+
+func NewTwoFactorService(db *sql.DB, jwtService auth.JWTServiceInterface) *service.TwoFactorService {
+	userRepository := database.NewUserRepository(db)
+	userTokenRepository := database.NewUserTokenRepository(db)
+	recoveryCodeRepository := database.NewRecoveryCodeRepository(db)
+	twoFactorService := service.NewTwoFactorService(userRepository, userTokenRepository, recoveryCodeRepository, jwtService)
+	return twoFactorService
+}
+
+func NewTwoFactorHandler(twoFactor interfaces.TwoFactorServiceInterface, sessions interfaces.SessionServiceInterface) *handler.TwoFactorHandler {
+	twoFactorHandler := handler.NewTwoFactorHandler(twoFactor, sessions)
+	return twoFactorHandler
+}
+
+func NewLoginThrottle(db *sql.DB, emailService interfaces.EmailService, options service.LoginThrottleOptions) *service.LoginThrottle {
+	userRepository := database.NewUserRepository(db)
+	loginThrottle := service.NewLoginThrottle(userRepository, emailService, options)
+	return loginThrottle
+}
+
+// wire.go:
 
 var setSourceHandlerDependecy = wire.NewSet(handler.NewSourceHandler, wire.Bind(new(interfaces.SourceHandlerInterface), new(*handler.SourceHandler)))
 
@@ -72,8 +203,54 @@ var setArticleServiceDependecy = wire.NewSet(service.NewArticleService, wire.Bin
 
 var setArticleRepositoryDependecy = wire.NewSet(database.NewArticleRepository, wire.Bind(new(interfaces.ArticleRepositoryInterface), new(*database.ArticleRepository)))
 
+var setArticleRevisionRepositoryDependecy = wire.NewSet(database.NewArticleRevisionRepository, wire.Bind(new(interfaces.ArticleRevisionRepositoryInterface), new(*database.ArticleRevisionRepository)))
+
+var setArticleStateRepositoryDependecy = wire.NewSet(database.NewArticleStateRepository, wire.Bind(new(interfaces.ArticleStateRepositoryInterface), new(*database.ArticleStateRepository)))
+
+var setFetchRunRepositoryDependecy = wire.NewSet(database.NewFetchRunRepository, wire.Bind(new(interfaces.FetchRunRepositoryInterface), new(*database.FetchRunRepository)))
+
 var setUserRepositoryDependecy = wire.NewSet(database.NewUserRepository, wire.Bind(new(interfaces.UserRepositoryInterface), new(*database.UserRepository)))
 
 var setUserServiceDependecy = wire.NewSet(service.NewUserService, wire.Bind(new(interfaces.UserServiceInterface), new(*service.UserService)))
 
 var setUserHandlerDependecy = wire.NewSet(handler.NewUserHandler, wire.Bind(new(interfaces.UserHandlerInterface), new(*handler.UserHandler)))
+
+var setFeedServiceDependecy = wire.NewSet(service.NewFeedService, wire.Bind(new(interfaces.FeedServiceInterface), new(*service.FeedService)))
+
+var setFeedHandlerDependecy = wire.NewSet(handler.NewFeedHandler, wire.Bind(new(interfaces.FeedHandlerInterface), new(*handler.FeedHandler)))
+
+var setWebSubRepositoryDependecy = wire.NewSet(database.NewWebSubRepository, wire.Bind(new(interfaces.WebSubRepositoryInterface), new(*database.WebSubRepository)))
+
+var setWebSubHandlerDependecy = wire.NewSet(handler.NewWebSubHandler, wire.Bind(new(interfaces.WebSubHandlerInterface), new(*handler.WebSubHandler)))
+
+var setSavedSearchRepositoryDependecy = wire.NewSet(database.NewSavedSearchRepository, wire.Bind(new(interfaces.SavedSearchRepositoryInterface), new(*database.SavedSearchRepository)))
+
+var setSavedSearchServiceDependecy = wire.NewSet(service.NewSavedSearchService, wire.Bind(new(interfaces.SavedSearchServiceInterface), new(*service.SavedSearchService)))
+
+var setSavedSearchHandlerDependecy = wire.NewSet(handler.NewSavedSearchHandler, wire.Bind(new(interfaces.SavedSearchHandlerInterface), new(*handler.SavedSearchHandler)))
+
+var setSessionRepositoryDependecy = wire.NewSet(database.NewSessionRepository, wire.Bind(new(interfaces.SessionRepositoryInterface), new(*database.SessionRepository)))
+
+var setSessionServiceDependecy = wire.NewSet(service.NewSessionService, wire.Bind(new(interfaces.SessionServiceInterface), new(*service.SessionService)))
+
+var setUserTokenRepositoryDependecy = wire.NewSet(database.NewUserTokenRepository, wire.Bind(new(interfaces.UserTokenRepositoryInterface), new(*database.UserTokenRepository)))
+
+var setAccountServiceDependecy = wire.NewSet(service.NewAccountService, wire.Bind(new(interfaces.AccountServiceInterface), new(*service.AccountService)))
+
+var setAdminServiceDependecy = wire.NewSet(service.NewAdminService, wire.Bind(new(interfaces.AdminServiceInterface), new(*service.AdminService)))
+
+var setAdminHandlerDependecy = wire.NewSet(handler.NewAdminHandler, wire.Bind(new(interfaces.AdminHandlerInterface), new(*handler.AdminHandler)))
+
+var setAPIKeyRepositoryDependecy = wire.NewSet(database.NewAPIKeyRepository, wire.Bind(new(interfaces.APIKeyRepositoryInterface), new(*database.APIKeyRepository)))
+
+var setAPIKeyServiceDependecy = wire.NewSet(service.NewAPIKeyService, wire.Bind(new(interfaces.APIKeyServiceInterface), new(*service.APIKeyService)))
+
+var setAPIKeyHandlerDependecy = wire.NewSet(handler.NewAPIKeyHandler, wire.Bind(new(interfaces.APIKeyHandlerInterface), new(*handler.APIKeyHandler)))
+
+var setRecoveryCodeRepositoryDependecy = wire.NewSet(database.NewRecoveryCodeRepository, wire.Bind(new(interfaces.RecoveryCodeRepositoryInterface), new(*database.RecoveryCodeRepository)))
+
+var setTwoFactorServiceDependecy = wire.NewSet(service.NewTwoFactorService, wire.Bind(new(interfaces.TwoFactorServiceInterface), new(*service.TwoFactorService)))
+
+var setTwoFactorHandlerDependecy = wire.NewSet(handler.NewTwoFactorHandler, wire.Bind(new(interfaces.TwoFactorHandlerInterface), new(*handler.TwoFactorHandler)))
+
+var setLoginThrottleDependecy = wire.NewSet(service.NewLoginThrottle, wire.Bind(new(interfaces.LoginThrottleInterface), new(*service.LoginThrottle)))
@@ -2,20 +2,23 @@ package dto
 
 import (
 	"github.com/go-playground/validator/v10"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
 	"time"
 )
 
 var Validate = validator.New()
 
 type CreateArticleInput struct {
-	ID          string    `json:"id" validate:"required,max=36"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Content     string    `json:"content"`
-	Link        string    `json:"link"`
-	PubDate     time.Time `json:"pub_date"`
-	Author      string    `json:"author"`
-	SourceID    int       `json:"source_id" validate:"required"`
+	ID            string    `json:"id" validate:"required,max=36"`
+	Title         string    `json:"title"`
+	Description   string    `json:"description"`
+	Content       string    `json:"content"`
+	Link          string    `json:"link"`
+	PubDate       time.Time `json:"pub_date"`
+	Author        string    `json:"author"`
+	SourceID      int       `json:"source_id" validate:"required"`
+	Guid          string    `json:"guid" validate:"max=1024"`
+	PubDateSource string    `json:"-"`
 }
 
 type CreateArticleOutput struct {
@@ -27,6 +30,7 @@ type CreateArticleOutput struct {
 	PubDate     time.Time `json:"pub_date"`
 	Author      string    `json:"author"`
 	SourceID    int       `json:"source_id"`
+	Guid        string    `json:"guid"`
 	CreatedAt   time.Time `json:"created_at"`
 }
 
@@ -50,41 +54,95 @@ type UpdateArticleOutput struct {
 	PubDate     time.Time `json:"pub_date"`
 	Author      string    `json:"author"`
 	SourceID    int       `json:"source_id"`
+	Guid        string    `json:"guid"`
 	CreatedAt   time.Time `json:"created_at"`
 }
 
 type FindOneArticleOutput struct {
-	ID          string    `json:"id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Content     string    `json:"content"`
-	Link        string    `json:"link"`
-	PubDate     time.Time `json:"pub_date"`
-	Author      string    `json:"author"`
-	SourceID    int       `json:"source_id"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID               string    `json:"id"`
+	Title            string    `json:"title"`
+	Description      string    `json:"description"`
+	Content          string    `json:"content"`
+	Link             string    `json:"link"`
+	PubDate          time.Time `json:"pub_date"`
+	Author           string    `json:"author"`
+	SourceID         int       `json:"source_id"`
+	Guid             string    `json:"guid"`
+	PubDateSource    string    `json:"pub_date_source"`
+	ExtractedContent string    `json:"extracted_content"`
+	ExtractedAt      time.Time `json:"extracted_at"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+type SearchArticlesOutput struct {
+	Query   string                `json:"query"`
+	Sort    string                `json:"sort"`
+	Page    int                   `json:"page"`
+	Limit   int                   `json:"limit"`
+	Total   int                   `json:"total"`
+	Results []models.ArticleMatch `json:"results"`
+}
+
+type UpdateArticleStateInput struct {
+	Read     *bool `json:"read"`
+	Starred  *bool `json:"starred"`
+	Archived *bool `json:"archived"`
+}
+
+type MarkArticlesReadInput struct {
+	SourceID int       `json:"source_id" validate:"gte=0"`
+	Before   time.Time `json:"before"`
+}
+
+type MarkArticlesReadOutput struct {
+	Marked int `json:"marked"`
+}
+
+type CreateSavedSearchInput struct {
+	Name         string `json:"name" validate:"required,max=100"`
+	Query        string `json:"query" validate:"max=255"`
+	SourceIDs    []int  `json:"source_ids" validate:"max=20,dive,gt=0"`
+	Author       string `json:"author" validate:"max=255"`
+	Days         int    `json:"days" validate:"gte=0,lte=3650"`
+	InNewsletter bool   `json:"in_newsletter"`
+}
+
+type UpdateSavedSearchInput struct {
+	Name         string `json:"name" validate:"required,max=100"`
+	Query        string `json:"query" validate:"max=255"`
+	SourceIDs    []int  `json:"source_ids" validate:"max=20,dive,gt=0"`
+	Author       string `json:"author" validate:"max=255"`
+	Days         int    `json:"days" validate:"gte=0,lte=3650"`
+	InNewsletter bool   `json:"in_newsletter"`
 }
 
 type CreateSourceInput struct {
 	Name           string `json:"name" validate:"required"`
 	Url            string `json:"url" validate:"required,url"`
-	UserID         int    `json:"user_id" validate:"required"`
+	UserID         int    `json:"-"`
 	UpdateInterval int    `json:"update_interval" validate:"required,gt=0"`
+	Category       string `json:"category" validate:"max=255"`
+	FullContent    bool   `json:"full_content"`
 }
 
 type CreateSourceOutput struct {
-	ID             int       `json:"id"`
-	Name           string    `json:"name"`
-	Url            string    `json:"url"`
-	UpdateInterval int       `json:"update_interval"`
-	UserID         int       `json:"user_id"`
-	SavedAt        time.Time `json:"saved_at"`
+	ID              int                    `json:"id"`
+	Name            string                 `json:"name"`
+	Url             string                 `json:"url"`
+	UpdateInterval  int                    `json:"update_interval"`
+	Category        string                 `json:"category"`
+	FullContent     bool                   `json:"full_content"`
+	UserID          int                    `json:"user_id"`
+	SavedAt         time.Time              `json:"saved_at"`
+	DiscoveredFeeds []models.FeedCandidate `json:"discovered_feeds,omitempty"`
 }
 
 type UpdateSourceInput struct {
 	Name           string `json:"name" validate:"required"`
 	Url            string `json:"url" validate:"required,url"`
 	UpdateInterval int    `json:"update_interval" validate:"required,gt=0"`
+	Category       string `json:"category" validate:"max=255"`
+	FullContent    *bool  `json:"full_content"`
 }
 
 type UpdateSourceOutput struct {
@@ -92,17 +150,41 @@ type UpdateSourceOutput struct {
 	Name           string    `json:"name"`
 	Url            string    `json:"url"`
 	UpdateInterval int       `json:"update_interval"`
+	Category       string    `json:"category"`
+	FullContent    bool      `json:"full_content"`
 	UserID         int       `json:"user_id"`
 	SavedAt        time.Time `json:"saved_at"`
 }
 
 type FindOneSourceOutput struct {
-	ID             int       `json:"id"`
-	Name           string    `json:"name"`
-	Url            string    `json:"url"`
-	UpdateInterval int       `json:"update_interval"`
-	UserID         int       `json:"user_id"`
-	SavedAt        time.Time `json:"saved_at"`
+	ID                  int       `json:"id"`
+	Name                string    `json:"name"`
+	Url                 string    `json:"url"`
+	UpdateInterval      int       `json:"update_interval"`
+	Category            string    `json:"category"`
+	FullContent         bool      `json:"full_content"`
+	UserID              int       `json:"user_id"`
+	SavedAt             time.Time `json:"saved_at"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	Disabled            bool      `json:"disabled"`
+	FetchStatus         string    `json:"fetch_status"`
+	LastError           string    `json:"last_error"`
+}
+
+type ImportOPMLEntry struct {
+	Name     string `json:"name"`
+	Url      string `json:"url"`
+	Category string `json:"category"`
+	Status   string `json:"status"`
+	SourceID int    `json:"source_id,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type ImportOPMLOutput struct {
+	Imported   int               `json:"imported"`
+	Duplicates int               `json:"duplicates"`
+	Failed     int               `json:"failed"`
+	Entries    []ImportOPMLEntry `json:"entries"`
 }
 
 type CreateUserInput struct {
@@ -118,9 +200,17 @@ type CreateUserOutput struct {
 }
 
 type FindUserByEmailOutput struct {
-	ID       int    `json:"id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
+	ID               int    `json:"id"`
+	Username         string `json:"username"`
+	Email            string `json:"email"`
+	Role             string `json:"role"`
+	EmailVerified    bool   `json:"email_verified"`
+	TwoFactorEnabled bool   `json:"two_factor_enabled"`
+}
+
+type FeedTokenOutput struct {
+	FeedToken string            `json:"feed_token"`
+	Feeds     map[string]string `json:"feeds"`
 }
 
 type LoginUserInput struct {
@@ -129,7 +219,28 @@ type LoginUserInput struct {
 }
 
 type LoginUserOutput struct {
-	Token string `json:"token"`
+	Token                 string    `json:"token"`
+	RefreshToken          string    `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+}
+
+type RequestPasswordResetInput struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ConfirmPasswordResetInput struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=8"`
+}
+
+type RefreshTokenInput struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// SessionClient describes the device a session is used from.
+type SessionClient struct {
+	UserAgent string
+	IP        string
 }
 
 type UpdateUserPasswordInput struct {
@@ -139,7 +250,70 @@ type UpdateUserPasswordInput struct {
 }
 
 type FindUserByIdOutput struct {
-	ID       int    `json:"id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
+	ID               int    `json:"id"`
+	Username         string `json:"username"`
+	Email            string `json:"email"`
+	Role             string `json:"role"`
+	EmailVerified    bool   `json:"email_verified"`
+	TwoFactorEnabled bool   `json:"two_factor_enabled"`
+	Disabled         bool   `json:"disabled"`
+}
+
+type UpdateUserRoleInput struct {
+	Role string `json:"role" validate:"required,oneof=user admin"`
+}
+
+type FetchHealthOutput struct {
+	Sources   int             `json:"sources"`
+	Healthy   int             `json:"healthy"`
+	Failing   int             `json:"failing"`
+	Disabled  int             `json:"disabled"`
+	ByStatus  map[string]int  `json:"by_status"`
+	Unhealthy []models.Source `json:"unhealthy"`
+}
+
+type CreateAPIKeyInput struct {
+	Name      string     `json:"name" validate:"required,max=100"`
+	Scopes    []string   `json:"scopes" validate:"omitempty,unique,dive,oneof=articles:read sources:manage"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreateAPIKeyOutput carries the only copy of the key ever returned.
+type CreateAPIKeyOutput struct {
+	Key string `json:"key"`
+	models.APIKey
+}
+
+// TwoFactorChallengeOutput answers a login with the right password when the
+// user has two-factor authentication enabled.
+type TwoFactorChallengeOutput struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	ChallengeToken    string    `json:"challenge_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+type TwoFactorLoginInput struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	TwoFactorCodeInput
+}
+
+// TwoFactorCodeInput takes either a TOTP code or one of the recovery codes.
+type TwoFactorCodeInput struct {
+	Code         string `json:"code" validate:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode string `json:"recovery_code" validate:"required_without=Code"`
+}
+
+type TwoFactorConfirmInput struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+type TwoFactorEnrollOutput struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// RecoveryCodesOutput carries the only copy of the recovery codes ever
+// returned.
+type RecoveryCodesOutput struct {
+	RecoveryCodes []string `json:"recovery_codes"`
 }
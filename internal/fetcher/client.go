@@ -0,0 +1,118 @@
+package fetcher
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultConnectTimeout = 10 * time.Second
+	defaultReadTimeout    = 30 * time.Second
+	defaultUserAgent      = "SmartNewsFetcher/1.0 (+https://github.com/matheusvidal21/smart-news-fetcher)"
+	defaultMaxBodyBytes   = 10 << 20
+	defaultMaxRedirects   = 5
+)
+
+var (
+	errBodyTooLarge     = errors.New("response body too large")
+	errTooManyRedirects = errors.New("too many redirects")
+)
+
+func newHTTPClient(options Options) (*http.Client, error) {
+	proxy := http.ProxyFromEnvironment
+	if options.ProxyURL != "" {
+		proxyURL, err := url.Parse(options.ProxyURL)
+		if err != nil {
+			return nil, errors.New("invalid proxy url: " + err.Error())
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if options.CABundlePath != "" {
+		pool, err := loadCABundle(options.CABundlePath)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   options.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   options.ConnectTimeout,
+		ResponseHeaderTimeout: options.ReadTimeout,
+		MaxIdleConnsPerHost:   options.HostMaxInFlight,
+		IdleConnTimeout:       90 * time.Second,
+	}
+
+	maxRedirects := options.MaxRedirects
+	return &http.Client{
+		Transport: transport,
+		Timeout:   options.ConnectTimeout + options.ReadTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("%w: stopped after %d redirects", errTooManyRedirects, maxRedirects)
+			}
+			return nil
+		},
+	}, nil
+}
+
+func loadCABundle(path string) (*x509.CertPool, error) {
+	bundle, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.New("failed to read CA bundle: " + err.Error())
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, errors.New("no certificates found in CA bundle: " + path)
+	}
+	return pool, nil
+}
+
+func limitBody(resp *http.Response, maxBytes int64) error {
+	if resp.ContentLength > maxBytes {
+		return fmt.Errorf("%w: %s bytes announced, limit is %s", errBodyTooLarge, strconv.FormatInt(resp.ContentLength, 10), strconv.FormatInt(maxBytes, 10))
+	}
+	resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: maxBytes}
+	return nil
+}
+
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, errBodyTooLarge
+	}
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n + int(b.remaining), errBodyTooLarge
+	}
+	return n, err
+}
@@ -0,0 +1,144 @@
+package fetcher
+
+import (
+	"bytes"
+	"errors"
+	"github.com/google/logger"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"github.com/mmcdole/gofeed"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var feedLinkTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+}
+
+var commonFeedPaths = []string{"/feed", "/rss.xml", "/atom.xml", "/feed.xml", "/index.xml"}
+
+// DiscoverFeeds looks for feeds published by the page at pageURL. A feed URL is
+// returned as its own single candidate; HTML pages are searched for alternate
+// links and, when they announce none, common feed paths of the site are probed.
+func (f *Fetcher) DiscoverFeeds(pageURL string) ([]models.FeedCandidate, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, errors.New("Invalid url: " + err.Error())
+	}
+
+	resp, err := f.get(pageURL, "", "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, classify(gofeed.HTTPError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+		})
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, classify(err)
+	}
+	if resp.Request != nil && resp.Request.URL != nil {
+		base = resp.Request.URL
+	}
+
+	if !isHTML(resp.Header.Get("Content-Type"), body) {
+		feed, err := gofeed.NewParser().Parse(bytes.NewReader(body))
+		if err != nil {
+			return nil, parseError(err)
+		}
+		return []models.FeedCandidate{{Url: base.String(), Title: feed.Title, Type: feed.FeedType}}, nil
+	}
+
+	candidates := feedLinks(base, body)
+	if len(candidates) == 0 {
+		candidates = f.probeCommonPaths(base)
+	}
+	logger.Infof("Discovered %d feeds at %s", len(candidates), pageURL)
+	return candidates, nil
+}
+
+func (f *Fetcher) probeCommonPaths(base *url.URL) []models.FeedCandidate {
+	candidates := []models.FeedCandidate{}
+	for _, path := range commonFeedPaths {
+		candidateURL := base.ResolveReference(&url.URL{Path: path}).String()
+		feed, err := f.ParseFeed(candidateURL)
+		if err != nil {
+			continue
+		}
+		candidates = append(candidates, models.FeedCandidate{Url: candidateURL, Title: feed.Title, Type: feed.FeedType})
+	}
+	return candidates
+}
+
+func isHTML(contentType string, body []byte) bool {
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	contentType = strings.ToLower(contentType)
+	return strings.Contains(contentType, "text/html") || strings.Contains(contentType, "application/xhtml")
+}
+
+// feedLinks collects the <link rel="alternate"> feeds of an HTML document,
+// resolving their hrefs against the page URL or its <base> element.
+func feedLinks(base *url.URL, body []byte) []models.FeedCandidate {
+	candidates := []models.FeedCandidate{}
+	seen := make(map[string]bool)
+
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			return candidates
+		}
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			continue
+		}
+
+		token := tokenizer.Token()
+		if token.DataAtom == atom.Body {
+			return candidates
+		}
+		attrs := make(map[string]string, len(token.Attr))
+		for _, attr := range token.Attr {
+			attrs[strings.ToLower(attr.Key)] = strings.TrimSpace(attr.Val)
+		}
+
+		switch token.DataAtom {
+		case atom.Base:
+			if href, err := base.Parse(attrs["href"]); err == nil && attrs["href"] != "" {
+				base = href
+			}
+		case atom.Link:
+			linkType := strings.ToLower(attrs["type"])
+			if !hasRel(attrs["rel"], "alternate") || !feedLinkTypes[linkType] || attrs["href"] == "" {
+				continue
+			}
+			href, err := base.Parse(attrs["href"])
+			if err != nil || seen[href.String()] {
+				continue
+			}
+			seen[href.String()] = true
+			candidates = append(candidates, models.FeedCandidate{Url: href.String(), Title: attrs["title"], Type: linkType})
+		}
+	}
+}
+
+func hasRel(rel, value string) bool {
+	for _, field := range strings.Fields(strings.ToLower(rel)) {
+		if field == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package fetcher
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"github.com/mmcdole/gofeed"
+	"net"
+	"os"
+	"time"
+)
+
+const (
+	ErrorKindTimeout     = "timeout"
+	ErrorKindDNS         = "dns"
+	ErrorKindTLS         = "tls"
+	ErrorKindTooLarge    = "too_large"
+	ErrorKindRedirects   = "too_many_redirects"
+	ErrorKindRateLimited = "rate_limited"
+	ErrorKindHTTP        = "http"
+	ErrorKindParse       = "parse"
+	ErrorKindNetwork     = "network"
+	ErrorKindUnknown     = "unknown"
+	FetchStatusOK        = "ok"
+)
+
+type FetchError struct {
+	Kind string
+	Err  error
+}
+
+func (e *FetchError) Error() string {
+	return e.Kind + ": " + e.Err.Error()
+}
+
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorKind returns the classification of an error returned by the fetcher, or
+// FetchStatusOK when there is no error.
+func ErrorKind(err error) string {
+	if err == nil {
+		return FetchStatusOK
+	}
+	var fetchErr *FetchError
+	if errors.As(err, &fetchErr) {
+		return fetchErr.Kind
+	}
+	return classify(err).Kind
+}
+
+// RetryAfter returns the time before which the host asked not to be requested
+// again, when the error comes from the host rate limiting the fetcher.
+func RetryAfter(err error) (time.Time, bool) {
+	var retryErr *retryError
+	if errors.As(err, &retryErr) {
+		return retryErr.until, true
+	}
+	return time.Time{}, false
+}
+
+// parseError marks an error of the feed parser, which has no type of its own
+// to be classified by.
+func parseError(err error) *FetchError {
+	return &FetchError{Kind: ErrorKindParse, Err: err}
+}
+
+func classify(err error) *FetchError {
+	var fetchErr *FetchError
+	if errors.As(err, &fetchErr) {
+		return fetchErr
+	}
+	return &FetchError{Kind: errorKind(err), Err: err}
+}
+
+func errorKind(err error) string {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var httpErr gofeed.HTTPError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var certificateInvalidErr x509.CertificateInvalidError
+	var hostnameErr x509.HostnameError
+	var certificateVerificationErr *tls.CertificateVerificationError
+	var recordHeaderErr tls.RecordHeaderError
+
+	switch {
+	case errors.Is(err, errBodyTooLarge):
+		return ErrorKindTooLarge
+	case errors.Is(err, errTooManyRedirects):
+		return ErrorKindRedirects
+	case errors.Is(err, errRateLimited), errors.Is(err, errHostBusy):
+		return ErrorKindRateLimited
+	case errors.As(err, &httpErr):
+		return ErrorKindHTTP
+	case errors.As(err, &dnsErr):
+		return ErrorKindDNS
+	case errors.As(err, &unknownAuthorityErr),
+		errors.As(err, &certificateInvalidErr),
+		errors.As(err, &hostnameErr),
+		errors.As(err, &certificateVerificationErr),
+		errors.As(err, &recordHeaderErr):
+		return ErrorKindTLS
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, os.ErrDeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorKindTimeout
+	case errors.As(err, &netErr):
+		return ErrorKindNetwork
+	default:
+		return ErrorKindUnknown
+	}
+}
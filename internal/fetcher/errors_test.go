@@ -0,0 +1,80 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/mmcdole/gofeed"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestErrorKind(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"no error", nil, FetchStatusOK},
+		{"body too large", fmt.Errorf("%w: 20 bytes announced", errBodyTooLarge), ErrorKindTooLarge},
+		{"too many redirects", fmt.Errorf("%w: stopped after 5 redirects", errTooManyRedirects), ErrorKindRedirects},
+		{"rate limited", &retryError{err: errRateLimited, host: "example.com", until: time.Now()}, ErrorKindRateLimited},
+		{"host busy", &retryError{err: errHostBusy, host: "example.com", until: time.Now()}, ErrorKindRateLimited},
+		{"http status", gofeed.HTTPError{StatusCode: http.StatusNotFound, Status: "404 Not Found"}, ErrorKindHTTP},
+		{"dns", &net.DNSError{Err: "no such host", Name: "missing.example"}, ErrorKindDNS},
+		{"timeout", fmt.Errorf("Get: %w", context.DeadlineExceeded), ErrorKindTimeout},
+		{"network", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, ErrorKindNetwork},
+		{"parse", parseError(errors.New("Failed to detect feed type")), ErrorKindParse},
+		{"classified", classify(&net.DNSError{Err: "no such host"}), ErrorKindDNS},
+		{"unknown", errors.New("something else"), ErrorKindUnknown},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ErrorKind(test.err); got != test.want {
+				t.Errorf("ErrorKind() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLimitBody(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		contentLength int64
+		want          string
+	}{
+		{"within the limit", "0123456789", 10, FetchStatusOK},
+		{"announced too large", "0123456789ABCDEF", 16, ErrorKindTooLarge},
+		{"read past the limit", "0123456789ABCDEF", -1, ErrorKindTooLarge},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := &http.Response{Body: io.NopCloser(strings.NewReader(test.body)), ContentLength: test.contentLength}
+			err := limitBody(resp, 10)
+			if err == nil {
+				_, err = io.ReadAll(resp.Body)
+			}
+			if got := ErrorKind(err); got != test.want {
+				t.Errorf("ErrorKind() = %q, want %q (%v)", got, test.want, err)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	until := time.Now().Add(time.Minute)
+	err := classify(&retryError{err: errRateLimited, host: "example.com", until: until})
+	if got, ok := RetryAfter(err); !ok || !got.Equal(until) {
+		t.Errorf("RetryAfter() = %s, %t, want %s, true", got, ok, until)
+	}
+	if _, ok := RetryAfter(errors.New("other")); ok {
+		t.Errorf("RetryAfter() reported a deadline for an unrelated error")
+	}
+}
@@ -0,0 +1,138 @@
+package fetcher
+
+import (
+	"errors"
+	"github.com/google/logger"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/readability"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/sanitize"
+	"github.com/mmcdole/gofeed"
+	"golang.org/x/net/html/charset"
+	"net/url"
+	"strings"
+)
+
+const extractionQueueSize = 256
+
+var ErrNotHTML = errors.New("article page is not HTML")
+
+// Start runs the worker extracting the full content of new articles of the
+// sources created with full content.
+func (f *Fetcher) Start() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.started {
+		return
+	}
+	f.started = true
+
+	f.wg.Add(1)
+	go f.extractLoop()
+	logger.Info("Article extraction started")
+}
+
+func (f *Fetcher) Stop() {
+	f.mu.Lock()
+	if !f.started {
+		f.mu.Unlock()
+		return
+	}
+	f.started = false
+	f.mu.Unlock()
+
+	close(f.quit)
+	f.wg.Wait()
+	logger.Info("Article extraction stopped")
+}
+
+// enqueueExtraction hands the article to the extraction worker, so downloading
+// article pages never holds up the fetch of the feed. Articles past a full
+// queue are dropped: their content can still be extracted on demand.
+func (f *Fetcher) enqueueExtraction(id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.extracting[id] {
+		return
+	}
+
+	select {
+	case f.extractions <- id:
+		f.extracting[id] = true
+	default:
+		logger.Errorf("Article extraction queue is full, dropping article %s", id)
+	}
+}
+
+func (f *Fetcher) extractLoop() {
+	defer f.wg.Done()
+	for {
+		select {
+		case id := <-f.extractions:
+			if err := f.ExtractArticle(id); err != nil {
+				logger.Errorf("Failed to extract full content of article %s: %v", id, err)
+			}
+			f.mu.Lock()
+			delete(f.extracting, id)
+			f.mu.Unlock()
+		case <-f.quit:
+			return
+		}
+	}
+}
+
+// ExtractContent downloads the article page and returns its main content,
+// sanitized and with URLs resolved against the page. The request goes through
+// the same per host politeness limits as feed fetches.
+func (f *Fetcher) ExtractContent(link string) (string, error) {
+	page, err := url.Parse(link)
+	if err != nil || !page.IsAbs() {
+		return "", errors.New("Invalid article link: " + link)
+	}
+
+	resp, err := f.get(link, "", "")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", classify(gofeed.HTTPError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+		})
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if contentType != "" && !strings.Contains(strings.ToLower(contentType), "html") {
+		return "", ErrNotHTML
+	}
+	if resp.Request != nil && resp.Request.URL != nil {
+		page = resp.Request.URL
+	}
+
+	body, err := charset.NewReader(resp.Body, contentType)
+	if err != nil {
+		return "", classify(err)
+	}
+	content, err := readability.Extract(body)
+	if err == readability.ErrNoContent {
+		return "", err
+	}
+	if err != nil {
+		return "", classify(err)
+	}
+	return sanitize.HTML(content, page), nil
+}
+
+// ExtractArticle stores the main content of the article page next to the
+// content given by its feed.
+func (f *Fetcher) ExtractArticle(id string) error {
+	article, err := f.articleRepository.FindOne(id)
+	if err != nil {
+		return err
+	}
+
+	content, err := f.ExtractContent(article.Link)
+	if err != nil {
+		return errors.New("Failed to extract article content: " + err.Error())
+	}
+	return f.articleService.SaveExtractedContent(id, content)
+}
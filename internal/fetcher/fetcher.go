@@ -1,28 +1,101 @@
 package fetcher
 
 import (
+	"bytes"
 	"errors"
 	"github.com/google/logger"
-	"github.com/matheusvidal21/smart-news-fetcher/internal/dto"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
 	"github.com/mmcdole/gofeed"
 	"github.com/patrickmn/go-cache"
+	"io"
+	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
+const maxRunErrorLength = 1024
+
+// parsedFeed is what is cached for a feed URL: the feed and the validators of
+// the response it was parsed from.
+type parsedFeed struct {
+	feed         *gofeed.Feed
+	etag         string
+	lastModified string
+}
+
+type Options struct {
+	HostRequestsPerMinute int
+	HostBurst             int
+	HostMaxInFlight       int
+	ConnectTimeout        time.Duration
+	ReadTimeout           time.Duration
+	UserAgent             string
+	ProxyURL              string
+	MaxBodyBytes          int64
+	MaxRedirects          int
+	CABundlePath          string
+}
+
 type Fetcher struct {
-	articleService interfaces.ArticleServiceInterface
-	cache          *cache.Cache
+	articleService     interfaces.ArticleServiceInterface
+	articleRepository  interfaces.ArticleRepositoryInterface
+	sourceRepository   interfaces.SourceRepositoryInterface
+	fetchRunRepository interfaces.FetchRunRepositoryInterface
+	cache              *cache.Cache
+	client             *http.Client
+	limiter            *hostLimiter
+	hubObserver        interfaces.HubObserver
+	userAgent          string
+	maxBodyBytes       int64
+	extractions        chan string
+	mu                 sync.Mutex
+	extracting         map[string]bool
+	quit               chan struct{}
+	wg                 sync.WaitGroup
+	started            bool
 }
 
-func NewFetcher(articleService interfaces.ArticleServiceInterface) *Fetcher {
+func NewFetcher(articleService interfaces.ArticleServiceInterface, articleRepository interfaces.ArticleRepositoryInterface, sourceRepository interfaces.SourceRepositoryInterface, fetchRunRepository interfaces.FetchRunRepositoryInterface, options Options) (*Fetcher, error) {
+	if options.ConnectTimeout <= 0 {
+		options.ConnectTimeout = defaultConnectTimeout
+	}
+	if options.ReadTimeout <= 0 {
+		options.ReadTimeout = defaultReadTimeout
+	}
+	if options.UserAgent == "" {
+		options.UserAgent = defaultUserAgent
+	}
+	if options.MaxBodyBytes <= 0 {
+		options.MaxBodyBytes = defaultMaxBodyBytes
+	}
+	if options.MaxRedirects <= 0 {
+		options.MaxRedirects = defaultMaxRedirects
+	}
+
+	client, err := newHTTPClient(options)
+	if err != nil {
+		return nil, err
+	}
+
 	c := cache.New(5*time.Minute, 10*time.Minute)
 	return &Fetcher{
-		articleService: articleService,
-		cache:          c,
-	}
+		articleService:     articleService,
+		articleRepository:  articleRepository,
+		sourceRepository:   sourceRepository,
+		fetchRunRepository: fetchRunRepository,
+		cache:              c,
+		client:             client,
+		limiter:            newHostLimiter(options.HostRequestsPerMinute, options.HostBurst, options.HostMaxInFlight, client.Timeout),
+		userAgent:          options.UserAgent,
+		maxBodyBytes:       options.MaxBodyBytes,
+		extractions:        make(chan string, extractionQueueSize),
+		extracting:         make(map[string]bool),
+		quit:               make(chan struct{}),
+	}, nil
 }
 
 func (f *Fetcher) LoadFeed(id int) (*gofeed.Feed, error) {
@@ -42,74 +115,246 @@ func (f *Fetcher) StoreFeed(id int, feed *gofeed.Feed) {
 }
 
 func (f *Fetcher) ParseFeed(url string) (*gofeed.Feed, error) {
-	if cachedFeed, found := f.cache.Get(url); found {
+	if cached, found := f.cache.Get(url); found {
 		logger.Info("Feed loaded from cache: " + url)
-		return cachedFeed.(*gofeed.Feed), nil
+		return cached.(parsedFeed).feed, nil
 	}
 
-	fp := gofeed.NewParser()
-	feed, err := fp.ParseURL(url)
+	resp, err := f.get(url, "", "")
 	if err != nil {
 		logger.Errorf("Failed to parse feed: %v", err)
 		return nil, err
 	}
+	defer resp.Body.Close()
 
-	f.cache.Set(url, feed, cache.DefaultExpiration)
-	logger.Info("Feed parsed and cached: " + url)
+	feed, err := f.parseResponse(url, resp)
+	if err != nil {
+		logger.Errorf("Failed to parse feed: %v", err)
+		return nil, err
+	}
 	return feed, nil
 }
 
-func (f *Fetcher) FetchFeeds(id int, feed *gofeed.Feed) {
-	for _, item := range feed.Items {
-		idArticle := f.articleService.GenerateArticleID(item.Title, item.Link)
+// FeedValidators returns the ETag and Last-Modified of the response the feed at
+// url was last parsed from, so a new source can send conditional requests from
+// its first scheduled fetch.
+func (f *Fetcher) FeedValidators(url string) (string, string) {
+	cached, found := f.cache.Get(url)
+	if !found {
+		return "", ""
+	}
+	return cached.(parsedFeed).etag, cached.(parsedFeed).lastModified
+}
 
-		existingArticle, err := f.articleService.FindOne(idArticle)
-		if err == nil && existingArticle.ID != "" {
-			logger.Info("Article already exists: " + item.Title)
-			continue
-		}
+// ParseSourceFeed reports false when the feed was not modified since the last fetch.
+func (f *Fetcher) ParseSourceFeed(source models.Source) (*gofeed.Feed, bool, error) {
+	feed, status, err := f.parseSourceFeed(source)
+	if err != nil {
+		return nil, false, err
+	}
+	return feed, status != http.StatusNotModified, nil
+}
+
+func (f *Fetcher) parseSourceFeed(source models.Source) (*gofeed.Feed, int, error) {
+	if cached, found := f.cache.Get(source.Url); found {
+		logger.Info("Feed loaded from cache: " + source.Url)
+		feed := cached.(parsedFeed).feed
+		f.observeHub(source, feed)
+		return feed, 0, nil
+	}
+
+	resp, err := f.get(source.Url, source.ETag, source.LastModified)
+	if err != nil {
+		logger.Errorf("Failed to parse feed: %v", err)
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		logger.Info("Feed not modified since last fetch: " + source.Url)
+		return nil, resp.StatusCode, nil
+	}
+
+	feed, err := f.parseResponse(source.Url, resp)
+	if err != nil {
+		logger.Errorf("Failed to parse feed: %v", err)
+		return nil, resp.StatusCode, err
+	}
 
-		var authorName string
-		if len(item.Authors) > 0 {
-			authorName = item.Authors[0].Name
-		} else {
-			authorName = "Unknown"
+	etag := resp.Header.Get("ETag")
+	lastModified := resp.Header.Get("Last-Modified")
+	if etag != source.ETag || lastModified != source.LastModified {
+		err = f.sourceRepository.UpdateCacheValidators(source.ID, etag, lastModified)
+		if err != nil {
+			logger.Errorf("Failed to store cache validators: %v", err)
 		}
+	}
+	f.observeHub(source, feed)
+	return feed, resp.StatusCode, nil
+}
+
+// PostForm sends a form, like a WebSub subscription request, through the same
+// client, User-Agent and per host limits as feed fetches.
+func (f *Fetcher) PostForm(target string, form url.Values) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, classify(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return f.do(req)
+}
+
+func (f *Fetcher) get(url, etag, lastModified string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, classify(err)
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+	return f.do(req)
+}
+
+func (f *Fetcher) do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("User-Agent", f.userAgent)
+	host := strings.ToLower(req.URL.Host)
+	release, err := f.limiter.acquire(host)
+	if err != nil {
+		return nil, classify(err)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		release()
+		return nil, classify(err)
+	}
+	resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: release}
+	err = f.limiter.observe(host, resp)
+	if err != nil {
+		resp.Body.Close()
+		return nil, classify(err)
+	}
+
+	err = limitBody(resp, f.maxBodyBytes)
+	if err != nil {
+		resp.Body.Close()
+		return nil, classify(err)
+	}
+	return resp, nil
+}
+
+func (f *Fetcher) parseResponse(url string, resp *http.Response) (*gofeed.Feed, error) {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, classify(gofeed.HTTPError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+		})
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, classify(err)
+	}
+
+	feed, err := gofeed.NewParser().Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, parseError(err)
+	}
+	setHubLinks(feed, body, resp.Header)
+
+	f.cache.Set(url, parsedFeed{
+		feed:         feed,
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	}, cache.DefaultExpiration)
+	logger.Info("Feed parsed and cached: " + url)
+	return feed, nil
+}
+
+func (f *Fetcher) FetchFeeds(id int, feed *gofeed.Feed) models.FetchStats {
+	var stats models.FetchStats
+	seenAt := time.Now().UTC().Truncate(time.Second)
 
-		article := dto.CreateArticleInput{
-			ID:          idArticle,
-			Title:       item.Title,
-			Description: item.Description,
-			Content:     item.Content,
-			Link:        item.Link,
-			PubDate:     *item.PublishedParsed,
-			Author:      authorName,
-			SourceID:    id,
+	source, err := f.sourceRepository.FindOne(id)
+	if err != nil {
+		logger.Errorf("Failed to find source %d: %v", id, err)
+	}
+	base := feedBaseURL(source.Url, feed)
+
+	for _, item := range feed.Items {
+		if item == nil {
+			continue
 		}
-		_, err = f.articleService.Create(article)
+
+		articleID, result, err := f.saveItem(id, feed, base, item, seenAt)
 		if err != nil {
-			logger.Errorf("Failed to create article: %v", err)
+			logger.Errorf("Failed to save article: %v", err)
+			stats.Failed++
 			continue
 		}
-		logger.Info("Article created: " + item.Title)
+
+		switch result {
+		case models.ArticleCreated:
+			logger.Info("Article created: " + item.Title)
+			stats.New++
+			if source.FullContent {
+				f.enqueueExtraction(articleID)
+			}
+		case models.ArticleUpdated:
+			logger.Info("Article updated: " + item.Title)
+			stats.Updated++
+		default:
+			logger.Info("Article already exists: " + item.Title)
+			stats.Skipped++
+		}
 	}
+	return stats
+}
+
+// saveItem normalizes and stores one item. A malformed item fails on its own
+// instead of stopping the whole fetch.
+func (f *Fetcher) saveItem(id int, feed *gofeed.Feed, base *url.URL, item *gofeed.Item, seenAt time.Time) (articleID, result string, err error) {
+	article := normalizeItem(id, feed, base, item, seenAt)
+	result, err = f.articleService.Save(article)
+	return f.articleService.GenerateArticleID(id, article.Guid, article.Link, article.Title), result, err
 }
 
-func (f *Fetcher) StartScheduler(source models.Source, feed *gofeed.Feed) {
-	interval := time.Duration(source.UpdateInterval) * time.Hour
-	ticker := time.NewTicker(interval)
+func (f *Fetcher) FetchSource(source models.Source) (models.FetchStats, error) {
+	run := models.FetchRun{
+		SourceID:  source.ID,
+		StartedAt: time.Now(),
+	}
+	defer f.recordRun(&run)
 
-	go func() {
-		f.FetchFeeds(source.ID, feed)
-		for range ticker.C {
-			if feed.UpdatedParsed != nil {
-				timeSinceUptade := time.Since(*feed.UpdatedParsed)
-				if timeSinceUptade < interval {
-					logger.Info("Feed not updated since last check, skipping fetch")
-					continue
-				}
-			}
-			f.FetchFeeds(source.ID, feed)
-		}
-	}()
+	feed, status, err := f.parseSourceFeed(source)
+	run.HTTPStatus = status
+	if err != nil {
+		run.Error = err.Error()
+		return models.FetchStats{}, err
+	}
+	if status == http.StatusNotModified {
+		return models.FetchStats{}, nil
+	}
+
+	f.StoreFeed(source.ID, feed)
+	stats := f.FetchFeeds(source.ID, feed)
+	run.ItemsSeen = len(feed.Items)
+	run.ItemsInserted = stats.New
+	run.ItemsUpdated = stats.Updated
+	logger.Infof("Source %d fetched: %d new, %d updated, %d skipped, %d failed", source.ID, stats.New, stats.Updated, stats.Skipped, stats.Failed)
+	return stats, nil
+}
+
+func (f *Fetcher) recordRun(run *models.FetchRun) {
+	run.DurationMs = time.Since(run.StartedAt).Milliseconds()
+	if len(run.Error) > maxRunErrorLength {
+		run.Error = run.Error[:maxRunErrorLength]
+	}
+	_, err := f.fetchRunRepository.Create(*run)
+	if err != nil {
+		logger.Errorf("Failed to record fetch run: %v", err)
+	}
 }
@@ -0,0 +1,69 @@
+package fetcher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+)
+
+func TestFeedValidators(t *testing.T) {
+	feed, err := os.ReadFile("testdata/rss_missing_date.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Sat, 17 Oct 2026 10:00:00 GMT")
+		w.Write(feed)
+	}))
+	defer server.Close()
+
+	f, err := NewFetcher(nil, nil, nil, nil, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if etag, lastModified := f.FeedValidators(server.URL); etag != "" || lastModified != "" {
+		t.Errorf("FeedValidators() = %q, %q before the feed was parsed", etag, lastModified)
+	}
+	if _, err := f.ParseFeed(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	etag, lastModified := f.FeedValidators(server.URL)
+	if etag != `"v1"` || lastModified != "Sat, 17 Oct 2026 10:00:00 GMT" {
+		t.Errorf("FeedValidators() = %q, %q, want the validators of the response", etag, lastModified)
+	}
+}
+
+func TestPostForm(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		received <- r
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	f, err := NewFetcher(nil, nil, nil, nil, Options{UserAgent: "test-agent/1.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := f.PostForm(server.URL, url.Values{"hub.mode": {"subscribe"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	r := <-received
+	if r.Method != http.MethodPost || r.PostForm.Get("hub.mode") != "subscribe" {
+		t.Errorf("received %s with form %v, want a POST of the form", r.Method, r.PostForm)
+	}
+	if got := r.UserAgent(); got != "test-agent/1.0" {
+		t.Errorf("User-Agent = %q, want the configured one", got)
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusAccepted)
+	}
+}
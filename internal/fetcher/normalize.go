@@ -0,0 +1,199 @@
+package fetcher
+
+import (
+	"github.com/matheusvidal21/smart-news-fetcher/internal/dto"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"github.com/mmcdole/gofeed"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const unknownAuthor = "Unknown"
+
+// dateLayouts are the formats tried for Dublin Core dates, which gofeed keeps
+// as plain text.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC822Z,
+	time.RFC822,
+}
+
+// TIMESTAMP columns only hold dates within this range.
+var (
+	minPubDate = time.Date(1970, time.January, 2, 0, 0, 0, 0, time.UTC)
+	maxPubDate = time.Date(2038, time.January, 18, 0, 0, 0, 0, time.UTC)
+)
+
+// normalizeItem turns a feed item into an article, filling in what the feed
+// left out: the date falls back from the publish date to the update date, the
+// Dublin Core date and the time the item was first seen, links are made
+// absolute and every author is kept.
+func normalizeItem(sourceID int, feed *gofeed.Feed, base *url.URL, item *gofeed.Item, seenAt time.Time) dto.CreateArticleInput {
+	pubDate, pubDateSource := itemPubDate(item, seenAt)
+	return dto.CreateArticleInput{
+		Title:         strings.TrimSpace(item.Title),
+		Description:   item.Description,
+		Content:       item.Content,
+		Link:          itemLink(base, item),
+		PubDate:       pubDate,
+		Author:        itemAuthors(feed, item),
+		SourceID:      sourceID,
+		Guid:          strings.TrimSpace(item.GUID),
+		PubDateSource: pubDateSource,
+	}
+}
+
+func itemPubDate(item *gofeed.Item, seenAt time.Time) (time.Time, string) {
+	if validPubDate(item.PublishedParsed) {
+		return item.PublishedParsed.UTC().Truncate(time.Second), publishedSource(item)
+	}
+	if validPubDate(item.UpdatedParsed) {
+		return item.UpdatedParsed.UTC().Truncate(time.Second), models.PubDateUpdated
+	}
+	if item.DublinCoreExt != nil {
+		for _, value := range item.DublinCoreExt.Date {
+			if date, ok := parseDate(value); ok {
+				return date.UTC().Truncate(time.Second), models.PubDateDublinCore
+			}
+		}
+	}
+	return seenAt, models.PubDateFirstSeen
+}
+
+// publishedSource tells where the parser took the publish date from, as it
+// already falls back to the Dublin Core date for RSS and to the update date
+// for Atom.
+func publishedSource(item *gofeed.Item) string {
+	if item.DublinCoreExt != nil && len(item.DublinCoreExt.Date) > 0 && item.Published == item.DublinCoreExt.Date[0] {
+		return models.PubDateDublinCore
+	}
+	if item.Updated != "" && item.Published == item.Updated {
+		return models.PubDateUpdated
+	}
+	return models.PubDatePublished
+}
+
+func validPubDate(date *time.Time) bool {
+	return date != nil && date.After(minPubDate) && date.Before(maxPubDate)
+}
+
+func parseDate(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		date, err := time.Parse(layout, value)
+		if err == nil && validPubDate(&date) {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// feedBaseURL is the URL relative item links are resolved against: the site
+// link of the feed, itself resolved against the feed URL, falling back to the
+// URL the feed was fetched from.
+func feedBaseURL(sourceURL string, feed *gofeed.Feed) *url.URL {
+	var base *url.URL
+	for _, link := range []string{sourceURL, feed.FeedLink, feed.Link} {
+		ref, err := url.Parse(strings.TrimSpace(link))
+		if err != nil || ref.String() == "" {
+			continue
+		}
+		if base != nil {
+			ref = base.ResolveReference(ref)
+		}
+		if ref.IsAbs() {
+			base = ref
+		}
+	}
+	return base
+}
+
+// itemLink returns the absolute link of the item, falling back to its other
+// links and to a GUID holding its permalink.
+func itemLink(base *url.URL, item *gofeed.Item) string {
+	for _, link := range append([]string{item.Link}, item.Links...) {
+		if resolved := resolveLink(base, link); resolved != "" {
+			return resolved
+		}
+	}
+
+	guid, err := url.Parse(strings.TrimSpace(item.GUID))
+	if err == nil && (guid.Scheme == "http" || guid.Scheme == "https") && guid.Host != "" {
+		return guid.String()
+	}
+	return ""
+}
+
+func resolveLink(base *url.URL, link string) string {
+	link = strings.TrimSpace(link)
+	if link == "" {
+		return ""
+	}
+	ref, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		ref = base.ResolveReference(ref)
+	}
+	return ref.String()
+}
+
+// itemAuthors joins the authors of the item with its Dublin Core creators, as
+// the parser only keeps the first creator, falling back to the authors of the
+// feed.
+func itemAuthors(feed *gofeed.Feed, item *gofeed.Item) string {
+	names := personNames(item.Authors)
+	if item.DublinCoreExt != nil {
+		names = uniqueNames(append(append(names, item.DublinCoreExt.Creator...), item.DublinCoreExt.Author...))
+	}
+	if len(names) == 0 {
+		names = personNames(feed.Authors)
+	}
+	if len(names) == 0 {
+		return unknownAuthor
+	}
+	return strings.Join(names, ", ")
+}
+
+func personNames(people []*gofeed.Person) []string {
+	var names []string
+	for _, person := range people {
+		if person == nil {
+			continue
+		}
+		name := person.Name
+		if strings.TrimSpace(name) == "" {
+			name = person.Email
+		}
+		names = append(names, name)
+	}
+	return uniqueNames(names)
+}
+
+func uniqueNames(names []string) []string {
+	var unique []string
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		unique = append(unique, name)
+	}
+	return unique
+}
@@ -0,0 +1,139 @@
+package fetcher
+
+import (
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"github.com/mmcdole/gofeed"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type normalizedItem struct {
+	link          string
+	author        string
+	pubDate       time.Time
+	pubDateSource string
+}
+
+func TestNormalizeItem(t *testing.T) {
+	seenAt := time.Date(2024, time.March, 10, 15, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		fixture   string
+		sourceURL string
+		want      []normalizedItem
+	}{
+		{
+			name:      "missing dates fall back to the first time the item was seen",
+			fixture:   "rss_missing_date.xml",
+			sourceURL: "https://blog.test/feed.xml",
+			want: []normalizedItem{
+				{"https://blog.test/no-date", unknownAuthor, seenAt, models.PubDateFirstSeen},
+				{"https://blog.test/out-of-range", unknownAuthor, seenAt, models.PubDateFirstSeen},
+			},
+		},
+		{
+			name:      "dublin core dates and creators",
+			fixture:   "rss_dublin_core.xml",
+			sourceURL: "https://blog.test/feed.xml",
+			want: []normalizedItem{
+				{"https://blog.test/dc", "Ana Souza, Bruno Lima", time.Date(2024, time.March, 5, 10, 20, 30, 0, time.UTC), models.PubDateDublinCore},
+				{"https://blog.test/published", unknownAuthor, time.Date(2024, time.March, 5, 11, 0, 0, 0, time.UTC), models.PubDatePublished},
+			},
+		},
+		{
+			name:      "atom update dates and multiple authors",
+			fixture:   "atom_updated.xml",
+			sourceURL: "https://blog.test/atom.xml",
+			want: []normalizedItem{
+				{"https://blog.test/updated", "Ana Souza, Bruno Lima", time.Date(2024, time.March, 6, 12, 0, 0, 0, time.UTC), models.PubDateUpdated},
+				{"https://blog.test/feed-author", "Equipe do Blog", time.Date(2024, time.March, 4, 9, 0, 0, 0, time.UTC), models.PubDatePublished},
+			},
+		},
+		{
+			name:      "relative links resolve against the site link",
+			fixture:   "rss_relative_links.xml",
+			sourceURL: "https://blog.test/feed.xml",
+			want: []normalizedItem{
+				{"https://blog.test/posts/1", unknownAuthor, time.Date(2024, time.March, 5, 8, 0, 0, 0, time.UTC), models.PubDatePublished},
+				{"https://blog.test/news/post-2", unknownAuthor, time.Date(2024, time.March, 5, 8, 0, 0, 0, time.UTC), models.PubDatePublished},
+				{"https://blog.test/posts/3", unknownAuthor, time.Date(2024, time.March, 5, 8, 0, 0, 0, time.UTC), models.PubDatePublished},
+			},
+		},
+		{
+			name:      "relative links resolve against the source url without a site link",
+			fixture:   "rss_no_base.xml",
+			sourceURL: "https://feeds.test/blog/rss.xml",
+			want: []normalizedItem{
+				{"https://feeds.test/blog/entry?id=4", unknownAuthor, time.Date(2024, time.March, 5, 8, 0, 0, 0, time.UTC), models.PubDatePublished},
+				{"https://other.test/5", unknownAuthor, time.Date(2024, time.March, 5, 8, 0, 0, 0, time.UTC), models.PubDatePublished},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			feed := parseFixture(t, test.fixture)
+			if len(feed.Items) != len(test.want) {
+				t.Fatalf("fixture has %d items, want %d", len(feed.Items), len(test.want))
+			}
+
+			base := feedBaseURL(test.sourceURL, feed)
+			for i, item := range feed.Items {
+				article := normalizeItem(1, feed, base, item, seenAt)
+				want := test.want[i]
+				if article.Link != want.link {
+					t.Errorf("item %d: link = %q, want %q", i, article.Link, want.link)
+				}
+				if article.Author != want.author {
+					t.Errorf("item %d: author = %q, want %q", i, article.Author, want.author)
+				}
+				if !article.PubDate.Equal(want.pubDate) {
+					t.Errorf("item %d: pub date = %s, want %s", i, article.PubDate, want.pubDate)
+				}
+				if article.PubDateSource != want.pubDateSource {
+					t.Errorf("item %d: pub date source = %q, want %q", i, article.PubDateSource, want.pubDateSource)
+				}
+			}
+		})
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+		ok    bool
+	}{
+		{"2024-03-05T10:20:30Z", time.Date(2024, time.March, 5, 10, 20, 30, 0, time.UTC), true},
+		{"2024-03-05T10:20:30-0300", time.Date(2024, time.March, 5, 13, 20, 30, 0, time.UTC), true},
+		{" 2024-03-05 ", time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC), true},
+		{"Tue, 5 Mar 2024 10:20:30 +0000", time.Date(2024, time.March, 5, 10, 20, 30, 0, time.UTC), true},
+		{"1960-01-01", time.Time{}, false},
+		{"yesterday", time.Time{}, false},
+	}
+
+	for _, test := range tests {
+		got, ok := parseDate(test.value)
+		if ok != test.ok || !got.Equal(test.want) {
+			t.Errorf("parseDate(%q) = %s, %t, want %s, %t", test.value, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func parseFixture(t *testing.T, name string) *gofeed.Feed {
+	t.Helper()
+	file, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	feed, err := gofeed.NewParser().Parse(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return feed
+}
@@ -0,0 +1,190 @@
+package fetcher
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHostRequestsPerMinute = 60
+	defaultHostBurst             = 5
+	defaultHostMaxInFlight       = 2
+	defaultRetryAfter            = time.Minute
+	maxRetryAfter                = 24 * time.Hour
+)
+
+var (
+	errRateLimited = errors.New("host rate limited")
+	errHostBusy    = errors.New("host has too many requests in flight")
+)
+
+// retryError reports that the host must not be requested again before until.
+type retryError struct {
+	err   error
+	host  string
+	until time.Time
+}
+
+func (e *retryError) Error() string {
+	return e.err.Error() + ": " + e.host + " can be retried after " + e.until.Format(time.RFC1123)
+}
+
+func (e *retryError) Unwrap() error {
+	return e.err
+}
+
+type hostState struct {
+	tokens     float64
+	lastRefill time.Time
+	retryAfter time.Time
+	slots      chan struct{}
+}
+
+type hostLimiter struct {
+	mu          sync.Mutex
+	hosts       map[string]*hostState
+	ratePerSec  float64
+	burst       float64
+	maxInFlight int
+	slotTimeout time.Duration
+}
+
+func newHostLimiter(requestsPerMinute, burst, maxInFlight int, slotTimeout time.Duration) *hostLimiter {
+	if requestsPerMinute <= 0 {
+		requestsPerMinute = defaultHostRequestsPerMinute
+	}
+	if burst <= 0 {
+		burst = defaultHostBurst
+	}
+	if maxInFlight <= 0 {
+		maxInFlight = defaultHostMaxInFlight
+	}
+	return &hostLimiter{
+		hosts:       make(map[string]*hostState),
+		ratePerSec:  float64(requestsPerMinute) / 60,
+		burst:       float64(burst),
+		maxInFlight: maxInFlight,
+		slotTimeout: slotTimeout,
+	}
+}
+
+func (l *hostLimiter) state(host string) *hostState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state, exists := l.hosts[host]
+	if !exists {
+		state = &hostState{
+			tokens:     l.burst,
+			lastRefill: time.Now(),
+			slots:      make(chan struct{}, l.maxInFlight),
+		}
+		l.hosts[host] = state
+	}
+	return state
+}
+
+// acquire blocks until the host has a free request slot and a token available,
+// giving up after slotTimeout when the slots stay taken. The returned function
+// must be called once the response has been consumed.
+func (l *hostLimiter) acquire(host string) (func(), error) {
+	state := l.state(host)
+
+	l.mu.Lock()
+	retryAfter := state.retryAfter
+	l.mu.Unlock()
+	if time.Now().Before(retryAfter) {
+		return nil, &retryError{err: errRateLimited, host: host, until: retryAfter}
+	}
+
+	timer := time.NewTimer(l.slotTimeout)
+	select {
+	case state.slots <- struct{}{}:
+		timer.Stop()
+	case <-timer.C:
+		return nil, &retryError{err: errHostBusy, host: host, until: time.Now().Add(l.slotTimeout)}
+	}
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		state.tokens += now.Sub(state.lastRefill).Seconds() * l.ratePerSec
+		if state.tokens > l.burst {
+			state.tokens = l.burst
+		}
+		state.lastRefill = now
+
+		if state.tokens >= 1 {
+			state.tokens--
+			l.mu.Unlock()
+			break
+		}
+		wait := time.Duration((1 - state.tokens) / l.ratePerSec * float64(time.Second))
+		l.mu.Unlock()
+		time.Sleep(wait)
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-state.slots
+		})
+	}, nil
+}
+
+// observe records the Retry-After of a 429 or 503 response and returns the
+// error the request must fail with, or nil when the host did not throttle it.
+func (l *hostLimiter) observe(host string, resp *http.Response) error {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return nil
+	}
+
+	delay := parseRetryAfter(resp.Header.Get("Retry-After"))
+	if delay <= 0 {
+		if resp.StatusCode != http.StatusTooManyRequests {
+			return nil
+		}
+		delay = defaultRetryAfter
+	}
+	if delay > maxRetryAfter {
+		delay = maxRetryAfter
+	}
+
+	state := l.state(host)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	until := time.Now().Add(delay)
+	if until.After(state.retryAfter) {
+		state.retryAfter = until
+	}
+	return &retryError{err: errRateLimited, host: host, until: state.retryAfter}
+}
+
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
+
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+}
+
+func (r *releaseOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.release()
+	return err
+}
@@ -0,0 +1,125 @@
+package fetcher
+
+import (
+	"bytes"
+	"encoding/xml"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"github.com/mmcdole/gofeed"
+	"net/http"
+	"strings"
+)
+
+const (
+	customHubLink  = "websub_hub"
+	customSelfLink = "websub_self"
+)
+
+// SetHubObserver registers the subscriber notified of the WebSub hubs found in
+// source feeds. It must be called before the scheduler starts.
+func (f *Fetcher) SetHubObserver(observer interfaces.HubObserver) {
+	f.hubObserver = observer
+}
+
+func (f *Fetcher) observeHub(source models.Source, feed *gofeed.Feed) {
+	if f.hubObserver == nil || feed == nil || feed.Custom[customHubLink] == "" {
+		return
+	}
+
+	topic := feed.Custom[customSelfLink]
+	if topic == "" {
+		topic = source.Url
+	}
+	f.hubObserver.Observe(source, feed.Custom[customHubLink], topic)
+}
+
+// setHubLinks keeps the hub and self links of the feed in feed.Custom, as the
+// parser drops them for Atom feeds. Link headers take precedence over the body.
+func setHubLinks(feed *gofeed.Feed, body []byte, header http.Header) {
+	hub, self := linkHeaderHubLinks(header)
+	if hub == "" || self == "" {
+		bodyHub, bodySelf := bodyHubLinks(body)
+		if hub == "" {
+			hub = bodyHub
+		}
+		if self == "" {
+			self = bodySelf
+		}
+	}
+	if hub == "" {
+		return
+	}
+
+	if feed.Custom == nil {
+		feed.Custom = make(map[string]string)
+	}
+	feed.Custom[customHubLink] = hub
+	if self != "" {
+		feed.Custom[customSelfLink] = self
+	}
+}
+
+func linkHeaderHubLinks(header http.Header) (string, string) {
+	var hub, self string
+	for _, value := range header.Values("Link") {
+		for _, link := range strings.Split(value, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.Trim(strings.TrimSpace(parts[0]), "<>")
+			for _, param := range parts[1:] {
+				key, rel, found := strings.Cut(strings.TrimSpace(param), "=")
+				if !found || !strings.EqualFold(key, "rel") {
+					continue
+				}
+				switch {
+				case hasRel(strings.Trim(rel, `"`), "hub") && hub == "":
+					hub = target
+				case hasRel(strings.Trim(rel, `"`), "self") && self == "":
+					self = target
+				}
+			}
+		}
+	}
+	return hub, self
+}
+
+// bodyHubLinks reads the feed level <link rel="hub"> and <link rel="self">
+// elements of RSS and Atom documents, stopping at the first item.
+func bodyHubLinks(body []byte) (string, string) {
+	var hub, self string
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return hub, self
+		}
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch element.Name.Local {
+		case "item", "entry":
+			return hub, self
+		case "link":
+			var rel, href string
+			for _, attr := range element.Attr {
+				switch attr.Name.Local {
+				case "rel":
+					rel = attr.Value
+				case "href":
+					href = strings.TrimSpace(attr.Value)
+				}
+			}
+			if href == "" {
+				continue
+			}
+			if hasRel(rel, "hub") && hub == "" {
+				hub = href
+			}
+			if hasRel(rel, "self") && self == "" {
+				self = href
+			}
+		}
+	}
+}
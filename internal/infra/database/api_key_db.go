@@ -0,0 +1,129 @@
+package database
+
+import (
+	"database/sql"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"github.com/matheusvidal21/smart-news-fetcher/pkg/utils"
+	"strings"
+	"time"
+)
+
+const apiKeyColumns = "id, user_id, name, prefix, key_hash, scopes, created_at, expires_at, last_used_at, revoked_at"
+
+type APIKeyRepository struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepository(db *sql.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+func scanAPIKey(row rowScanner) (models.APIKey, error) {
+	var key models.APIKey
+	var scopes string
+	var createdAt, expiresAt, lastUsedAt, revokedAt []byte
+	err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash, &scopes, &createdAt, &expiresAt, &lastUsedAt, &revokedAt)
+	if err != nil {
+		return models.APIKey{}, err
+	}
+
+	key.Scopes = []string{}
+	if scopes != "" {
+		key.Scopes = strings.Split(scopes, ",")
+	}
+	if key.CreatedAt, err = utils.ParseTime(createdAt); err != nil {
+		return models.APIKey{}, err
+	}
+	if key.ExpiresAt, err = utils.ParseTime(expiresAt); err != nil {
+		return models.APIKey{}, err
+	}
+	if key.LastUsedAt, err = utils.ParseTime(lastUsedAt); err != nil {
+		return models.APIKey{}, err
+	}
+	if key.RevokedAt, err = utils.ParseTime(revokedAt); err != nil {
+		return models.APIKey{}, err
+	}
+	return key, nil
+}
+
+func (kr *APIKeyRepository) FindOne(id int) (models.APIKey, error) {
+	stmt, err := kr.db.Prepare("SELECT " + apiKeyColumns + " FROM api_keys WHERE id = ?")
+	if err != nil {
+		return models.APIKey{}, err
+	}
+	defer stmt.Close()
+
+	return scanAPIKey(stmt.QueryRow(id))
+}
+
+func (kr *APIKeyRepository) FindByHash(keyHash string) (models.APIKey, error) {
+	stmt, err := kr.db.Prepare("SELECT " + apiKeyColumns + " FROM api_keys WHERE key_hash = ?")
+	if err != nil {
+		return models.APIKey{}, err
+	}
+	defer stmt.Close()
+
+	return scanAPIKey(stmt.QueryRow(keyHash))
+}
+
+// FindByUserId returns the keys of the user that were not revoked, newest
+// first.
+func (kr *APIKeyRepository) FindByUserId(userID int) ([]models.APIKey, error) {
+	rows, err := kr.db.Query("SELECT "+apiKeyColumns+" FROM api_keys WHERE user_id = ? AND revoked_at IS NULL ORDER BY created_at DESC, id DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (kr *APIKeyRepository) Create(key models.APIKey) (models.APIKey, error) {
+	stmt, err := kr.db.Prepare("INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return models.APIKey{}, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.Exec(key.UserID, key.Name, key.Prefix, key.KeyHash, strings.Join(key.Scopes, ","), key.CreatedAt, nullTime(key.ExpiresAt))
+	if err != nil {
+		return models.APIKey{}, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return models.APIKey{}, err
+	}
+	key.ID = int(id)
+	return key, nil
+}
+
+func (kr *APIKeyRepository) UpdateLastUsed(id int, lastUsedAt time.Time) error {
+	stmt, err := kr.db.Prepare("UPDATE api_keys SET last_used_at = ? WHERE id = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(lastUsedAt, id)
+	return err
+}
+
+func (kr *APIKeyRepository) Revoke(id int, revokedAt time.Time) error {
+	stmt, err := kr.db.Prepare("UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(revokedAt, id)
+	return err
+}
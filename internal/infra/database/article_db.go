@@ -4,8 +4,12 @@ import (
 	"database/sql"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
 	"github.com/matheusvidal21/smart-news-fetcher/pkg/utils"
+	"strings"
+	"time"
 )
 
+const articleColumns = "a.id, a.title, a.description, a.content, a.link, a.pub_date, a.author, a.source_id, a.guid, a.content_hash, a.pub_date_source, a.plain_text, a.extracted_content, a.extracted_at"
+
 type ArticleRepository struct {
 	db *sql.DB
 }
@@ -14,97 +18,113 @@ func NewArticleRepository(db *sql.DB) *ArticleRepository {
 	return &ArticleRepository{db: db}
 }
 
-func (ar *ArticleRepository) FindAll(page, limit int, sort string) ([]models.Article, error) {
-	sql := "SELECT id, title, description, content, link, pub_date, author, source_id FROM articles"
-
-	offset := (page - 1) * limit
-
-	if sort != "" && sort != "asc" && sort != "desc" {
-		sort = "asc"
+// scanArticle reads the articleColumns of the row, then the extra columns
+// selected after them.
+func scanArticle(row rowScanner, extra ...interface{}) (models.Article, error) {
+	var article models.Article
+	var pubDate, extractedAt []byte
+	dest := []interface{}{&article.ID, &article.Title, &article.Description, &article.Content, &article.Link, &pubDate, &article.Author, &article.SourceID, &article.Guid, &article.ContentHash, &article.PubDateSource, &article.PlainText, &article.ExtractedContent, &extractedAt}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return models.Article{}, err
 	}
 
-	if page != 0 && limit != 0 {
-		sql = sql + "  ORDER BY pub_date " + sort + " LIMIT ? OFFSET ? "
-	} else {
-		sql = sql + " ORDER BY pub_date " + sort
+	article.PubDate, err = utils.ParseTime(pubDate)
+	if err != nil {
+		return models.Article{}, err
 	}
-
-	rows, err := ar.db.Query(sql, limit, offset)
+	article.ExtractedAt, err = utils.ParseTime(extractedAt)
 	if err != nil {
-		return nil, err
+		return models.Article{}, err
 	}
-	defer rows.Close()
+	return article, nil
+}
+
+func scanArticles(rows *sql.Rows) ([]models.Article, error) {
 	var articles []models.Article
-	var pubDate []byte
 	for rows.Next() {
-		var article models.Article
-		err = rows.Scan(&article.ID, &article.Title, &article.Description, &article.Content, &article.Link, &pubDate, &article.Author, &article.SourceID)
-		if err != nil {
-			return nil, err
-		}
-		article.PubDate, err = utils.ParseTime(pubDate)
+		article, err := scanArticle(rows)
 		if err != nil {
 			return nil, err
 		}
 		articles = append(articles, article)
 	}
-	return articles, nil
+	return articles, rows.Err()
 }
 
-func (ar *ArticleRepository) FindOne(id string) (models.Article, error) {
-	stmt, err := ar.db.Prepare("SELECT id, title, description, content, link, pub_date, author, source_id FROM articles WHERE id = ?")
-	if err != nil {
-		return models.Article{}, err
+// FindAll returns the articles matching the filter ordered by date, ascending
+// or descending.
+func (ar *ArticleRepository) FindAll(filter models.ArticleFilter, page, limit int, sort string) ([]models.Article, error) {
+	clauses, args := filterClauses(filter)
+	sql := "SELECT " + articleColumns + " FROM articles a" + clauses
+
+	offset := (page - 1) * limit
+
+	if sort != "" && sort != "asc" && sort != "desc" {
+		sort = "asc"
 	}
-	defer stmt.Close()
 
-	var article models.Article
-	var pubDate []byte
-	err = stmt.QueryRow(id).Scan(
-		&article.ID, &article.Title, &article.Description,
-		&article.Content, &article.Link, &pubDate, &article.Author, &article.SourceID)
+	if page != 0 && limit != 0 {
+		sql = sql + "  ORDER BY a.pub_date " + sort + " LIMIT ? OFFSET ? "
+		args = append(args, limit, offset)
+	} else {
+		sql = sql + " ORDER BY a.pub_date " + sort
+	}
+
+	rows, err := ar.db.Query(sql, args...)
 	if err != nil {
-		return models.Article{}, err
+		return nil, err
 	}
+	defer rows.Close()
+	return scanArticles(rows)
+}
 
-	article.PubDate, err = utils.ParseTime(pubDate)
+func (ar *ArticleRepository) FindOne(id string) (models.Article, error) {
+	stmt, err := ar.db.Prepare("SELECT " + articleColumns + " FROM articles a WHERE a.id = ?")
 	if err != nil {
 		return models.Article{}, err
 	}
+	defer stmt.Close()
 
-	return article, nil
+	return scanArticle(stmt.QueryRow(id))
 }
 
 func (ar *ArticleRepository) Create(article models.Article) (models.Article, error) {
-	stmt, err := ar.db.Prepare("INSERT INTO articles (id, title, description, content, link, pub_date, author, source_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?)")
+	stmt, err := ar.db.Prepare("INSERT INTO articles (id, title, description, content, link, pub_date, author, source_id, guid, content_hash, pub_date_source, plain_text, extracted_content) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
 	if err != nil {
 		return models.Article{}, err
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(article.ID, article.Title, article.Description, article.Content, article.Link, &article.PubDate, article.Author, article.SourceID)
+	_, err = stmt.Exec(article.ID, article.Title, article.Description, article.Content, article.Link, &article.PubDate, article.Author, article.SourceID, article.Guid, article.ContentHash, article.PubDateSource, article.PlainText, article.ExtractedContent)
 	if err != nil {
 		return models.Article{}, err
 	}
 
 	return models.Article{
-		ID:          article.ID,
-		Title:       article.Title,
-		Description: article.Description,
-		Link:        article.Link,
-		PubDate:     article.PubDate,
-		Author:      article.Author,
-		SourceID:    article.SourceID,
+		ID:               article.ID,
+		Title:            article.Title,
+		Description:      article.Description,
+		Content:          article.Content,
+		Link:             article.Link,
+		PubDate:          article.PubDate,
+		Author:           article.Author,
+		SourceID:         article.SourceID,
+		Guid:             article.Guid,
+		ContentHash:      article.ContentHash,
+		PubDateSource:    article.PubDateSource,
+		PlainText:        article.PlainText,
+		ExtractedContent: article.ExtractedContent,
 	}, nil
 }
 
 func (ar *ArticleRepository) Update(id string, article models.Article) (models.Article, error) {
-	stmt, err := ar.db.Prepare("UPDATE articles set title = ?, description = ?, content = ?, link = ?, pub_date = ?, author = ?, source_id = ? WHERE id = ?")
+	stmt, err := ar.db.Prepare("UPDATE articles set title = ?, description = ?, content = ?, link = ?, pub_date = ?, author = ?, source_id = ?, guid = ?, content_hash = ?, pub_date_source = ?, plain_text = ? WHERE id = ?")
 	if err != nil {
 		return models.Article{}, err
 	}
 	defer stmt.Close()
-	_, err = stmt.Exec(article.Title, article.Description, article.Content, article.Link, article.PubDate, article.Author, article.SourceID, id)
+	_, err = stmt.Exec(article.Title, article.Description, article.Content, article.Link, article.PubDate, article.Author, article.SourceID, article.Guid, article.ContentHash, article.PubDateSource, article.PlainText, id)
 	if err != nil {
 		return models.Article{}, err
 	}
@@ -117,6 +137,60 @@ func (ar *ArticleRepository) Update(id string, article models.Article) (models.A
 	return uptadedArticle, nil
 }
 
+// UpdateExtractedContent stores the body extracted from the article page along
+// with the plain text derived from it.
+func (ar *ArticleRepository) UpdateExtractedContent(id, extractedContent, plainText string, extractedAt time.Time) error {
+	stmt, err := ar.db.Prepare("UPDATE articles SET extracted_content = ?, plain_text = ?, extracted_at = ? WHERE id = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(extractedContent, plainText, extractedAt, id)
+	return err
+}
+
+// FindWithoutPlainText returns, ordered by id, the articles after afterID whose
+// plain text is empty, like those stored before the column was added.
+func (ar *ArticleRepository) FindWithoutPlainText(afterID string, limit int) ([]models.Article, error) {
+	stmt, err := ar.db.Prepare("SELECT " + articleColumns + " FROM articles a WHERE a.plain_text = '' AND a.id > ? ORDER BY a.id LIMIT ?")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanArticles(rows)
+}
+
+func (ar *ArticleRepository) UpdatePlainText(id, plainText string) error {
+	stmt, err := ar.db.Prepare("UPDATE articles SET plain_text = ? WHERE id = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(plainText, id)
+	return err
+}
+
+// Rekey moves an article to a new id. Revisions follow through ON UPDATE CASCADE.
+func (ar *ArticleRepository) Rekey(oldID, newID string) error {
+	stmt, err := ar.db.Prepare("UPDATE articles SET id = ? WHERE id = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(newID, oldID)
+	return err
+}
+
 func (ar *ArticleRepository) Delete(id string) error {
 	stmt, err := ar.db.Prepare("DELETE FROM articles WHERE id = ?")
 	if err != nil {
@@ -131,7 +205,7 @@ func (ar *ArticleRepository) Delete(id string) error {
 }
 
 func (ar *ArticleRepository) FindAllBySourceId(sourceID int) ([]models.Article, error) {
-	stmt, err := ar.db.Prepare("SELECT * FROM articles WHERE source_id = ?")
+	stmt, err := ar.db.Prepare("SELECT " + articleColumns + " FROM articles a WHERE a.source_id = ?")
 	if err != nil {
 		return nil, err
 	}
@@ -143,20 +217,126 @@ func (ar *ArticleRepository) FindAllBySourceId(sourceID int) ([]models.Article,
 	}
 	defer rows.Close()
 
-	var articles []models.Article
-	var pubDate []byte
-	for rows.Next() {
-		var article models.Article
-		err = rows.Scan(&article.ID, &article.Title, &article.Description, &article.Content, &article.Link, &pubDate, &article.Author, &article.SourceID)
-		if err != nil {
-			return nil, err
+	return scanArticles(rows)
+}
+
+const articleMatch = "MATCH(a.title, a.plain_text) AGAINST (? IN NATURAL LANGUAGE MODE)"
+
+// filterClauses turns the filter into the joins and WHERE clause shared by the
+// filtered queries.
+func filterClauses(filter models.ArticleFilter) (string, []interface{}) {
+	var joins string
+	var joinArgs []interface{}
+	var conditions []string
+	var args []interface{}
+
+	if filter.UserID != 0 {
+		joins += " JOIN sources s ON s.id = a.source_id"
+		conditions = append(conditions, "s.user_id = ?")
+		args = append(args, filter.UserID)
+	}
+	if filter.ReaderID != 0 && (filter.UnreadOnly || filter.Starred) {
+		joins += " LEFT JOIN user_article_state st ON st.article_id = a.id AND st.user_id = ?"
+		joinArgs = append(joinArgs, filter.ReaderID)
+		if filter.UnreadOnly {
+			conditions = append(conditions, "COALESCE(st.is_read, FALSE) = FALSE")
 		}
-		article.PubDate, err = utils.ParseTime(pubDate)
-		if err != nil {
-			return nil, err
+		if filter.Starred {
+			conditions = append(conditions, "st.is_starred = TRUE")
 		}
-		articles = append(articles, article)
 	}
+	if filter.SourceID != 0 {
+		conditions = append(conditions, "a.source_id = ?")
+		args = append(args, filter.SourceID)
+	}
+	if len(filter.SourceIDs) > 0 {
+		conditions = append(conditions, "a.source_id IN (?"+strings.Repeat(", ?", len(filter.SourceIDs)-1)+")")
+		for _, sourceID := range filter.SourceIDs {
+			args = append(args, sourceID)
+		}
+	}
+	if filter.Query != "" {
+		conditions = append(conditions, articleMatch)
+		args = append(args, filter.Query)
+	}
+	if filter.Author != "" {
+		conditions = append(conditions, "a.author LIKE ?")
+		args = append(args, "%"+escapeLike(filter.Author)+"%")
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "a.pub_date >= ?")
+		args = append(args, filter.From)
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "a.pub_date <= ?")
+		args = append(args, filter.To)
+	}
+
+	if len(conditions) > 0 {
+		joins += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	return joins, append(joinArgs, args...)
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(value)
+}
+
+// FindFiltered returns the articles matching the filter, newest first.
+func (ar *ArticleRepository) FindFiltered(filter models.ArticleFilter, page, limit int) ([]models.Article, error) {
+	clauses, args := filterClauses(filter)
+	query := "SELECT " + articleColumns + " FROM articles a" + clauses + " ORDER BY a.pub_date DESC, a.id DESC"
+	if page > 0 && limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, limit, (page-1)*limit)
+	}
+
+	rows, err := ar.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	return articles, nil
+	articles, err := scanArticles(rows)
+	if articles == nil && err == nil {
+		articles = []models.Article{}
+	}
+	return articles, err
+}
+
+// Search returns a page of the articles matching the filter, ordered by
+// relevance to filter.Query or by date, along with the number of matches.
+func (ar *ArticleRepository) Search(filter models.ArticleFilter, sort string, page, limit int) ([]models.ArticleMatch, int, error) {
+	clauses, args := filterClauses(filter)
+
+	var total int
+	err := ar.db.QueryRow("SELECT COUNT(*) FROM articles a"+clauses, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	order := " ORDER BY score DESC, a.pub_date DESC, a.id DESC"
+	if sort == models.ArticleSortDate {
+		order = " ORDER BY a.pub_date DESC, a.id DESC"
+	}
+	query := "SELECT " + articleColumns + ", " + articleMatch + " AS score FROM articles a" + clauses + order + " LIMIT ? OFFSET ?"
+	args = append([]interface{}{filter.Query}, args...)
+	args = append(args, limit, (page-1)*limit)
+
+	rows, err := ar.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	matches := []models.ArticleMatch{}
+	for rows.Next() {
+		var match models.ArticleMatch
+		match.Article, err = scanArticle(rows, &match.Score)
+		if err != nil {
+			return nil, 0, err
+		}
+		matches = append(matches, match)
+	}
+	return matches, total, rows.Err()
 }
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"database/sql"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"github.com/matheusvidal21/smart-news-fetcher/pkg/utils"
+)
+
+type ArticleRevisionRepository struct {
+	db *sql.DB
+}
+
+func NewArticleRevisionRepository(db *sql.DB) *ArticleRevisionRepository {
+	return &ArticleRevisionRepository{db: db}
+}
+
+func (rr *ArticleRevisionRepository) Create(revision models.ArticleRevision) (models.ArticleRevision, error) {
+	stmt, err := rr.db.Prepare("INSERT INTO article_revisions (article_id, title, description, content, link, author, pub_date, content_hash, revised_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return models.ArticleRevision{}, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.Exec(revision.ArticleID, revision.Title, revision.Description, revision.Content, revision.Link, revision.Author, revision.PubDate, revision.ContentHash, revision.RevisedAt)
+	if err != nil {
+		return models.ArticleRevision{}, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return models.ArticleRevision{}, err
+	}
+
+	revision.ID = int(id)
+	return revision, nil
+}
+
+func (rr *ArticleRevisionRepository) FindByArticleId(articleID string) ([]models.ArticleRevision, error) {
+	rows, err := rr.db.Query("SELECT id, article_id, title, description, content, link, author, pub_date, content_hash, revised_at FROM article_revisions WHERE article_id = ? ORDER BY revised_at DESC, id DESC", articleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []models.ArticleRevision{}
+	for rows.Next() {
+		var revision models.ArticleRevision
+		var pubDate, revisedAt []byte
+		err = rows.Scan(&revision.ID, &revision.ArticleID, &revision.Title, &revision.Description, &revision.Content, &revision.Link, &revision.Author, &pubDate, &revision.ContentHash, &revisedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		revision.PubDate, err = utils.ParseTime(pubDate)
+		if err != nil {
+			return nil, err
+		}
+		revision.RevisedAt, err = utils.ParseTime(revisedAt)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
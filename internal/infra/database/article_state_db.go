@@ -0,0 +1,119 @@
+package database
+
+import (
+	"database/sql"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"github.com/matheusvidal21/smart-news-fetcher/pkg/utils"
+	"time"
+)
+
+type ArticleStateRepository struct {
+	db *sql.DB
+}
+
+func NewArticleStateRepository(db *sql.DB) *ArticleStateRepository {
+	return &ArticleStateRepository{db: db}
+}
+
+// FindOne returns the state of the article for the user, sql.ErrNoRows when
+// the user never changed it.
+func (sr *ArticleStateRepository) FindOne(userID int, articleID string) (models.ArticleState, error) {
+	stmt, err := sr.db.Prepare("SELECT user_id, article_id, is_read, read_at, is_starred, starred_at, is_archived, archived_at FROM user_article_state WHERE user_id = ? AND article_id = ?")
+	if err != nil {
+		return models.ArticleState{}, err
+	}
+	defer stmt.Close()
+
+	var state models.ArticleState
+	var readAt, starredAt, archivedAt []byte
+	err = stmt.QueryRow(userID, articleID).Scan(&state.UserID, &state.ArticleID, &state.Read, &readAt, &state.Starred, &starredAt, &state.Archived, &archivedAt)
+	if err != nil {
+		return models.ArticleState{}, err
+	}
+
+	if state.ReadAt, err = utils.ParseTime(readAt); err != nil {
+		return models.ArticleState{}, err
+	}
+	if state.StarredAt, err = utils.ParseTime(starredAt); err != nil {
+		return models.ArticleState{}, err
+	}
+	if state.ArchivedAt, err = utils.ParseTime(archivedAt); err != nil {
+		return models.ArticleState{}, err
+	}
+	return state, nil
+}
+
+func (sr *ArticleStateRepository) Save(state models.ArticleState) error {
+	stmt, err := sr.db.Prepare("INSERT INTO user_article_state (user_id, article_id, is_read, read_at, is_starred, starred_at, is_archived, archived_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?) " +
+		"ON DUPLICATE KEY UPDATE is_read = VALUES(is_read), read_at = VALUES(read_at), is_starred = VALUES(is_starred), starred_at = VALUES(starred_at), is_archived = VALUES(is_archived), archived_at = VALUES(archived_at)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(state.UserID, state.ArticleID, state.Read, nullTime(state.ReadAt), state.Starred, nullTime(state.StarredAt), state.Archived, nullTime(state.ArchivedAt))
+	return err
+}
+
+// MarkRead marks the unread articles matching the filter as read for the user
+// and returns how many there were.
+func (sr *ArticleStateRepository) MarkRead(userID int, filter models.ArticleFilter, readAt time.Time) (int, error) {
+	filter.ReaderID = userID
+	filter.UnreadOnly = true
+	clauses, args := filterClauses(filter)
+
+	tx, err := sr.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var count int
+	err = tx.QueryRow("SELECT COUNT(*) FROM articles a"+clauses, args...).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		return 0, nil
+	}
+
+	query := "INSERT INTO user_article_state (user_id, article_id, is_read, read_at) SELECT ?, a.id, TRUE, ? FROM articles a" + clauses +
+		" ON DUPLICATE KEY UPDATE is_read = TRUE, read_at = VALUES(read_at)"
+	_, err = tx.Exec(query, append([]interface{}{userID, readAt}, args...)...)
+	if err != nil {
+		return 0, err
+	}
+	return count, tx.Commit()
+}
+
+// CountUnread returns the number of articles of each source the user has not
+// read yet.
+func (sr *ArticleStateRepository) CountUnread(userID int, sourceIDs []int) (map[int]int, error) {
+	counts := make(map[int]int)
+	if len(sourceIDs) == 0 {
+		return counts, nil
+	}
+
+	clauses, args := filterClauses(models.ArticleFilter{SourceIDs: sourceIDs, ReaderID: userID, UnreadOnly: true})
+	rows, err := sr.db.Query("SELECT a.source_id, COUNT(*) FROM articles a"+clauses+" GROUP BY a.source_id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sourceID, count int
+		if err := rows.Scan(&sourceID, &count); err != nil {
+			return nil, err
+		}
+		counts[sourceID] = count
+	}
+	return counts, rows.Err()
+}
+
+func nullTime(value time.Time) interface{} {
+	if value.IsZero() {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"database/sql"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"github.com/matheusvidal21/smart-news-fetcher/pkg/utils"
+)
+
+type FetchRunRepository struct {
+	db *sql.DB
+}
+
+func NewFetchRunRepository(db *sql.DB) *FetchRunRepository {
+	return &FetchRunRepository{db: db}
+}
+
+func (fr *FetchRunRepository) Create(run models.FetchRun) (models.FetchRun, error) {
+	stmt, err := fr.db.Prepare("INSERT INTO fetch_runs (source_id, started_at, duration_ms, http_status, items_seen, items_inserted, items_updated, error_message) VALUES (?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return models.FetchRun{}, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.Exec(run.SourceID, run.StartedAt, run.DurationMs, run.HTTPStatus, run.ItemsSeen, run.ItemsInserted, run.ItemsUpdated, run.Error)
+	if err != nil {
+		return models.FetchRun{}, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return models.FetchRun{}, err
+	}
+
+	run.ID = int(id)
+	return run, nil
+}
+
+func (fr *FetchRunRepository) FindBySourceId(sourceID, page, limit int) ([]models.FetchRun, error) {
+	offset := (page - 1) * limit
+	rows, err := fr.db.Query("SELECT id, source_id, started_at, duration_ms, http_status, items_seen, items_inserted, items_updated, error_message FROM fetch_runs WHERE source_id = ? ORDER BY started_at DESC, id DESC LIMIT ? OFFSET ?", sourceID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []models.FetchRun
+	for rows.Next() {
+		var run models.FetchRun
+		var startedAt []byte
+		err = rows.Scan(&run.ID, &run.SourceID, &startedAt, &run.DurationMs, &run.HTTPStatus, &run.ItemsSeen, &run.ItemsInserted, &run.ItemsUpdated, &run.Error)
+		if err != nil {
+			return nil, err
+		}
+		run.StartedAt, err = utils.ParseTime(startedAt)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+type RecoveryCodeRepository struct {
+	db *sql.DB
+}
+
+func NewRecoveryCodeRepository(db *sql.DB) *RecoveryCodeRepository {
+	return &RecoveryCodeRepository{db: db}
+}
+
+// Replace swaps every recovery code of the user for the new hashes.
+func (rr *RecoveryCodeRepository) Replace(userID int, codeHashes []string, createdAt time.Time) error {
+	tx, err := rr.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM user_recovery_codes WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO user_recovery_codes (user_id, code_hash, created_at) VALUES (?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, codeHash := range codeHashes {
+		if _, err := stmt.Exec(userID, codeHash, createdAt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Use marks the code as used and reports whether it was still unused.
+func (rr *RecoveryCodeRepository) Use(userID int, codeHash string, usedAt time.Time) (bool, error) {
+	stmt, err := rr.db.Prepare("UPDATE user_recovery_codes SET used_at = ? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL")
+	if err != nil {
+		return false, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.Exec(usedAt, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+func (rr *RecoveryCodeRepository) DeleteByUserId(userID int) error {
+	stmt, err := rr.db.Prepare("DELETE FROM user_recovery_codes WHERE user_id = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(userID)
+	return err
+}
@@ -0,0 +1,150 @@
+package database
+
+import (
+	"database/sql"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"github.com/matheusvidal21/smart-news-fetcher/pkg/utils"
+	"strconv"
+	"strings"
+)
+
+const savedSearchColumns = "id, user_id, name, query, source_ids, author, days, in_newsletter, created_at"
+
+type SavedSearchRepository struct {
+	db *sql.DB
+}
+
+func NewSavedSearchRepository(db *sql.DB) *SavedSearchRepository {
+	return &SavedSearchRepository{db: db}
+}
+
+func scanSavedSearch(row rowScanner) (models.SavedSearch, error) {
+	var search models.SavedSearch
+	var sourceIDs string
+	var createdAt []byte
+	err := row.Scan(&search.ID, &search.UserID, &search.Name, &search.Query, &sourceIDs, &search.Author, &search.Days, &search.InNewsletter, &createdAt)
+	if err != nil {
+		return models.SavedSearch{}, err
+	}
+
+	search.SourceIDs, err = parseIDs(sourceIDs)
+	if err != nil {
+		return models.SavedSearch{}, err
+	}
+	search.CreatedAt, err = utils.ParseTime(createdAt)
+	if err != nil {
+		return models.SavedSearch{}, err
+	}
+	return search, nil
+}
+
+func scanSavedSearches(rows *sql.Rows) ([]models.SavedSearch, error) {
+	searches := []models.SavedSearch{}
+	for rows.Next() {
+		search, err := scanSavedSearch(rows)
+		if err != nil {
+			return nil, err
+		}
+		searches = append(searches, search)
+	}
+	return searches, rows.Err()
+}
+
+// Source ids are kept as a comma separated list.
+func formatIDs(ids []int) string {
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = strconv.Itoa(id)
+	}
+	return strings.Join(values, ",")
+}
+
+func parseIDs(value string) ([]int, error) {
+	ids := []int{}
+	if value == "" {
+		return ids, nil
+	}
+	for _, field := range strings.Split(value, ",") {
+		id, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (sr *SavedSearchRepository) FindOne(id int) (models.SavedSearch, error) {
+	stmt, err := sr.db.Prepare("SELECT " + savedSearchColumns + " FROM saved_searches WHERE id = ?")
+	if err != nil {
+		return models.SavedSearch{}, err
+	}
+	defer stmt.Close()
+
+	return scanSavedSearch(stmt.QueryRow(id))
+}
+
+func (sr *SavedSearchRepository) FindByUserId(userID int) ([]models.SavedSearch, error) {
+	rows, err := sr.db.Query("SELECT "+savedSearchColumns+" FROM saved_searches WHERE user_id = ? ORDER BY name", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanSavedSearches(rows)
+}
+
+// FindInNewsletter returns the searches to include in the newsletter digest.
+func (sr *SavedSearchRepository) FindInNewsletter() ([]models.SavedSearch, error) {
+	rows, err := sr.db.Query("SELECT " + savedSearchColumns + " FROM saved_searches WHERE in_newsletter = TRUE ORDER BY user_id, name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanSavedSearches(rows)
+}
+
+func (sr *SavedSearchRepository) Create(search models.SavedSearch) (models.SavedSearch, error) {
+	stmt, err := sr.db.Prepare("INSERT INTO saved_searches (user_id, name, query, source_ids, author, days, in_newsletter) VALUES (?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return models.SavedSearch{}, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.Exec(search.UserID, search.Name, search.Query, formatIDs(search.SourceIDs), search.Author, search.Days, search.InNewsletter)
+	if err != nil {
+		return models.SavedSearch{}, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return models.SavedSearch{}, err
+	}
+	return sr.FindOne(int(id))
+}
+
+func (sr *SavedSearchRepository) Update(id int, search models.SavedSearch) (models.SavedSearch, error) {
+	stmt, err := sr.db.Prepare("UPDATE saved_searches SET name = ?, query = ?, source_ids = ?, author = ?, days = ?, in_newsletter = ? WHERE id = ?")
+	if err != nil {
+		return models.SavedSearch{}, err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(search.Name, search.Query, formatIDs(search.SourceIDs), search.Author, search.Days, search.InNewsletter, id)
+	if err != nil {
+		return models.SavedSearch{}, err
+	}
+	return sr.FindOne(id)
+}
+
+func (sr *SavedSearchRepository) Delete(id int) error {
+	stmt, err := sr.db.Prepare("DELETE FROM saved_searches WHERE id = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(id)
+	return err
+}
@@ -0,0 +1,150 @@
+package database
+
+import (
+	"database/sql"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"github.com/matheusvidal21/smart-news-fetcher/pkg/utils"
+	"time"
+)
+
+const sessionColumns = "id, user_id, token_hash, previous_token_hash, token_version, user_agent, ip, created_at, last_used_at, expires_at, revoked_at"
+
+type SessionRepository struct {
+	db *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+func scanSession(row rowScanner) (models.Session, error) {
+	var session models.Session
+	var createdAt, lastUsedAt, expiresAt, revokedAt []byte
+	err := row.Scan(&session.ID, &session.UserID, &session.TokenHash, &session.PreviousTokenHash, &session.TokenVersion, &session.UserAgent, &session.IP, &createdAt, &lastUsedAt, &expiresAt, &revokedAt)
+	if err != nil {
+		return models.Session{}, err
+	}
+
+	if session.CreatedAt, err = utils.ParseTime(createdAt); err != nil {
+		return models.Session{}, err
+	}
+	if session.LastUsedAt, err = utils.ParseTime(lastUsedAt); err != nil {
+		return models.Session{}, err
+	}
+	if session.ExpiresAt, err = utils.ParseTime(expiresAt); err != nil {
+		return models.Session{}, err
+	}
+	if session.RevokedAt, err = utils.ParseTime(revokedAt); err != nil {
+		return models.Session{}, err
+	}
+	return session, nil
+}
+
+func (sr *SessionRepository) FindOne(id int) (models.Session, error) {
+	stmt, err := sr.db.Prepare("SELECT " + sessionColumns + " FROM user_sessions WHERE id = ?")
+	if err != nil {
+		return models.Session{}, err
+	}
+	defer stmt.Close()
+
+	return scanSession(stmt.QueryRow(id))
+}
+
+// FindByTokenHash finds the session whose current or previous refresh token
+// has the hash.
+func (sr *SessionRepository) FindByTokenHash(tokenHash string) (models.Session, error) {
+	stmt, err := sr.db.Prepare("SELECT " + sessionColumns + " FROM user_sessions WHERE token_hash = ? OR previous_token_hash = ? LIMIT 1")
+	if err != nil {
+		return models.Session{}, err
+	}
+	defer stmt.Close()
+
+	return scanSession(stmt.QueryRow(tokenHash, tokenHash))
+}
+
+// FindActiveByUserId returns the sessions of the user that can still be used,
+// most recently used first.
+func (sr *SessionRepository) FindActiveByUserId(userID, tokenVersion int, now time.Time) ([]models.Session, error) {
+	rows, err := sr.db.Query("SELECT "+sessionColumns+" FROM user_sessions WHERE user_id = ? AND token_version = ? AND revoked_at IS NULL AND expires_at > ? ORDER BY last_used_at DESC", userID, tokenVersion, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (sr *SessionRepository) Create(session models.Session) (models.Session, error) {
+	stmt, err := sr.db.Prepare("INSERT INTO user_sessions (user_id, token_hash, token_version, user_agent, ip, created_at, last_used_at, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return models.Session{}, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.Exec(session.UserID, session.TokenHash, session.TokenVersion, session.UserAgent, session.IP, session.CreatedAt, session.LastUsedAt, session.ExpiresAt)
+	if err != nil {
+		return models.Session{}, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return models.Session{}, err
+	}
+	session.ID = int(id)
+	return session, nil
+}
+
+// Rotate stores the new refresh token of the session, keeping the presented
+// one as previous, provided it is still current and the session was not
+// revoked meanwhile. It reports whether the session was updated.
+func (sr *SessionRepository) Rotate(session models.Session, presentedHash string) (bool, error) {
+	// MySQL assigns from left to right, so the previous hash is copied before
+	// the current one is replaced.
+	stmt, err := sr.db.Prepare("UPDATE user_sessions SET previous_token_hash = token_hash, token_hash = ?, user_agent = ?, ip = ?, last_used_at = ?, expires_at = ? " +
+		"WHERE id = ? AND token_hash = ? AND revoked_at IS NULL")
+	if err != nil {
+		return false, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.Exec(session.TokenHash, session.UserAgent, session.IP, session.LastUsedAt, session.ExpiresAt, session.ID, presentedHash)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+func (sr *SessionRepository) Revoke(id int, revokedAt time.Time) error {
+	stmt, err := sr.db.Prepare("UPDATE user_sessions SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(revokedAt, id)
+	return err
+}
+
+// DeleteExpired removes the sessions of the user that cannot be used anymore.
+func (sr *SessionRepository) DeleteExpired(userID, tokenVersion int, now time.Time) error {
+	stmt, err := sr.db.Prepare("DELETE FROM user_sessions WHERE user_id = ? AND (expires_at <= ? OR revoked_at IS NOT NULL OR token_version <> ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(userID, now, tokenVersion)
+	return err
+}
@@ -6,6 +6,16 @@ import (
 	"github.com/matheusvidal21/smart-news-fetcher/pkg/utils"
 )
 
+const sourceColumns = "id, name, url, saved_at, user_id, update_interval, category, full_content, subscriber, etag, last_modified, consecutive_failures, disabled, fetch_status, last_error"
+
+// enabledOwner keeps the sources whose owner was not disabled by an
+// administrator.
+const enabledOwner = "user_id NOT IN (SELECT id FROM users WHERE disabled = TRUE)"
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
 type SourceRepository struct {
 	db *sql.DB
 }
@@ -14,35 +24,50 @@ func NewSourceRepository(db *sql.DB) *SourceRepository {
 	return &SourceRepository{db: db}
 }
 
-func (sr *SourceRepository) FindAll(page, limit int, sort string) ([]models.Source, error) {
-	sql := "SELECT id, name, url, saved_at, user_id, update_interval, subscriber FROM sources"
+func scanSource(row rowScanner) (models.Source, error) {
+	var source models.Source
+	var savedAt []byte
+	err := row.Scan(&source.ID, &source.Name, &source.Url, &savedAt, &source.UserID, &source.UpdateInterval, &source.Category, &source.FullContent, &source.Subscriber, &source.ETag, &source.LastModified, &source.ConsecutiveFailures, &source.Disabled, &source.FetchStatus, &source.LastError)
+	if err != nil {
+		return models.Source{}, err
+	}
+
+	source.SavedAt, err = utils.ParseTime(savedAt)
+	if err != nil {
+		return models.Source{}, err
+	}
+	return source, nil
+}
+
+// FindAll returns the sources of the user, or of every user when userID is 0.
+func (sr *SourceRepository) FindAll(userID, page, limit int, sort string) ([]models.Source, error) {
+	sql := "SELECT " + sourceColumns + " FROM sources"
 	offset := (page - 1) * limit
 
 	if sort != "" && sort != "asc" && sort != "desc" {
 		sort = "asc"
 	}
 
+	var args []interface{}
+	if userID != 0 {
+		sql = sql + " WHERE user_id = ?"
+		args = append(args, userID)
+	}
 	if page != 0 && limit != 0 {
 		sql = sql + " ORDER BY saved_at " + sort + " LIMIT ? OFFSET ? "
+		args = append(args, limit, offset)
 	} else {
 		sql = sql + " ORDER BY saved_at " + sort
 	}
 
-	rows, err := sr.db.Query(sql, limit, offset)
+	rows, err := sr.db.Query(sql, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	var sources []models.Source
 	for rows.Next() {
-		var source models.Source
-		var savedAt []byte
-		err = rows.Scan(&source.ID, &source.Name, &source.Url, &savedAt, &source.UserID, &source.UpdateInterval, &source.Subscriber)
-		if err != nil {
-			return nil, err
-		}
-
-		source.SavedAt, err = utils.ParseTime(savedAt)
+		source, err := scanSource(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -52,34 +77,23 @@ func (sr *SourceRepository) FindAll(page, limit int, sort string) ([]models.Sour
 }
 
 func (sr *SourceRepository) FindOne(id int) (models.Source, error) {
-	stmt, err := sr.db.Prepare("SELECT id, name, url, saved_at, user_id, update_interval, subscriber FROM sources WHERE id = ?")
+	stmt, err := sr.db.Prepare("SELECT " + sourceColumns + " FROM sources WHERE id = ?")
 	if err != nil {
 		return models.Source{}, err
 	}
 	defer stmt.Close()
 
-	var source models.Source
-	var savedAt []byte
-	err = stmt.QueryRow(id).Scan(&source.ID, &source.Name, &source.Url, &savedAt, &source.UserID, &source.UpdateInterval, &source.Subscriber)
-	if err != nil {
-		return models.Source{}, err
-	}
-
-	source.SavedAt, err = utils.ParseTime(savedAt)
-	if err != nil {
-		return models.Source{}, err
-	}
-	return source, nil
+	return scanSource(stmt.QueryRow(id))
 }
 
 func (sr *SourceRepository) Create(source models.Source) (models.Source, error) {
-	stmt, err := sr.db.Prepare("INSERT INTO sources (name, url, saved_at, user_id, update_interval) VALUES (?, ?, ?, ?, ?)")
+	stmt, err := sr.db.Prepare("INSERT INTO sources (name, url, saved_at, user_id, update_interval, category, full_content, etag, last_modified) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)")
 	if err != nil {
 		return models.Source{}, err
 	}
 	defer stmt.Close()
 
-	result, err := stmt.Exec(source.Name, source.Url, source.SavedAt, source.UserID, source.UpdateInterval)
+	result, err := stmt.Exec(source.Name, source.Url, source.SavedAt, source.UserID, source.UpdateInterval, source.Category, source.FullContent, source.ETag, source.LastModified)
 	if err != nil {
 		return models.Source{}, err
 	}
@@ -95,19 +109,23 @@ func (sr *SourceRepository) Create(source models.Source) (models.Source, error)
 		Name:           source.Name,
 		Url:            source.Url,
 		UpdateInterval: source.UpdateInterval,
+		Category:       source.Category,
+		FullContent:    source.FullContent,
 		SavedAt:        source.SavedAt,
 		UserID:         source.UserID,
+		ETag:           source.ETag,
+		LastModified:   source.LastModified,
 	}, nil
 }
 
 func (sr *SourceRepository) Update(id int, source models.Source) (models.Source, error) {
-	stmt, err := sr.db.Prepare("UPDATE sources SET name = ?, url = ?, update_interval = ?, subscriber = ? WHERE id = ?")
+	stmt, err := sr.db.Prepare("UPDATE sources SET name = ?, url = ?, update_interval = ?, category = ?, full_content = ?, subscriber = ? WHERE id = ?")
 	if err != nil {
 		return models.Source{}, err
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(source.Name, source.Url, source.UpdateInterval, source.Subscriber, id)
+	_, err = stmt.Exec(source.Name, source.Url, source.UpdateInterval, source.Category, source.FullContent, source.Subscriber, id)
 	if err != nil {
 		return models.Source{}, err
 	}
@@ -134,27 +152,17 @@ func (sr *SourceRepository) Delete(id int) error {
 }
 
 func (sr *SourceRepository) FindByUrl(url string) (models.Source, error) {
-	stmt, err := sr.db.Prepare("SELECT id, name, url, saved_at, user_id, update_interval, subscriber FROM sources WHERE url = ?")
+	stmt, err := sr.db.Prepare("SELECT " + sourceColumns + " FROM sources WHERE url = ?")
 	if err != nil {
 		return models.Source{}, err
 	}
 	defer stmt.Close()
 
-	var source models.Source
-	var savedAt []byte
-	err = stmt.QueryRow(url).Scan(&source.ID, &source.Name, &source.Url, &savedAt, &source.UserID, &source.UpdateInterval, &source.Subscriber)
-	if err != nil {
-		return models.Source{}, err
-	}
-	source.SavedAt, err = utils.ParseTime(savedAt)
-	if err != nil {
-		return models.Source{}, err
-	}
-	return source, nil
+	return scanSource(stmt.QueryRow(url))
 }
 
 func (sr *SourceRepository) FindByUserId(userId int) ([]models.Source, error) {
-	rows, err := sr.db.Query("SELECT id, name, url, saved_at, update_interval, subscriber FROM sources WHERE user_id = ?", userId)
+	rows, err := sr.db.Query("SELECT "+sourceColumns+" FROM sources WHERE user_id = ?", userId)
 	if err != nil {
 		return nil, err
 	}
@@ -162,13 +170,7 @@ func (sr *SourceRepository) FindByUserId(userId int) ([]models.Source, error) {
 
 	var sources []models.Source
 	for rows.Next() {
-		var source models.Source
-		var savedAt []byte
-		err = rows.Scan(&source.ID, &source.Name, &source.Url, &savedAt, &source.UpdateInterval, &source.Subscriber)
-		if err != nil {
-			return nil, err
-		}
-		source.SavedAt, err = utils.ParseTime(savedAt)
+		source, err := scanSource(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -177,22 +179,36 @@ func (sr *SourceRepository) FindByUserId(userId int) ([]models.Source, error) {
 	return sources, nil
 }
 
+// FindAllActive returns the sources subscribed to the newsletter whose owner
+// is not disabled.
 func (sr *SourceRepository) FindAllActive() ([]models.Source, error) {
-	rows, err := sr.db.Query("SELECT id, name, url, saved_at, user_id, update_interval, subscriber FROM sources WHERE subscriber = 1")
+	rows, err := sr.db.Query("SELECT " + sourceColumns + " FROM sources WHERE subscriber = 1 AND " + enabledOwner)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	var sources []models.Source
 	for rows.Next() {
-		var source models.Source
-		var savedAt []byte
-		err = rows.Scan(&source.ID, &source.Name, &source.Url, &savedAt, &source.UserID, &source.UpdateInterval, &source.Subscriber)
+		source, err := scanSource(rows)
 		if err != nil {
 			return nil, err
 		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
 
-		source.SavedAt, err = utils.ParseTime(savedAt)
+// FindAllOfEnabledUsers returns the sources to fetch: those whose owner is not
+// disabled.
+func (sr *SourceRepository) FindAllOfEnabledUsers() ([]models.Source, error) {
+	rows, err := sr.db.Query("SELECT " + sourceColumns + " FROM sources WHERE " + enabledOwner + " ORDER BY saved_at asc")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var sources []models.Source
+	for rows.Next() {
+		source, err := scanSource(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -200,3 +216,29 @@ func (sr *SourceRepository) FindAllActive() ([]models.Source, error) {
 	}
 	return sources, nil
 }
+
+func (sr *SourceRepository) UpdateCacheValidators(id int, etag, lastModified string) error {
+	stmt, err := sr.db.Prepare("UPDATE sources SET etag = ?, last_modified = ? WHERE id = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(etag, lastModified, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (sr *SourceRepository) UpdateFetchState(id, consecutiveFailures int, disabled bool, fetchStatus, lastError string) error {
+	stmt, err := sr.db.Prepare("UPDATE sources SET consecutive_failures = ?, disabled = ?, fetch_status = ?, last_error = ? WHERE id = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(consecutiveFailures, disabled, fetchStatus, lastError, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -6,6 +6,8 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+const userColumns = "id, username, email, password, feed_token, role, disabled, token_version, email_verified, totp_secret, totp_enabled, totp_last_step"
+
 type UserRepository struct {
 	db *sql.DB
 }
@@ -17,14 +19,18 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 }
 
 func (ur *UserRepository) FindByEmail(email string) (*models.User, error) {
-	stmt, err := ur.db.Prepare("SELECT * FROM users WHERE email = ?")
+	stmt, err := ur.db.Prepare("SELECT " + userColumns + " FROM users WHERE email = ?")
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
-	var user models.User
-	err = stmt.QueryRow(email).Scan(&user.ID, &user.Username, &user.Email, &user.Password)
 
+	return scanUser(stmt.QueryRow(email))
+}
+
+func scanUser(row rowScanner) (*models.User, error) {
+	var user models.User
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.FeedToken, &user.Role, &user.Disabled, &user.TokenVersion, &user.EmailVerified, &user.TOTPSecret, &user.TwoFactorEnabled, &user.TOTPLastStep)
 	if err != nil {
 		return nil, err
 	}
@@ -54,6 +60,7 @@ func (ur *UserRepository) Create(user models.User) (*models.User, error) {
 		ID:       int(id),
 		Email:    user.Email,
 		Username: user.Username,
+		Role:     models.RoleUser,
 	}, nil
 }
 
@@ -96,15 +103,125 @@ func (ur *UserRepository) Update(user models.User) (*models.User, error) {
 }
 
 func (ur *UserRepository) FindById(id int) (*models.User, error) {
-	stmt, err := ur.db.Prepare("SELECT * FROM users WHERE id = ?")
+	stmt, err := ur.db.Prepare("SELECT " + userColumns + " FROM users WHERE id = ?")
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
-	var user models.User
-	err = stmt.QueryRow(id).Scan(&user.ID, &user.Username, &user.Email, &user.Password)
+
+	return scanUser(stmt.QueryRow(id))
+}
+
+func (ur *UserRepository) UpdateFeedToken(id int, feedToken string) error {
+	stmt, err := ur.db.Prepare("UPDATE users SET feed_token = ? WHERE id = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(feedToken, id)
+	return err
+}
+
+func (ur *UserRepository) FindAll(page, limit int) ([]models.User, error) {
+	sql := "SELECT " + userColumns + " FROM users ORDER BY id"
+	var args []interface{}
+	if page != 0 && limit != 0 {
+		sql = sql + " LIMIT ? OFFSET ?"
+		args = append(args, limit, (page-1)*limit)
+	}
+
+	rows, err := ur.db.Query(sql, args...)
 	if err != nil {
 		return nil, err
 	}
-	return &user, nil
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	return users, rows.Err()
+}
+
+func (ur *UserRepository) UpdateRole(id int, role string) error {
+	stmt, err := ur.db.Prepare("UPDATE users SET role = ? WHERE id = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(role, id)
+	return err
+}
+
+func (ur *UserRepository) UpdateDisabled(id int, disabled bool) error {
+	stmt, err := ur.db.Prepare("UPDATE users SET disabled = ? WHERE id = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(disabled, id)
+	return err
+}
+
+// IncrementTokenVersion ends every session of the user.
+func (ur *UserRepository) IncrementTokenVersion(id int) error {
+	stmt, err := ur.db.Prepare("UPDATE users SET token_version = token_version + 1 WHERE id = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(id)
+	return err
+}
+
+func (ur *UserRepository) UpdateEmailVerified(id int, verified bool) error {
+	stmt, err := ur.db.Prepare("UPDATE users SET email_verified = ? WHERE id = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(verified, id)
+	return err
+}
+
+// UpdateTwoFactor stores the TOTP secret of the user and whether it is required
+// to log in. An empty secret removes the second factor.
+func (ur *UserRepository) UpdateTwoFactor(id int, secret string, enabled bool) error {
+	stmt, err := ur.db.Prepare("UPDATE users SET totp_secret = ?, totp_enabled = ?, totp_last_step = 0 WHERE id = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(secret, enabled, id)
+	return err
+}
+
+// UseTOTPStep records the time step of an accepted TOTP code and reports
+// whether it is newer than the last one, so each code is only accepted once.
+func (ur *UserRepository) UseTOTPStep(id int, step int64) (bool, error) {
+	stmt, err := ur.db.Prepare("UPDATE users SET totp_last_step = ? WHERE id = ? AND totp_last_step < ?")
+	if err != nil {
+		return false, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.Exec(step, id, step)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
 }
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"database/sql"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"time"
+)
+
+type UserTokenRepository struct {
+	db *sql.DB
+}
+
+func NewUserTokenRepository(db *sql.DB) *UserTokenRepository {
+	return &UserTokenRepository{db: db}
+}
+
+func (tr *UserTokenRepository) Create(token models.UserToken) error {
+	stmt, err := tr.db.Prepare("INSERT INTO user_tokens (id, user_id, purpose, created_at, expires_at) VALUES (?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(token.ID, token.UserID, token.Purpose, token.CreatedAt, token.ExpiresAt)
+	return err
+}
+
+// Use marks the token as used and reports whether it could still be used.
+func (tr *UserTokenRepository) Use(id string, userID int, purpose string, now time.Time) (bool, error) {
+	stmt, err := tr.db.Prepare("UPDATE user_tokens SET used_at = ? WHERE id = ? AND user_id = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?")
+	if err != nil {
+		return false, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.Exec(now, id, userID, purpose, now)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+// UseAll marks every unused token of the user for the purpose as used.
+func (tr *UserTokenRepository) UseAll(userID int, purpose string, now time.Time) error {
+	stmt, err := tr.db.Prepare("UPDATE user_tokens SET used_at = ? WHERE user_id = ? AND purpose = ? AND used_at IS NULL")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(now, userID, purpose)
+	return err
+}
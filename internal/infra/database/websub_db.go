@@ -0,0 +1,87 @@
+package database
+
+import (
+	"database/sql"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"github.com/matheusvidal21/smart-news-fetcher/pkg/utils"
+	"time"
+)
+
+const webSubColumns = "source_id, hub_url, topic_url, secret, state, lease_seconds, lease_expires_at, updated_at, callback_token"
+
+type WebSubRepository struct {
+	db *sql.DB
+}
+
+func NewWebSubRepository(db *sql.DB) *WebSubRepository {
+	return &WebSubRepository{db: db}
+}
+
+func scanWebSubSubscription(row rowScanner) (models.WebSubSubscription, error) {
+	var subscription models.WebSubSubscription
+	var leaseExpiresAt, updatedAt []byte
+	err := row.Scan(&subscription.SourceID, &subscription.HubURL, &subscription.TopicURL, &subscription.Secret, &subscription.State, &subscription.LeaseSeconds, &leaseExpiresAt, &updatedAt, &subscription.CallbackToken)
+	if err != nil {
+		return models.WebSubSubscription{}, err
+	}
+
+	if leaseExpiresAt != nil {
+		subscription.LeaseExpiresAt, err = utils.ParseTime(leaseExpiresAt)
+		if err != nil {
+			return models.WebSubSubscription{}, err
+		}
+	}
+	subscription.UpdatedAt, err = utils.ParseTime(updatedAt)
+	if err != nil {
+		return models.WebSubSubscription{}, err
+	}
+	return subscription, nil
+}
+
+func (wr *WebSubRepository) FindBySourceId(sourceID int) (models.WebSubSubscription, error) {
+	stmt, err := wr.db.Prepare("SELECT " + webSubColumns + " FROM websub_subscriptions WHERE source_id = ?")
+	if err != nil {
+		return models.WebSubSubscription{}, err
+	}
+	defer stmt.Close()
+
+	return scanWebSubSubscription(stmt.QueryRow(sourceID))
+}
+
+// Save inserts the subscription or replaces the one of the same source.
+func (wr *WebSubRepository) Save(subscription models.WebSubSubscription) error {
+	stmt, err := wr.db.Prepare("INSERT INTO websub_subscriptions (" + webSubColumns + ") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) " +
+		"ON DUPLICATE KEY UPDATE hub_url = VALUES(hub_url), topic_url = VALUES(topic_url), secret = VALUES(secret), state = VALUES(state), " +
+		"lease_seconds = VALUES(lease_seconds), lease_expires_at = VALUES(lease_expires_at), updated_at = VALUES(updated_at), callback_token = VALUES(callback_token)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	var leaseExpiresAt interface{}
+	if !subscription.LeaseExpiresAt.IsZero() {
+		leaseExpiresAt = subscription.LeaseExpiresAt
+	}
+	_, err = stmt.Exec(subscription.SourceID, subscription.HubURL, subscription.TopicURL, subscription.Secret, subscription.State,
+		subscription.LeaseSeconds, leaseExpiresAt, time.Now(), subscription.CallbackToken)
+	return err
+}
+
+// FindRenewable returns the active subscriptions whose lease ends before the given time.
+func (wr *WebSubRepository) FindRenewable(before time.Time) ([]models.WebSubSubscription, error) {
+	rows, err := wr.db.Query("SELECT "+webSubColumns+" FROM websub_subscriptions WHERE state = ? AND lease_expires_at < ?", models.WebSubStateActive, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscriptions []models.WebSubSubscription
+	for rows.Next() {
+		subscription, err := scanWebSubSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	return subscriptions, rows.Err()
+}
@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/logger"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/dto"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/middleware"
+	"net/http"
+	"strconv"
+)
+
+type AdminHandler struct {
+	adminService interfaces.AdminServiceInterface
+}
+
+func NewAdminHandler(adminService interfaces.AdminServiceInterface) *AdminHandler {
+	return &AdminHandler{adminService: adminService}
+}
+
+func (ah *AdminHandler) FindUsers(c *gin.Context) {
+	page, limit, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	users, err := ah.adminService.FindUsers(page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Users found: " + strconv.Itoa(len(users)))
+	c.JSON(http.StatusOK, users)
+}
+
+func (ah *AdminHandler) UpdateUserRole(c *gin.Context) {
+	adminID, userID, ok := adminUserParams(c)
+	if !ok {
+		return
+	}
+
+	var roleDto dto.UpdateUserRoleInput
+	if err := c.BindJSON(&roleDto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := dto.Validate.Struct(roleDto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := ah.adminService.UpdateUserRole(adminID, userID, roleDto)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("User role updated: " + c.Param("id") + " is " + user.Role)
+	c.JSON(http.StatusOK, user)
+}
+
+func (ah *AdminHandler) DisableUser(c *gin.Context) {
+	adminID, userID, ok := adminUserParams(c)
+	if !ok {
+		return
+	}
+
+	user, err := ah.adminService.DisableUser(adminID, userID)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("User disabled: " + c.Param("id"))
+	c.JSON(http.StatusOK, user)
+}
+
+func (ah *AdminHandler) EnableUser(c *gin.Context) {
+	_, userID, ok := adminUserParams(c)
+	if !ok {
+		return
+	}
+
+	user, err := ah.adminService.EnableUser(userID)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("User enabled: " + c.Param("id"))
+	c.JSON(http.StatusOK, user)
+}
+
+func (ah *AdminHandler) ResetTwoFactor(c *gin.Context) {
+	_, userID, ok := adminUserParams(c)
+	if !ok {
+		return
+	}
+
+	user, err := ah.adminService.ResetTwoFactor(userID)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Two-factor authentication reset for user: " + c.Param("id"))
+	c.JSON(http.StatusOK, user)
+}
+
+func (ah *AdminHandler) FindSources(c *gin.Context) {
+	page, limit, ok := pageParams(c)
+	if !ok {
+		return
+	}
+	sort := c.DefaultQuery("sort", "asc")
+
+	sources, err := ah.adminService.FindSources(page, limit, sort)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Sources found: " + strconv.Itoa(len(sources)))
+	c.JSON(http.StatusOK, sources)
+}
+
+// FetchHealth reports how many sources fetch successfully and lists the
+// failing ones.
+func (ah *AdminHandler) FetchHealth(c *gin.Context) {
+	health, err := ah.adminService.FetchHealth()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Unhealthy sources: " + strconv.Itoa(len(health.Unhealthy)))
+	c.JSON(http.StatusOK, health)
+}
+
+// FetchSource fetches any user's source right away.
+func (ah *AdminHandler) FetchSource(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid source ID"})
+		return
+	}
+
+	err = ah.adminService.FetchSource(id)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Source fetch triggered: " + c.Param("id"))
+	c.JSON(http.StatusOK, gin.H{"message": "Source fetch scheduled"})
+}
+
+func pageParams(c *gin.Context) (int, int, bool) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+		return 0, 0, false
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit number"})
+		return 0, 0, false
+	}
+	return page, limit, true
+}
+
+func adminUserParams(c *gin.Context) (int, int, bool) {
+	adminID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return 0, 0, false
+	}
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return 0, 0, false
+	}
+	return adminID, userID, true
+}
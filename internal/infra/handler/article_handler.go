@@ -1,23 +1,40 @@
 package handler
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/google/logger"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/dto"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/middleware"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSearchLimit = 10
+	maxSearchLimit     = 100
 )
 
 type ArticleHandler struct {
 	articleService interfaces.ArticleServiceInterface
+	extractor      interfaces.ArticleExtractorInterface
 }
 
-func NewArticleHandler(articleService interfaces.ArticleServiceInterface) *ArticleHandler {
-	return &ArticleHandler{articleService: articleService}
+func NewArticleHandler(articleService interfaces.ArticleServiceInterface, extractor interfaces.ArticleExtractorInterface) *ArticleHandler {
+	return &ArticleHandler{articleService: articleService, extractor: extractor}
 }
 
 func (h *ArticleHandler) FindAll(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
 	pageStr := c.DefaultQuery("page", "1")
 	limitStr := c.DefaultQuery("limit", "10")
 	sort := c.DefaultQuery("sort", "asc")
@@ -31,7 +48,13 @@ func (h *ArticleHandler) FindAll(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit number"})
 	}
 
-	articles, err := h.articleService.FindAll(page, limit, sort)
+	filter, err := articleStateFilter(c, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	articles, err := h.articleService.FindAll(userID, filter, page, limit, sort)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 	}
@@ -41,10 +64,16 @@ func (h *ArticleHandler) FindAll(c *gin.Context) {
 }
 
 func (h *ArticleHandler) FindOne(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
 	id := c.Param("id")
-	article, err := h.articleService.FindOne(id)
+	article, err := h.articleService.FindOne(userID, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -53,6 +82,12 @@ func (h *ArticleHandler) FindOne(c *gin.Context) {
 }
 
 func (h *ArticleHandler) Create(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
 	var article dto.CreateArticleInput
 
 	if err := c.BindJSON(&article); err != nil {
@@ -65,9 +100,9 @@ func (h *ArticleHandler) Create(c *gin.Context) {
 		return
 	}
 
-	createdArticle, err := h.articleService.Create(article)
+	createdArticle, err := h.articleService.Create(userID, article)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -76,6 +111,12 @@ func (h *ArticleHandler) Create(c *gin.Context) {
 }
 
 func (h *ArticleHandler) Update(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
 	id := c.Param("id")
 
 	var article dto.UpdateArticleInput
@@ -89,9 +130,9 @@ func (h *ArticleHandler) Update(c *gin.Context) {
 		return
 	}
 
-	uptdatedArticle, err := h.articleService.Update(id, article)
+	uptdatedArticle, err := h.articleService.Update(userID, id, article)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -100,11 +141,17 @@ func (h *ArticleHandler) Update(c *gin.Context) {
 }
 
 func (h *ArticleHandler) Delete(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
 	id := c.Param("id")
 
-	err := h.articleService.Delete(id)
+	err := h.articleService.Delete(userID, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -113,6 +160,12 @@ func (h *ArticleHandler) Delete(c *gin.Context) {
 }
 
 func (h *ArticleHandler) FindBySourceID(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
@@ -120,12 +173,253 @@ func (h *ArticleHandler) FindBySourceID(c *gin.Context) {
 		return
 	}
 
-	articles, err := h.articleService.FindAllBySourceId(id)
+	filter, err := articleStateFilter(c, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	articles, err := h.articleService.FindAllBySourceId(userID, id, filter)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
 	logger.Info("Articles found: " + strconv.Itoa(len(articles)))
 	c.JSON(http.StatusOK, articles)
 }
+
+// articleStateFilter reads the unread_only and starred query parameters, which
+// filter the articles on the state of the current user.
+func articleStateFilter(c *gin.Context, userID int) (models.ArticleFilter, error) {
+	filter := models.ArticleFilter{ReaderID: userID}
+	var err error
+	if value := c.Query("unread_only"); value != "" {
+		filter.UnreadOnly, err = strconv.ParseBool(value)
+		if err != nil {
+			return models.ArticleFilter{}, errors.New("Invalid unread_only value")
+		}
+	}
+	if value := c.Query("starred"); value != "" {
+		filter.Starred, err = strconv.ParseBool(value)
+		if err != nil {
+			return models.ArticleFilter{}, errors.New("Invalid starred value")
+		}
+	}
+	return filter, nil
+}
+
+func (h *ArticleHandler) FindState(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id := c.Param("id")
+	state, err := h.articleService.FindState(userID, id)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Article state found: " + id)
+	c.JSON(http.StatusOK, state)
+}
+
+// UpdateState marks the article as read, starred or archived for the current
+// user. Flags left out of the body keep their value.
+func (h *ArticleHandler) UpdateState(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var stateDto dto.UpdateArticleStateInput
+	if err := c.BindJSON(&stateDto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	id := c.Param("id")
+	state, err := h.articleService.UpdateState(userID, id, stateDto)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Article state updated: " + id)
+	c.JSON(http.StatusOK, state)
+}
+
+// MarkRead marks as read every article of the current user's sources, or of
+// one of them, published up to the given date.
+func (h *ArticleHandler) MarkRead(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var markDto dto.MarkArticlesReadInput
+	if err := c.BindJSON(&markDto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := dto.Validate.Struct(markDto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	output, err := h.articleService.MarkRead(userID, markDto)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Articles marked as read: " + strconv.Itoa(output.Marked))
+	c.JSON(http.StatusOK, output)
+}
+
+func (h *ArticleHandler) FindRevisions(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id := c.Param("id")
+	revisions, err := h.articleService.FindRevisions(userID, id)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Revisions found: " + strconv.Itoa(len(revisions)))
+	c.JSON(http.StatusOK, revisions)
+}
+
+// Extract fetches the article page again and stores its main content, for
+// articles whose extraction failed or whose source did not fetch full content.
+func (h *ArticleHandler) Extract(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id := c.Param("id")
+	if _, err := h.articleService.FindOne(userID, id); err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.extractor.ExtractArticle(id); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	article, err := h.articleService.FindOne(userID, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Article content extracted: " + id)
+	c.JSON(http.StatusOK, article)
+}
+
+// Search finds articles of the current user by keyword. The results can be
+// narrowed by source, author and publication date, and ordered by relevance or
+// date.
+func (h *ArticleHandler) Search(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	filter, err := articleFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.UserID = userID
+	if filter.Query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing search query"})
+		return
+	}
+
+	sort := c.DefaultQuery("sort", models.ArticleSortRelevance)
+	if sort != models.ArticleSortRelevance && sort != models.ArticleSortDate {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort, use relevance or date"})
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultSearchLimit)))
+	if err != nil || limit < 1 || limit > maxSearchLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit number"})
+		return
+	}
+
+	results, err := h.articleService.Search(filter, sort, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Articles found for \"" + filter.Query + "\": " + strconv.Itoa(results.Total))
+	c.JSON(http.StatusOK, results)
+}
+
+// articleFilterFromQuery reads the q, source_id, author, from and to query
+// parameters. Dates are RFC 3339 timestamps or days, a day given as "to"
+// including the whole day.
+func articleFilterFromQuery(c *gin.Context) (models.ArticleFilter, error) {
+	filter := models.ArticleFilter{
+		Query:  strings.TrimSpace(c.Query("q")),
+		Author: strings.TrimSpace(c.Query("author")),
+	}
+
+	var err error
+	if value := c.Query("source_id"); value != "" {
+		filter.SourceID, err = strconv.Atoi(value)
+		if err != nil {
+			return models.ArticleFilter{}, errors.New("Invalid source ID")
+		}
+	}
+	if value := c.Query("from"); value != "" {
+		filter.From, err = parseQueryDate(value, false)
+		if err != nil {
+			return models.ArticleFilter{}, errors.New("Invalid from date: " + value)
+		}
+	}
+	if value := c.Query("to"); value != "" {
+		filter.To, err = parseQueryDate(value, true)
+		if err != nil {
+			return models.ArticleFilter{}, errors.New("Invalid to date: " + value)
+		}
+	}
+	return filter, nil
+}
+
+func parseQueryDate(value string, endOfDay bool) (time.Time, error) {
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date.UTC(), nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		date = date.Add(24*time.Hour - time.Second)
+	}
+	return date, nil
+}
@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"net/http"
+)
+
+// errorStatus maps the errors services return for missing and foreign
+// resources to their HTTP status, and any other error to fallback.
+func errorStatus(err error, fallback int) int {
+	switch err {
+	case models.ErrSourceNotFound, models.ErrArticleNotFound, models.ErrSavedSearchNotFound, models.ErrUserNotFound, models.ErrSessionNotFound, models.ErrAPIKeyNotFound:
+		return http.StatusNotFound
+	case models.ErrInvalidCredentials, models.ErrInvalidRefreshToken, models.ErrSessionExpired, models.ErrInvalidAPIKey, models.ErrInvalidTwoFactorCode, models.ErrInvalidLoginChallenge:
+		return http.StatusUnauthorized
+	case models.ErrInvalidEmailToken, models.ErrAPIKeyExpiry, models.ErrTwoFactorNotEnrolled:
+		return http.StatusBadRequest
+	case models.ErrForbidden, models.ErrUserDisabled, models.ErrEmailNotVerified:
+		return http.StatusForbidden
+	case models.ErrTwoFactorEnabled, models.ErrTwoFactorNotEnabled:
+		return http.StatusConflict
+	case models.ErrTooManyAttempts:
+		return http.StatusTooManyRequests
+	}
+	return fallback
+}
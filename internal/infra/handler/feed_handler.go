@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"github.com/google/logger"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/syndication"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultFeedLimit = 20
+	maxFeedLimit     = 100
+)
+
+type FeedHandler struct {
+	feedService interfaces.FeedServiceInterface
+}
+
+func NewFeedHandler(feedService interfaces.FeedServiceInterface) *FeedHandler {
+	return &FeedHandler{feedService: feedService}
+}
+
+func (fh *FeedHandler) UserFeed(c *gin.Context) {
+	fh.serve(c, "Invalid user ID", fh.feedService.UserFeed)
+}
+
+func (fh *FeedHandler) SourceFeed(c *gin.Context) {
+	fh.serve(c, "Invalid source ID", fh.feedService.SourceFeed)
+}
+
+func (fh *FeedHandler) SavedSearchFeed(c *gin.Context) {
+	fh.serve(c, "Invalid saved search ID", fh.feedService.SavedSearchFeed)
+}
+
+func (fh *FeedHandler) serve(c *gin.Context, invalidID string, load func(id int, token string, page, limit int) (syndication.Feed, error)) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": invalidID})
+		return
+	}
+
+	format := strings.TrimPrefix(path.Ext(c.FullPath()), ".")
+	contentType, err := syndication.ContentType(format)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultFeedLimit)))
+	if err != nil || limit < 1 || limit > maxFeedLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit number"})
+		return
+	}
+
+	token := c.Query("token")
+	feed, err := load(id, token, page, limit)
+	if err == models.ErrInvalidFeedToken {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	pageURL := func(page int) string {
+		query := url.Values{}
+		query.Set("token", token)
+		query.Set("page", strconv.Itoa(page))
+		query.Set("limit", strconv.Itoa(limit))
+		return requestBaseURL(c) + c.Request.URL.Path + "?" + query.Encode()
+	}
+	feed.SelfURL = pageURL(page)
+	feed.FirstURL = pageURL(1)
+	if feed.HomeURL == "" {
+		feed.HomeURL = feed.FirstURL
+	}
+	if page > 1 {
+		feed.PrevURL = pageURL(page - 1)
+	}
+	if len(feed.Items) == limit {
+		feed.NextURL = pageURL(page + 1)
+	}
+
+	var body bytes.Buffer
+	err = syndication.Write(&body, format, feed)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write feed: " + err.Error()})
+		return
+	}
+
+	logger.Info("Feed served: " + c.Request.URL.Path)
+	c.Data(http.StatusOK, contentType, body.Bytes())
+}
+
+func requestBaseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if forwarded := c.GetHeader("X-Forwarded-Proto"); forwarded != "" {
+		scheme = forwarded
+	}
+	return scheme + "://" + c.Request.Host
+}
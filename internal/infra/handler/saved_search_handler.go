@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/logger"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/dto"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/middleware"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"net/http"
+	"strconv"
+)
+
+type SavedSearchHandler struct {
+	savedSearchService interfaces.SavedSearchServiceInterface
+}
+
+func NewSavedSearchHandler(savedSearchService interfaces.SavedSearchServiceInterface) *SavedSearchHandler {
+	return &SavedSearchHandler{savedSearchService: savedSearchService}
+}
+
+func (sh *SavedSearchHandler) FindAll(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	searches, err := sh.savedSearchService.FindByUserId(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Saved searches found: " + strconv.Itoa(len(searches)))
+	c.JSON(http.StatusOK, searches)
+}
+
+func (sh *SavedSearchHandler) FindOne(c *gin.Context) {
+	userID, id, ok := savedSearchParams(c)
+	if !ok {
+		return
+	}
+
+	search, err := sh.savedSearchService.FindOne(userID, id)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Saved search found: " + c.Param("id"))
+	c.JSON(http.StatusOK, search)
+}
+
+func (sh *SavedSearchHandler) Create(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var search dto.CreateSavedSearchInput
+	if err := c.BindJSON(&search); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := dto.Validate.Struct(search); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := sh.savedSearchService.Create(userID, search)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Saved search created: " + created.Name)
+	c.JSON(http.StatusOK, created)
+}
+
+func (sh *SavedSearchHandler) Update(c *gin.Context) {
+	userID, id, ok := savedSearchParams(c)
+	if !ok {
+		return
+	}
+
+	var search dto.UpdateSavedSearchInput
+	if err := c.BindJSON(&search); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := dto.Validate.Struct(search); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := sh.savedSearchService.Update(userID, id, search)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Saved search updated: " + c.Param("id"))
+	c.JSON(http.StatusOK, updated)
+}
+
+func (sh *SavedSearchHandler) Delete(c *gin.Context) {
+	userID, id, ok := savedSearchParams(c)
+	if !ok {
+		return
+	}
+
+	err := sh.savedSearchService.Delete(userID, id)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Saved search deleted: " + c.Param("id"))
+	c.JSON(http.StatusOK, gin.H{"message": "Saved search deleted successfully"})
+}
+
+// Results runs the saved search, ordered by relevance or date like a search.
+func (sh *SavedSearchHandler) Results(c *gin.Context) {
+	userID, id, ok := savedSearchParams(c)
+	if !ok {
+		return
+	}
+
+	sort := c.DefaultQuery("sort", models.ArticleSortRelevance)
+	if sort != models.ArticleSortRelevance && sort != models.ArticleSortDate {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort, use relevance or date"})
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultSearchLimit)))
+	if err != nil || limit < 1 || limit > maxSearchLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit number"})
+		return
+	}
+
+	results, err := sh.savedSearchService.Results(userID, id, sort, page, limit)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Articles found for saved search " + c.Param("id") + ": " + strconv.Itoa(results.Total))
+	c.JSON(http.StatusOK, results)
+}
+
+func savedSearchParams(c *gin.Context) (int, int, bool) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return 0, 0, false
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid saved search ID"})
+		return 0, 0, false
+	}
+	return userID, id, true
+}
@@ -5,10 +5,14 @@ import (
 	"github.com/google/logger"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/dto"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/middleware"
+	"io"
 	"net/http"
 	"strconv"
 )
 
+const maxOPMLSize = 5 << 20
+
 type SourceHandler struct {
 	sourceService interfaces.SourceServiceInterface
 }
@@ -18,6 +22,12 @@ func NewSourceHandler(sourceService interfaces.SourceServiceInterface) *SourceHa
 }
 
 func (sh *SourceHandler) FindAll(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
 	pageStr := c.DefaultQuery("page", "1")
 	limitStr := c.DefaultQuery("limit", "10")
 	sort := c.DefaultQuery("sort", "asc")
@@ -34,7 +44,7 @@ func (sh *SourceHandler) FindAll(c *gin.Context) {
 		return
 	}
 
-	sources, err := sh.sourceService.FindAll(page, limit, sort)
+	sources, err := sh.sourceService.FindAll(userID, page, limit, sort)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -45,6 +55,12 @@ func (sh *SourceHandler) FindAll(c *gin.Context) {
 }
 
 func (sh *SourceHandler) FindOne(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 
@@ -53,9 +69,9 @@ func (sh *SourceHandler) FindOne(c *gin.Context) {
 		return
 	}
 
-	source, err := sh.sourceService.FindOne(id)
+	source, err := sh.sourceService.FindOne(userID, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -64,11 +80,18 @@ func (sh *SourceHandler) FindOne(c *gin.Context) {
 }
 
 func (sh *SourceHandler) Create(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
 	var source dto.CreateSourceInput
 	if err := c.BindJSON(&source); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	source.UserID = userID
 
 	if err := dto.Validate.Struct(source); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -81,11 +104,91 @@ func (sh *SourceHandler) Create(c *gin.Context) {
 		return
 	}
 
+	if len(createdSource.DiscoveredFeeds) > 0 {
+		logger.Info("Multiple feeds discovered for: " + source.Url)
+		c.JSON(http.StatusMultipleChoices, gin.H{
+			"message":          "Multiple feeds found, create the source again with one of the discovered urls",
+			"discovered_feeds": createdSource.DiscoveredFeeds,
+		})
+		return
+	}
+
 	logger.Info("Source created: " + createdSource.Name)
 	c.JSON(http.StatusOK, createdSource)
 }
 
+func (sh *SourceHandler) Discover(c *gin.Context) {
+	url := c.Query("url")
+	if err := dto.Validate.Var(url, "required,url"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid url"})
+		return
+	}
+
+	candidates, err := sh.sourceService.Discover(url)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Feeds discovered: " + strconv.Itoa(len(candidates)))
+	c.JSON(http.StatusOK, candidates)
+}
+
+func (sh *SourceHandler) ImportOPML(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var document io.Reader
+	if file, err := c.FormFile("file"); err == nil {
+		opened, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read OPML file: " + err.Error()})
+			return
+		}
+		defer opened.Close()
+		document = opened
+	} else {
+		document = c.Request.Body
+	}
+
+	report, err := sh.sourceService.ImportOPML(userID, io.LimitReader(document, maxOPMLSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Sources imported from OPML: " + strconv.Itoa(report.Imported))
+	c.JSON(http.StatusOK, report)
+}
+
+func (sh *SourceHandler) ExportOPML(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	document, err := sh.sourceService.ExportOPML(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Sources exported to OPML for user: " + strconv.Itoa(userID))
+	c.Header("Content-Disposition", `attachment; filename="sources.opml"`)
+	c.Data(http.StatusOK, "text/x-opml; charset=utf-8", document)
+}
+
 func (sh *SourceHandler) Update(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 
@@ -105,9 +208,9 @@ func (sh *SourceHandler) Update(c *gin.Context) {
 		return
 	}
 
-	uptadedSource, err := sh.sourceService.Update(id, source)
+	uptadedSource, err := sh.sourceService.Update(userID, id, source)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -116,6 +219,12 @@ func (sh *SourceHandler) Update(c *gin.Context) {
 }
 
 func (sh *SourceHandler) Delete(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 
@@ -124,9 +233,9 @@ func (sh *SourceHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	err = sh.sourceService.Delete(id)
+	err = sh.sourceService.Delete(userID, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -135,6 +244,12 @@ func (sh *SourceHandler) Delete(c *gin.Context) {
 }
 
 func (sh *SourceHandler) LoadFeed(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
@@ -142,9 +257,9 @@ func (sh *SourceHandler) LoadFeed(c *gin.Context) {
 		return
 	}
 
-	err = sh.sourceService.LoadFeed(id)
+	err = sh.sourceService.LoadFeed(userID, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -152,7 +267,73 @@ func (sh *SourceHandler) LoadFeed(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Feed loaded successfully"})
 }
 
+func (sh *SourceHandler) Resume(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid source ID"})
+		return
+	}
+
+	err = sh.sourceService.Resume(userID, id)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Source resumed: " + idStr)
+	c.JSON(http.StatusOK, gin.H{"message": "Source resumed successfully"})
+}
+
+func (sh *SourceHandler) FindFetchRuns(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid source ID"})
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit number"})
+		return
+	}
+
+	runs, err := sh.sourceService.FindFetchRuns(userID, id, page, limit)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Fetch runs found: " + strconv.Itoa(len(runs)))
+	c.JSON(http.StatusOK, runs)
+}
+
 func (sh *SourceHandler) FindByUserId(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 
@@ -161,9 +342,9 @@ func (sh *SourceHandler) FindByUserId(c *gin.Context) {
 		return
 	}
 
-	sources, err := sh.sourceService.FindByUserId(id)
+	sources, err := sh.sourceService.FindByUserId(userID, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -172,6 +353,12 @@ func (sh *SourceHandler) FindByUserId(c *gin.Context) {
 }
 
 func (sh *SourceHandler) SubscribeToNewsletter(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
@@ -179,9 +366,9 @@ func (sh *SourceHandler) SubscribeToNewsletter(c *gin.Context) {
 		return
 	}
 
-	err = sh.sourceService.SubscribeToNewsletter(id)
+	err = sh.sourceService.SubscribeToNewsletter(userID, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -190,6 +377,12 @@ func (sh *SourceHandler) SubscribeToNewsletter(c *gin.Context) {
 }
 
 func (sh *SourceHandler) UnsubscribeFromNewsletter(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
@@ -197,9 +390,9 @@ func (sh *SourceHandler) UnsubscribeFromNewsletter(c *gin.Context) {
 		return
 	}
 
-	err = sh.sourceService.UnsubscribeFromNewsletter(id)
+	err = sh.sourceService.UnsubscribeFromNewsletter(userID, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
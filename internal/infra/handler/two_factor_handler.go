@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/dto"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/middleware"
+	"net/http"
+)
+
+type TwoFactorHandler struct {
+	twoFactorService interfaces.TwoFactorServiceInterface
+	sessionService   interfaces.SessionServiceInterface
+}
+
+func NewTwoFactorHandler(twoFactorService interfaces.TwoFactorServiceInterface, sessionService interfaces.SessionServiceInterface) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		twoFactorService: twoFactorService,
+		sessionService:   sessionService,
+	}
+}
+
+func (th *TwoFactorHandler) Enroll(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	enrollment, err := th.twoFactorService.Enroll(userID)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, enrollment)
+}
+
+func (th *TwoFactorHandler) Confirm(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var input dto.TwoFactorConfirmInput
+	if err := c.BindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := dto.Validate.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := th.twoFactorService.Confirm(userID, input)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, codes)
+}
+
+func (th *TwoFactorHandler) Disable(c *gin.Context) {
+	userID, input, ok := twoFactorCodeParams(c)
+	if !ok {
+		return
+	}
+
+	err := th.twoFactorService.Disable(userID, input)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled successfully"})
+}
+
+func (th *TwoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, input, ok := twoFactorCodeParams(c)
+	if !ok {
+		return
+	}
+
+	codes, err := th.twoFactorService.RegenerateRecoveryCodes(userID, input)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, codes)
+}
+
+// Login is the second step of the login of users with two-factor
+// authentication, trading the challenge token and a code for a session.
+func (th *TwoFactorHandler) Login(c *gin.Context) {
+	var input dto.TwoFactorLoginInput
+	if err := c.BindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := dto.Validate.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := th.twoFactorService.VerifyChallenge(input)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := th.sessionService.Start(user.ID, sessionClient(c))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+func twoFactorCodeParams(c *gin.Context) (int, dto.TwoFactorCodeInput, bool) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return 0, dto.TwoFactorCodeInput{}, false
+	}
+
+	var input dto.TwoFactorCodeInput
+	if err := c.BindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return 0, dto.TwoFactorCodeInput{}, false
+	}
+	if err := dto.Validate.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return 0, dto.TwoFactorCodeInput{}, false
+	}
+	return userID, input, true
+}
@@ -4,17 +4,28 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/dto"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/middleware"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"math"
 	"net/http"
 	"strconv"
 )
 
 type UserHandler struct {
-	userService interfaces.UserServiceInterface
+	userService      interfaces.UserServiceInterface
+	sessionService   interfaces.SessionServiceInterface
+	accountService   interfaces.AccountServiceInterface
+	twoFactorService interfaces.TwoFactorServiceInterface
+	loginThrottle    interfaces.LoginThrottleInterface
 }
 
-func NewUserHandler(userService interfaces.UserServiceInterface) *UserHandler {
+func NewUserHandler(userService interfaces.UserServiceInterface, sessionService interfaces.SessionServiceInterface, accountService interfaces.AccountServiceInterface, twoFactorService interfaces.TwoFactorServiceInterface, loginThrottle interfaces.LoginThrottleInterface) *UserHandler {
 	return &UserHandler{
-		userService: userService,
+		userService:      userService,
+		sessionService:   sessionService,
+		accountService:   accountService,
+		twoFactorService: twoFactorService,
+		loginThrottle:    loginThrottle,
 	}
 }
 
@@ -36,10 +47,95 @@ func (u *UserHandler) CreateUser(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+
+	err = u.accountService.SendVerification(createdUser.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User created but failed to send verification email: " + err.Error()})
+		return
+	}
 	c.JSON(http.StatusOK, createdUser)
 }
 
+// VerifyEmail is the link sent to confirm the email address of a user.
+func (u *UserHandler) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing token"})
+		return
+	}
+
+	err := u.accountService.VerifyEmail(token)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+}
+
+func (u *UserHandler) ResendVerification(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	err := u.accountService.SendVerification(userID)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
+
+// RequestPasswordReset answers the same whether or not the email belongs to a
+// user.
+func (u *UserHandler) RequestPasswordReset(c *gin.Context) {
+	var resetDto dto.RequestPasswordResetInput
+	if err := c.BindJSON(&resetDto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := dto.Validate.Struct(resetDto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := u.accountService.RequestPasswordReset(resetDto.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "If the email is registered, a password reset token was sent to it"})
+}
+
+func (u *UserHandler) ConfirmPasswordReset(c *gin.Context) {
+	var resetDto dto.ConfirmPasswordResetInput
+	if err := c.BindJSON(&resetDto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := dto.Validate.Struct(resetDto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := u.accountService.ResetPassword(resetDto)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Password updated successfully"})
+}
+
 func (u *UserHandler) FindByEmail(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
 	email := c.Param("email")
 	err := dto.Validate.Var(email, "email")
 	if err != nil {
@@ -47,15 +143,21 @@ func (u *UserHandler) FindByEmail(c *gin.Context) {
 		return
 	}
 
-	user, err := u.userService.FindByEmail(email)
+	user, err := u.userService.FindByEmail(userID, email)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, user)
 }
 
 func (u *UserHandler) DeleteUser(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
 	email := c.Param("email")
 
 	err := dto.Validate.Var(email, "email")
@@ -64,9 +166,9 @@ func (u *UserHandler) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	err = u.userService.Delete(email)
+	err = u.userService.Delete(userID, email)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
@@ -85,15 +187,156 @@ func (u *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := u.userService.Login(user)
+	if !u.allowPasswordCheck(c, user.Email) {
+		return
+	}
+	authenticated, err := u.userService.Authenticate(user)
+	u.recordPasswordCheck(c, user.Email, err)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	if authenticated.TwoFactorEnabled {
+		challenge, err := u.twoFactorService.Challenge(authenticated)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, challenge)
+		return
+	}
+
+	tokens, err := u.sessionService.Start(authenticated.ID, sessionClient(c))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Refresh exchanges a refresh token for a new pair of tokens. The refresh
+// token can only be used once.
+func (u *UserHandler) Refresh(c *gin.Context) {
+	var refreshDto dto.RefreshTokenInput
+	if err := c.BindJSON(&refreshDto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := dto.Validate.Struct(refreshDto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := u.sessionService.Refresh(refreshDto.RefreshToken, sessionClient(c))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, token)
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout ends the session of the access token.
+func (u *UserHandler) Logout(c *gin.Context) {
+	userID, sessionID, ok := currentSession(c)
+	if !ok {
+		return
+	}
+
+	err := u.sessionService.Revoke(userID, sessionID)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+func (u *UserHandler) FindSessions(c *gin.Context) {
+	userID, sessionID, ok := currentSession(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := u.sessionService.FindByUserId(userID, sessionID)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+func (u *UserHandler) RevokeSession(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	err = u.sessionService.Revoke(userID, id)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
+}
+
+func currentSession(c *gin.Context) (int, int, bool) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return 0, 0, false
+	}
+	sessionID, ok := middleware.CurrentSessionID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return 0, 0, false
+	}
+	return userID, sessionID, true
+}
+
+// allowPasswordCheck refuses requests from accounts and IPs that failed too
+// many password checks, before the password is compared.
+func (u *UserHandler) allowPasswordCheck(c *gin.Context, email string) bool {
+	wait, ok := u.loginThrottle.Allow(email, c.ClientIP())
+	if !ok {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": models.ErrTooManyAttempts.Error()})
+	}
+	return ok
+}
+
+func (u *UserHandler) recordPasswordCheck(c *gin.Context, email string, err error) {
+	switch err {
+	case nil:
+		u.loginThrottle.Success(email, c.ClientIP())
+	case models.ErrInvalidCredentials:
+		u.loginThrottle.Failure(email, c.ClientIP())
+	default:
+		u.loginThrottle.Release(email, c.ClientIP())
+	}
+}
+
+func sessionClient(c *gin.Context) dto.SessionClient {
+	return dto.SessionClient{
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	}
 }
 
 func (u *UserHandler) UpdatePassword(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
 	var user dto.UpdateUserPasswordInput
 	if err := c.BindJSON(&user); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -106,15 +349,25 @@ func (u *UserHandler) UpdatePassword(c *gin.Context) {
 		return
 	}
 
-	err = u.userService.UpdatePassword(user)
+	if !u.allowPasswordCheck(c, user.Email) {
+		return
+	}
+	err = u.userService.UpdatePassword(userID, user)
+	u.recordPasswordCheck(c, user.Email, err)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Password updated successfully"})
 }
 
 func (u *UserHandler) FindById(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
 	idStr := c.Param("id")
 	var id int
 	var err error
@@ -123,11 +376,41 @@ func (u *UserHandler) FindById(c *gin.Context) {
 		return
 	}
 
-	user, err := u.userService.FindById(id)
+	user, err := u.userService.FindById(userID, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, user)
 }
+
+func (u *UserHandler) FeedToken(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	feedToken, err := u.userService.FeedToken(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, feedToken)
+}
+
+func (u *UserHandler) RegenerateFeedToken(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	feedToken, err := u.userService.RegenerateFeedToken(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, feedToken)
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/logger"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/websub"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+const maxPushSize = 10 << 20
+
+type WebSubHandler struct {
+	subscriber interfaces.WebSubSubscriberInterface
+}
+
+func NewWebSubHandler(subscriber interfaces.WebSubSubscriberInterface) *WebSubHandler {
+	return &WebSubHandler{subscriber: subscriber}
+}
+
+func (wh *WebSubHandler) Verify(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid source ID"})
+		return
+	}
+
+	leaseSeconds, _ := strconv.Atoi(c.Query("hub.lease_seconds"))
+	challenge, err := wh.subscriber.Verify(id, c.Param("token"), c.Query("hub.mode"), c.Query("hub.topic"), c.Query("hub.challenge"), leaseSeconds)
+	if err != nil {
+		logger.Errorf("WebSub verification refused for source %d: %v", id, err)
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("WebSub verification accepted for source: " + idStr)
+	c.String(http.StatusOK, challenge)
+}
+
+func (wh *WebSubHandler) Receive(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid source ID"})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPushSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read pushed content"})
+		return
+	}
+
+	stats, err := wh.subscriber.Receive(id, c.Param("token"), c.GetHeader("X-Hub-Signature"), body)
+	switch err {
+	case nil:
+		c.JSON(http.StatusOK, stats)
+	case websub.ErrUnknownSubscription:
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case websub.ErrInvalidSignature:
+		// Hubs must not learn whether the signature matched, the content is ignored.
+		logger.Errorf("WebSub content ignored for source %d: %v", id, err)
+		c.Status(http.StatusAccepted)
+	default:
+		logger.Errorf("WebSub content rejected for source %d: %v", id, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
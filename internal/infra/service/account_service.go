@@ -0,0 +1,190 @@
+package service
+
+import (
+	"errors"
+	"github.com/google/logger"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/auth"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/dto"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/email"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"github.com/matheusvidal21/smart-news-fetcher/pkg/utils"
+	"html"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	userTokenIDSize      = 16
+	passwordResetTTL     = time.Hour
+	emailVerificationTTL = 48 * time.Hour
+)
+
+type AccountOptions struct {
+	// BaseURL is where the API is reached from the links sent by email.
+	BaseURL string
+}
+
+// AccountService sends the signed, single-use tokens that let users verify
+// their email address and reset a forgotten password.
+type AccountService struct {
+	userRepository      interfaces.UserRepositoryInterface
+	userTokenRepository interfaces.UserTokenRepositoryInterface
+	jwtService          auth.JWTServiceInterface
+	emailService        interfaces.EmailService
+	options             AccountOptions
+}
+
+func NewAccountService(userRepository interfaces.UserRepositoryInterface, userTokenRepository interfaces.UserTokenRepositoryInterface, jwtService auth.JWTServiceInterface, emailService interfaces.EmailService, options AccountOptions) *AccountService {
+	options.BaseURL = strings.TrimRight(options.BaseURL, "/")
+	return &AccountService{
+		userRepository:      userRepository,
+		userTokenRepository: userTokenRepository,
+		jwtService:          jwtService,
+		emailService:        emailService,
+		options:             options,
+	}
+}
+
+func (as *AccountService) issueToken(userID int, purpose string, ttl time.Duration) (string, error) {
+	id, err := utils.RandomToken(userTokenIDSize)
+	if err != nil {
+		return "", errors.New("Failed to generate token: " + err.Error())
+	}
+
+	now := time.Now().UTC()
+	err = as.userTokenRepository.Create(models.UserToken{
+		ID:        id,
+		UserID:    userID,
+		Purpose:   purpose,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	})
+	if err != nil {
+		return "", errors.New("Failed to save token: " + err.Error())
+	}
+
+	token, err := as.jwtService.GenerateActionToken(auth.Action{ID: id, UserID: userID, Purpose: purpose}, ttl)
+	if err != nil {
+		return "", errors.New("Failed to sign token: " + err.Error())
+	}
+	return token, nil
+}
+
+// useToken checks the signature of the token and marks it as used, returning
+// the user it was issued to.
+func (as *AccountService) useToken(token, purpose string) (*models.User, error) {
+	action, err := as.jwtService.ValidateActionToken(token, purpose)
+	if err != nil {
+		return nil, models.ErrInvalidEmailToken
+	}
+
+	used, err := as.userTokenRepository.Use(action.ID, action.UserID, purpose, time.Now().UTC())
+	if err != nil {
+		return nil, errors.New("Failed to use token: " + err.Error())
+	}
+	if !used {
+		return nil, models.ErrInvalidEmailToken
+	}
+
+	user, err := as.userRepository.FindById(action.UserID)
+	if err != nil {
+		return nil, models.ErrInvalidEmailToken
+	}
+	return user, nil
+}
+
+// SendVerification emails the user a link confirming they own their address.
+func (as *AccountService) SendVerification(userID int) error {
+	user, err := as.userRepository.FindById(userID)
+	if err != nil {
+		return models.ErrUserNotFound
+	}
+	if user.EmailVerified {
+		return errors.New("Email already verified")
+	}
+
+	token, err := as.issueToken(user.ID, models.TokenPurposeEmailVerification, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	link := as.options.BaseURL + "/users/verify_email?token=" + url.QueryEscape(token)
+	message := email.Message{
+		ToEmail:          user.Email,
+		Subject:          "Confirm your email for Smart News Fetcher",
+		PlainTextContent: "Hi " + user.Username + ", confirm your email address to receive newsletters by opening this link within 48 hours:\n\n" + link,
+		HtmlContent:      "<p>Hi " + html.EscapeString(user.Username) + ", confirm your email address to receive newsletters by opening this link within 48 hours:</p><p><a href=\"" + html.EscapeString(link) + "\">Confirm email</a></p>",
+	}
+	err = as.emailService.Send(message)
+	if err != nil {
+		return errors.New("Failed to send email: " + err.Error())
+	}
+	return nil
+}
+
+func (as *AccountService) VerifyEmail(token string) error {
+	user, err := as.useToken(token, models.TokenPurposeEmailVerification)
+	if err != nil {
+		return err
+	}
+
+	err = as.userRepository.UpdateEmailVerified(user.ID, true)
+	if err != nil {
+		return errors.New("Failed to verify email: " + err.Error())
+	}
+	return nil
+}
+
+// RequestPasswordReset emails a password reset token to the user. Unknown and
+// disabled accounts are ignored without an error, so the endpoint does not
+// reveal which emails are registered.
+func (as *AccountService) RequestPasswordReset(address string) error {
+	user, err := as.userRepository.FindByEmail(address)
+	if err != nil || user.Disabled {
+		return nil
+	}
+
+	token, err := as.issueToken(user.ID, models.TokenPurposePasswordReset, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	message := email.Message{
+		ToEmail:          user.Email,
+		Subject:          "Reset your Smart News Fetcher password",
+		PlainTextContent: "Hi " + user.Username + ", use this token within one hour to choose a new password at " + as.options.BaseURL + "/users/password_reset/confirm:\n\n" + token + "\n\nIf you did not ask for it, ignore this email.",
+		HtmlContent:      "<p>Hi " + html.EscapeString(user.Username) + ", use this token within one hour to choose a new password at " + html.EscapeString(as.options.BaseURL) + "/users/password_reset/confirm:</p><p><code>" + html.EscapeString(token) + "</code></p><p>If you did not ask for it, ignore this email.</p>",
+	}
+	err = as.emailService.Send(message)
+	if err != nil {
+		logger.Errorf("Failed to send password reset email to user %d: %v", user.ID, err)
+	}
+	return nil
+}
+
+// ResetPassword sets the new password and ends every session of the user, as
+// well as any other reset token sent to them.
+func (as *AccountService) ResetPassword(resetDto dto.ConfirmPasswordResetInput) error {
+	user, err := as.useToken(resetDto.Token, models.TokenPurposePasswordReset)
+	if err != nil {
+		return err
+	}
+
+	user.Password = resetDto.NewPassword
+	_, err = as.userRepository.Update(*user)
+	if err != nil {
+		return errors.New("Failed to update password: " + err.Error())
+	}
+
+	err = as.userRepository.IncrementTokenVersion(user.ID)
+	if err != nil {
+		return errors.New("Password updated but failed to end sessions: " + err.Error())
+	}
+	err = as.userTokenRepository.UseAll(user.ID, models.TokenPurposePasswordReset, time.Now().UTC())
+	if err != nil {
+		return errors.New("Password updated but failed to revoke reset tokens: " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"errors"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/dto"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+)
+
+// AdminService gives administrators access to every user and source, without
+// the ownership checks of the other services.
+type AdminService struct {
+	userRepository         interfaces.UserRepositoryInterface
+	recoveryCodeRepository interfaces.RecoveryCodeRepositoryInterface
+	sourceRepository       interfaces.SourceRepositoryInterface
+	scheduler              interfaces.SchedulerInterface
+}
+
+func NewAdminService(userRepository interfaces.UserRepositoryInterface, recoveryCodeRepository interfaces.RecoveryCodeRepositoryInterface, sourceRepository interfaces.SourceRepositoryInterface, scheduler interfaces.SchedulerInterface) *AdminService {
+	return &AdminService{
+		userRepository:         userRepository,
+		recoveryCodeRepository: recoveryCodeRepository,
+		sourceRepository:       sourceRepository,
+		scheduler:              scheduler,
+	}
+}
+
+func (as *AdminService) FindUsers(page, limit int) ([]models.User, error) {
+	users, err := as.userRepository.FindAll(page, limit)
+	if err != nil {
+		return []models.User{}, errors.New("Failed to find users: " + err.Error())
+	}
+	return users, nil
+}
+
+func (as *AdminService) findUser(id int) (*models.User, error) {
+	user, err := as.userRepository.FindById(id)
+	if err != nil {
+		return nil, models.ErrUserNotFound
+	}
+	return user, nil
+}
+
+// UpdateUserRole changes the role of the user. Administrators cannot demote
+// themselves, so there is always one left.
+func (as *AdminService) UpdateUserRole(adminID, userID int, roleDto dto.UpdateUserRoleInput) (models.User, error) {
+	user, err := as.findUser(userID)
+	if err != nil {
+		return models.User{}, err
+	}
+	if userID == adminID && roleDto.Role != models.RoleAdmin {
+		return models.User{}, errors.New("Administrators cannot remove their own role")
+	}
+
+	if user.Role == roleDto.Role {
+		return *user, nil
+	}
+
+	err = as.userRepository.UpdateRole(userID, roleDto.Role)
+	if err != nil {
+		return models.User{}, errors.New("Failed to update user role: " + err.Error())
+	}
+	// Access tokens carry the role, so the sessions of the user end and the
+	// new role applies from their next login.
+	err = as.userRepository.IncrementTokenVersion(userID)
+	if err != nil {
+		return models.User{}, errors.New("Failed to end user sessions: " + err.Error())
+	}
+	user.Role = roleDto.Role
+	user.TokenVersion++
+	return *user, nil
+}
+
+// DisableUser stops the user from logging in, ends their sessions and stops
+// fetching their sources. Administrators cannot disable their own account.
+func (as *AdminService) DisableUser(adminID, userID int) (models.User, error) {
+	if userID == adminID {
+		return models.User{}, errors.New("Administrators cannot disable their own account")
+	}
+	return as.setDisabled(userID, true)
+}
+
+func (as *AdminService) EnableUser(userID int) (models.User, error) {
+	return as.setDisabled(userID, false)
+}
+
+func (as *AdminService) setDisabled(userID int, disabled bool) (models.User, error) {
+	user, err := as.findUser(userID)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	err = as.userRepository.UpdateDisabled(userID, disabled)
+	if err != nil {
+		return models.User{}, errors.New("Failed to update user: " + err.Error())
+	}
+	if disabled {
+		err = as.userRepository.IncrementTokenVersion(userID)
+		if err != nil {
+			return models.User{}, errors.New("Failed to end user sessions: " + err.Error())
+		}
+		user.TokenVersion++
+	}
+	user.Disabled = disabled
+
+	sources, err := as.sourceRepository.FindByUserId(userID)
+	if err != nil {
+		return models.User{}, errors.New("Failed to find user sources: " + err.Error())
+	}
+	for _, source := range sources {
+		if disabled {
+			as.scheduler.Cancel(source.ID)
+		} else {
+			as.scheduler.Schedule(source)
+		}
+	}
+	return *user, nil
+}
+
+// ResetTwoFactor removes the second factor of a user who lost both their
+// authenticator and their recovery codes, so they can log in with the password
+// and enroll again.
+func (as *AdminService) ResetTwoFactor(userID int) (models.User, error) {
+	user, err := as.findUser(userID)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	err = as.userRepository.UpdateTwoFactor(userID, "", false)
+	if err != nil {
+		return models.User{}, errors.New("Failed to reset two-factor authentication: " + err.Error())
+	}
+	err = as.recoveryCodeRepository.DeleteByUserId(userID)
+	if err != nil {
+		return models.User{}, errors.New("Failed to delete recovery codes: " + err.Error())
+	}
+	user.TwoFactorEnabled = false
+	return *user, nil
+}
+
+func (as *AdminService) FindSources(page, limit int, sort string) ([]models.Source, error) {
+	sources, err := as.sourceRepository.FindAll(0, page, limit, sort)
+	if err != nil {
+		return []models.Source{}, errors.New("Failed to find sources: " + err.Error())
+	}
+	return sources, nil
+}
+
+// FetchHealth summarizes the fetch status of every source and lists the ones
+// failing or disabled after too many failures.
+func (as *AdminService) FetchHealth() (dto.FetchHealthOutput, error) {
+	sources, err := as.sourceRepository.FindAll(0, 0, 0, "asc")
+	if err != nil {
+		return dto.FetchHealthOutput{}, errors.New("Failed to find sources: " + err.Error())
+	}
+
+	health := dto.FetchHealthOutput{
+		Sources:   len(sources),
+		ByStatus:  make(map[string]int),
+		Unhealthy: []models.Source{},
+	}
+	for _, source := range sources {
+		health.ByStatus[source.FetchStatus]++
+		switch {
+		case source.Disabled:
+			health.Disabled++
+		case source.ConsecutiveFailures > 0:
+			health.Failing++
+		default:
+			health.Healthy++
+			continue
+		}
+		health.Unhealthy = append(health.Unhealthy, source)
+	}
+	return health, nil
+}
+
+// FetchSource fetches the source right away, resuming it first when it was
+// disabled after too many failures.
+func (as *AdminService) FetchSource(id int) error {
+	source, err := as.sourceRepository.FindOne(id)
+	if err != nil {
+		return models.ErrSourceNotFound
+	}
+
+	if source.Disabled {
+		err = as.sourceRepository.UpdateFetchState(id, 0, false, "", "")
+		if err != nil {
+			return errors.New("Failed to resume source: " + err.Error())
+		}
+		source.Disabled = false
+		source.ConsecutiveFailures = 0
+		source.FetchStatus = ""
+		source.LastError = ""
+	}
+
+	as.scheduler.FetchNow(source)
+	return nil
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"errors"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/dto"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"testing"
+)
+
+type roleUsers struct {
+	interfaces.UserRepositoryInterface
+	users map[int]models.User
+}
+
+func (r *roleUsers) FindById(id int) (*models.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, errors.New("user not found")
+	}
+	return &user, nil
+}
+
+func (r *roleUsers) UpdateRole(id int, role string) error {
+	user := r.users[id]
+	user.Role = role
+	r.users[id] = user
+	return nil
+}
+
+func (r *roleUsers) IncrementTokenVersion(id int) error {
+	user := r.users[id]
+	user.TokenVersion++
+	r.users[id] = user
+	return nil
+}
+
+func (r *roleUsers) UpdateDisabled(id int, disabled bool) error {
+	user := r.users[id]
+	user.Disabled = disabled
+	r.users[id] = user
+	return nil
+}
+
+type userSources struct {
+	interfaces.SourceRepositoryInterface
+	sources []models.Source
+}
+
+func (r userSources) FindByUserId(userID int) ([]models.Source, error) {
+	var sources []models.Source
+	for _, source := range r.sources {
+		if source.UserID == userID {
+			sources = append(sources, source)
+		}
+	}
+	return sources, nil
+}
+
+type recordingScheduler struct {
+	interfaces.SchedulerInterface
+	scheduled map[int]bool
+}
+
+func (s *recordingScheduler) Schedule(source models.Source) {
+	s.scheduled[source.ID] = true
+}
+
+func (s *recordingScheduler) Cancel(sourceID int) {
+	delete(s.scheduled, sourceID)
+}
+
+func TestDisableUser(t *testing.T) {
+	users := &roleUsers{users: map[int]models.User{
+		1: {ID: 1, Role: models.RoleAdmin},
+		2: {ID: 2, Role: models.RoleUser, TokenVersion: 3},
+	}}
+	sources := userSources{sources: []models.Source{{ID: 10, UserID: 2}, {ID: 11, UserID: 2}, {ID: 12, UserID: 1}}}
+	scheduler := &recordingScheduler{scheduled: map[int]bool{10: true, 11: true, 12: true}}
+	admin := NewAdminService(users, nil, sources, scheduler)
+
+	user, err := admin.DisableUser(1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !user.Disabled || !users.users[2].Disabled {
+		t.Errorf("user not disabled")
+	}
+	if users.users[2].TokenVersion != 4 {
+		t.Errorf("token version = %d, want 4 so the sessions of the user end", users.users[2].TokenVersion)
+	}
+	if scheduler.scheduled[10] || scheduler.scheduled[11] || !scheduler.scheduled[12] {
+		t.Errorf("scheduled sources = %v, want only the source of another user", scheduler.scheduled)
+	}
+
+	_, err = admin.EnableUser(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if users.users[2].Disabled || !scheduler.scheduled[10] || !scheduler.scheduled[11] {
+		t.Errorf("enabled user keeps disabled %t with scheduled sources %v", users.users[2].Disabled, scheduler.scheduled)
+	}
+
+	if _, err := admin.DisableUser(1, 1); err == nil || users.users[1].Disabled {
+		t.Errorf("administrator disabled their own account")
+	}
+}
+
+func TestUpdateUserRoleEndsSessions(t *testing.T) {
+	users := &roleUsers{users: map[int]models.User{
+		1: {ID: 1, Role: models.RoleAdmin},
+		2: {ID: 2, Role: models.RoleAdmin, TokenVersion: 3},
+	}}
+	admin := NewAdminService(users, nil, nil, nil)
+
+	user, err := admin.UpdateUserRole(1, 2, dto.UpdateUserRoleInput{Role: models.RoleUser})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Role != models.RoleUser || users.users[2].Role != models.RoleUser {
+		t.Errorf("role = %q, stored %q, want %q", user.Role, users.users[2].Role, models.RoleUser)
+	}
+	if users.users[2].TokenVersion != 4 {
+		t.Errorf("token version = %d, want 4 so the admin tokens of the user stop working", users.users[2].TokenVersion)
+	}
+
+	_, err = admin.UpdateUserRole(1, 2, dto.UpdateUserRoleInput{Role: models.RoleUser})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if users.users[2].TokenVersion != 4 {
+		t.Errorf("token version = %d, want 4 when the role does not change", users.users[2].TokenVersion)
+	}
+
+	_, err = admin.UpdateUserRole(1, 1, dto.UpdateUserRoleInput{Role: models.RoleUser})
+	if err == nil || users.users[1].Role != models.RoleAdmin {
+		t.Errorf("administrator removed their own role")
+	}
+}
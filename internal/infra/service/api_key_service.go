@@ -0,0 +1,132 @@
+package service
+
+import (
+	"errors"
+	"github.com/google/logger"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/auth"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/dto"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"github.com/matheusvidal21/smart-news-fetcher/pkg/utils"
+	"strings"
+	"time"
+)
+
+const (
+	apiKeySize = 32
+	// Only the first characters of a key are kept in clear so users can
+	// recognize it in listings.
+	apiKeyDisplayLength = 12
+	// Last use is recorded at most once per interval to spare a write on every
+	// request of busy scripts.
+	apiKeyLastUsedInterval = time.Minute
+)
+
+// APIKeyService mints the personal API keys of users and authenticates the
+// requests made with them.
+type APIKeyService struct {
+	apiKeyRepository interfaces.APIKeyRepositoryInterface
+	userRepository   interfaces.UserRepositoryInterface
+}
+
+func NewAPIKeyService(apiKeyRepository interfaces.APIKeyRepositoryInterface, userRepository interfaces.UserRepositoryInterface) *APIKeyService {
+	return &APIKeyService{
+		apiKeyRepository: apiKeyRepository,
+		userRepository:   userRepository,
+	}
+}
+
+// Create mints a key for the user. The key itself is only returned here, only
+// its hash is stored.
+func (ks *APIKeyService) Create(userID int, input dto.CreateAPIKeyInput) (dto.CreateAPIKeyOutput, error) {
+	now := time.Now().UTC()
+	var expiresAt time.Time
+	if input.ExpiresAt != nil {
+		expiresAt = input.ExpiresAt.UTC()
+		if !expiresAt.After(now) {
+			return dto.CreateAPIKeyOutput{}, models.ErrAPIKeyExpiry
+		}
+	}
+
+	secret, err := utils.RandomToken(apiKeySize)
+	if err != nil {
+		return dto.CreateAPIKeyOutput{}, errors.New("Failed to generate API key: " + err.Error())
+	}
+	key := models.APIKeyPrefix + secret
+
+	scopes := input.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	apiKey, err := ks.apiKeyRepository.Create(models.APIKey{
+		UserID:    userID,
+		Name:      strings.TrimSpace(input.Name),
+		Prefix:    key[:apiKeyDisplayLength],
+		KeyHash:   utils.HashToken(key),
+		Scopes:    scopes,
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return dto.CreateAPIKeyOutput{}, errors.New("Failed to create API key: " + err.Error())
+	}
+	return dto.CreateAPIKeyOutput{Key: key, APIKey: apiKey}, nil
+}
+
+func (ks *APIKeyService) FindByUserId(userID int) ([]models.APIKey, error) {
+	keys, err := ks.apiKeyRepository.FindByUserId(userID)
+	if err != nil {
+		return []models.APIKey{}, errors.New("Failed to find API keys: " + err.Error())
+	}
+	return keys, nil
+}
+
+// Revoke disables one of the user's keys. Keys of other users are reported as
+// not found.
+func (ks *APIKeyService) Revoke(userID, id int) error {
+	apiKey, err := ks.apiKeyRepository.FindOne(id)
+	if err != nil || apiKey.UserID != userID || !apiKey.RevokedAt.IsZero() {
+		return models.ErrAPIKeyNotFound
+	}
+
+	err = ks.apiKeyRepository.Revoke(id, time.Now().UTC())
+	if err != nil {
+		return errors.New("Failed to revoke API key: " + err.Error())
+	}
+	return nil
+}
+
+// Authenticate resolves a key presented to the API into the identity of its
+// user and the scopes the key is limited to.
+func (ks *APIKeyService) Authenticate(key string) (auth.Identity, []string, error) {
+	if !strings.HasPrefix(key, models.APIKeyPrefix) {
+		return auth.Identity{}, nil, models.ErrInvalidAPIKey
+	}
+
+	now := time.Now().UTC()
+	apiKey, err := ks.apiKeyRepository.FindByHash(utils.HashToken(key))
+	if err != nil || !apiKey.Active(now) {
+		return auth.Identity{}, nil, models.ErrInvalidAPIKey
+	}
+
+	user, err := ks.userRepository.FindById(apiKey.UserID)
+	if err != nil {
+		return auth.Identity{}, nil, models.ErrInvalidAPIKey
+	}
+	if user.Disabled {
+		return auth.Identity{}, nil, models.ErrUserDisabled
+	}
+
+	if now.Sub(apiKey.LastUsedAt) >= apiKeyLastUsedInterval {
+		if err := ks.apiKeyRepository.UpdateLastUsed(apiKey.ID, now); err != nil {
+			logger.Errorf("Failed to record use of API key %d: %v", apiKey.ID, err)
+		}
+	}
+
+	return auth.Identity{
+		UserID:       user.ID,
+		Email:        user.Email,
+		Role:         user.Role,
+		TokenVersion: user.TokenVersion,
+	}, apiKey.Scopes, nil
+}
@@ -2,66 +2,585 @@ package service
 
 import (
 	"crypto/sha1"
+	"crypto/sha256"
+	"database/sql"
 	"encoding/hex"
 	"errors"
+	"github.com/google/logger"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/dto"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/sanitize"
+	"html"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// snippetContext is how many bytes of text a search snippet shows before
+	// the first match, half its length.
+	snippetContext = 80
+	// plainTextBatchSize is how many articles BackfillPlainText reads at once.
+	plainTextBatchSize = 500
 )
 
 type ArticleService struct {
-	articleRepository interfaces.ArticleRepositoryInterface
+	articleRepository  interfaces.ArticleRepositoryInterface
+	revisionRepository interfaces.ArticleRevisionRepositoryInterface
+	stateRepository    interfaces.ArticleStateRepositoryInterface
+	sourceRepository   interfaces.SourceRepositoryInterface
 }
 
-func NewArticleService(articleRepository interfaces.ArticleRepositoryInterface) *ArticleService {
-	return &ArticleService{articleRepository: articleRepository}
+func NewArticleService(articleRepository interfaces.ArticleRepositoryInterface, revisionRepository interfaces.ArticleRevisionRepositoryInterface, stateRepository interfaces.ArticleStateRepositoryInterface, sourceRepository interfaces.SourceRepositoryInterface) *ArticleService {
+	return &ArticleService{articleRepository: articleRepository, revisionRepository: revisionRepository, stateRepository: stateRepository, sourceRepository: sourceRepository}
 }
 
-func (as *ArticleService) GenerateArticleID(title, link string) string {
+// ownedArticle returns the article when it comes from a source of the user.
+func (as *ArticleService) ownedArticle(userID int, id string) (models.Article, error) {
+	article, err := as.articleRepository.FindOne(id)
+	if err != nil {
+		return models.Article{}, models.ErrArticleNotFound
+	}
+	if err := as.ownsSource(userID, article.SourceID); err != nil {
+		return models.Article{}, models.ErrArticleNotFound
+	}
+	return article, nil
+}
+
+func (as *ArticleService) ownsSource(userID, sourceID int) error {
+	source, err := as.sourceRepository.FindOne(sourceID)
+	if err != nil || source.UserID != userID {
+		return models.ErrSourceNotFound
+	}
+	return nil
+}
+
+// GenerateArticleID identifies an item within its source by its GUID, falling
+// back to its normalized link and then to its title, so that edits to the
+// title or content keep the same article.
+func (as *ArticleService) GenerateArticleID(sourceID int, guid, link, title string) string {
+	key := "guid:" + strings.TrimSpace(guid)
+	if strings.TrimSpace(guid) == "" {
+		key = "link:" + normalizeLink(link)
+		if strings.TrimSpace(link) == "" {
+			key = "title:" + strings.TrimSpace(title)
+		}
+	}
+
+	h := sha1.New()
+	h.Write([]byte(strconv.Itoa(sourceID) + "|" + key))
+	return hex.EncodeToString(h.Sum(nil))[:35]
+}
+
+// legacyArticleID is the id given to articles before GUID based identity.
+func legacyArticleID(title, link string) string {
 	h := sha1.New()
 	h.Write([]byte(title + link))
 	return hex.EncodeToString(h.Sum(nil))[:35]
 }
 
-func (as *ArticleService) FindAll(page, limit int, sort string) ([]models.Article, error) {
-	articles, err := as.articleRepository.FindAll(page, limit, sort)
+// normalizeLink drops what does not change the target of a link: case of the
+// scheme and host, default ports, fragments, tracking parameters, the order of
+// the query and a trailing slash.
+func normalizeLink(link string) string {
+	link = strings.TrimSpace(link)
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		return link
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if (u.Scheme == "http" && u.Port() == "80") || (u.Scheme == "https" && u.Port() == "443") {
+		u.Host = u.Hostname()
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+
+	query := u.Query()
+	for key := range query {
+		if strings.HasPrefix(strings.ToLower(key), "utm_") {
+			query.Del(key)
+		}
+	}
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var params []string
+	for _, key := range keys {
+		values := query[key]
+		sort.Strings(values)
+		for _, value := range values {
+			params = append(params, url.QueryEscape(key)+"="+url.QueryEscape(value))
+		}
+	}
+	u.RawQuery = strings.Join(params, "&")
+	return u.String()
+}
+
+// sanitizeArticle cleans the publisher HTML, resolving relative URLs against the
+// article link, and derives the plain text used for search, previews and the
+// text part of emails.
+func sanitizeArticle(article *models.Article) {
+	base, err := url.Parse(article.Link)
+	if err != nil || !base.IsAbs() {
+		base = nil
+	}
+	article.Description = sanitize.HTML(article.Description, base)
+	article.Content = sanitize.HTML(article.Content, base)
+	article.PlainText = plainText(*article)
+}
+
+// plainText joins the text of the description, the content and the content
+// extracted from the article page, so a search finds words from any of them.
+// A text already found within another one, like a summary repeating the start
+// of the content, is only kept once.
+func plainText(article models.Article) string {
+	var parts []string
+	for _, content := range []string{article.Description, article.Content, article.ExtractedContent} {
+		text := sanitize.Text(content)
+		if text == "" || containsText(parts, text) {
+			continue
+		}
+		kept := parts[:0]
+		for _, part := range parts {
+			if !strings.Contains(text, part) {
+				kept = append(kept, part)
+			}
+		}
+		parts = append(kept, text)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// BackfillPlainText derives the plain text of the articles stored before it was
+// kept, so search finds them by their description and content and not only by
+// their title.
+func (as *ArticleService) BackfillPlainText() {
+	afterID := ""
+	updated := 0
+	for {
+		articles, err := as.articleRepository.FindWithoutPlainText(afterID, plainTextBatchSize)
+		if err != nil {
+			logger.Errorf("Failed to find articles without plain text: %v", err)
+			return
+		}
+		for _, article := range articles {
+			afterID = article.ID
+			text := plainText(article)
+			if text == "" {
+				continue
+			}
+			err = as.articleRepository.UpdatePlainText(article.ID, text)
+			if err != nil {
+				logger.Errorf("Failed to update plain text of article %s: %v", article.ID, err)
+				continue
+			}
+			updated++
+		}
+		if len(articles) < plainTextBatchSize {
+			break
+		}
+	}
+	if updated > 0 {
+		logger.Infof("Plain text derived for %d articles", updated)
+	}
+}
+
+func containsText(parts []string, text string) bool {
+	for _, part := range parts {
+		if strings.Contains(part, text) {
+			return true
+		}
+	}
+	return false
+}
+
+func contentHash(article models.Article) string {
+	h := sha256.New()
+	for _, field := range []string{article.Title, article.Description, article.Content, article.Link, article.Author} {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Save stores a fetched item, creating the article the first time it is seen
+// and updating it with a revision of the previous version when its content
+// changed. It reports whether the article was created, updated or unchanged.
+func (as *ArticleService) Save(articleDto dto.CreateArticleInput) (string, error) {
+	article := models.Article{
+		ID:            as.GenerateArticleID(articleDto.SourceID, articleDto.Guid, articleDto.Link, articleDto.Title),
+		Title:         articleDto.Title,
+		Description:   articleDto.Description,
+		Content:       articleDto.Content,
+		Link:          articleDto.Link,
+		PubDate:       articleDto.PubDate,
+		Author:        articleDto.Author,
+		SourceID:      articleDto.SourceID,
+		Guid:          articleDto.Guid,
+		PubDateSource: articleDto.PubDateSource,
+	}
+	sanitizeArticle(&article)
+	article.ContentHash = contentHash(article)
+	setPubDate(&article)
+
+	existing, err := as.findExisting(article)
+	if err == sql.ErrNoRows {
+		_, err = as.articleRepository.Create(article)
+		if err != nil {
+			return "", errors.New("Article not created: " + err.Error())
+		}
+		return models.ArticleCreated, nil
+	}
 	if err != nil {
-		return nil, errors.New("Articles not found: " + err.Error())
+		return "", errors.New("Failed to find article: " + err.Error())
 	}
-	return articles, nil
+
+	if existing.ExtractedContent != "" {
+		article.ExtractedContent = existing.ExtractedContent
+		article.PlainText = plainText(article)
+	}
+
+	// An undated item keeps the date it was first seen with.
+	if article.PubDateSource == models.PubDateFirstSeen && !existing.PubDate.IsZero() {
+		article.PubDate = existing.PubDate
+		article.PubDateSource = existing.PubDateSource
+	}
+
+	// Articles stored before content hashes or sanitization are compared in
+	// their sanitized form, so that only real changes make a revision.
+	hashStored := existing.ContentHash != "" && (existing.PlainText != "" || (existing.Description == "" && existing.Content == ""))
+	if !hashStored {
+		current := existing
+		sanitizeArticle(&current)
+		existing.ContentHash = contentHash(current)
+	}
+	if existing.ContentHash == article.ContentHash {
+		if hashStored && existing.Guid == article.Guid &&
+			existing.PubDate.Equal(article.PubDate) && existing.PubDateSource == article.PubDateSource {
+			return models.ArticleUnchanged, nil
+		}
+		err = as.revise(existing, article)
+		if err != nil {
+			return "", err
+		}
+		return models.ArticleUnchanged, nil
+	}
+
+	err = as.revise(existing, article)
+	if err != nil {
+		return "", err
+	}
+	return models.ArticleUpdated, nil
+}
+
+// setPubDate dates the articles given without a publish date with the current
+// time, marking where the date came from when the caller did not.
+func setPubDate(article *models.Article) {
+	if article.PubDate.IsZero() {
+		article.PubDate = time.Now().Truncate(time.Second)
+		article.PubDateSource = models.PubDateFirstSeen
+	}
+	if article.PubDateSource == "" {
+		article.PubDateSource = models.PubDatePublished
+	}
+}
+
+// findExisting looks the article up by id, then by the id it had before GUID
+// based identity, moving such an article to its new id.
+func (as *ArticleService) findExisting(article models.Article) (models.Article, error) {
+	existing, err := as.articleRepository.FindOne(article.ID)
+	if err != sql.ErrNoRows {
+		return existing, err
+	}
+
+	legacyID := legacyArticleID(article.Title, article.Link)
+	existing, err = as.articleRepository.FindOne(legacyID)
+	if err != nil {
+		return models.Article{}, err
+	}
+	if existing.SourceID != article.SourceID {
+		return models.Article{}, sql.ErrNoRows
+	}
+
+	err = as.articleRepository.Rekey(legacyID, article.ID)
+	if err != nil {
+		return models.Article{}, errors.New("Failed to rekey article: " + err.Error())
+	}
+	existing.ID = article.ID
+	return existing, nil
+}
+
+// revise updates the article, first recording the previous version as a
+// revision when the content changed. Articles stored before content hashes
+// get theirs without a revision.
+func (as *ArticleService) revise(existing, article models.Article) error {
+	if existing.ContentHash == "" {
+		existing.ContentHash = contentHash(existing)
+	}
+
+	if existing.ContentHash != article.ContentHash {
+		_, err := as.revisionRepository.Create(models.ArticleRevision{
+			ArticleID:   existing.ID,
+			Title:       existing.Title,
+			Description: existing.Description,
+			Content:     existing.Content,
+			Link:        existing.Link,
+			Author:      existing.Author,
+			PubDate:     existing.PubDate,
+			ContentHash: existing.ContentHash,
+			RevisedAt:   time.Now().Truncate(time.Second),
+		})
+		if err != nil {
+			return errors.New("Failed to record article revision: " + err.Error())
+		}
+	}
+
+	_, err := as.articleRepository.Update(existing.ID, article)
+	if err != nil {
+		return errors.New("Article not updated: " + err.Error())
+	}
+	return nil
 }
 
-func (as *ArticleService) FindOne(id string) (dto.FindOneArticleOutput, error) {
+// SaveExtractedContent stores the content extracted from the article page,
+// sanitized by the fetcher, and derives the plain text from it.
+func (as *ArticleService) SaveExtractedContent(id, content string) error {
 	article, err := as.articleRepository.FindOne(id)
+	if err != nil {
+		return errors.New("Article not found: " + err.Error())
+	}
+
+	article.ExtractedContent = content
+	err = as.articleRepository.UpdateExtractedContent(id, content, plainText(article), time.Now().Truncate(time.Second))
+	if err != nil {
+		return errors.New("Failed to save extracted content: " + err.Error())
+	}
+	return nil
+}
+
+func (as *ArticleService) FindRevisions(userID int, id string) ([]models.ArticleRevision, error) {
+	_, err := as.ownedArticle(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions, err := as.revisionRepository.FindByArticleId(id)
+	if err != nil {
+		return nil, errors.New("Revisions not found: " + err.Error())
+	}
+	return revisions, nil
+}
+
+// Search runs a full-text search over the articles matching the filter and
+// highlights the terms found in a snippet of each result.
+func (as *ArticleService) Search(filter models.ArticleFilter, sort string, page, limit int) (dto.SearchArticlesOutput, error) {
+	if sort != models.ArticleSortDate {
+		sort = models.ArticleSortRelevance
+	}
+
+	matches, total, err := as.articleRepository.Search(filter, sort, page, limit)
+	if err != nil {
+		return dto.SearchArticlesOutput{}, errors.New("Failed to search articles: " + err.Error())
+	}
+
+	terms := searchTerms(filter.Query)
+	for i := range matches {
+		matches[i].Snippet = snippet(matches[i].PlainText, terms)
+	}
+
+	return dto.SearchArticlesOutput{
+		Query:   filter.Query,
+		Sort:    sort,
+		Page:    page,
+		Limit:   limit,
+		Total:   total,
+		Results: matches,
+	}, nil
+}
+
+// searchTerms returns a pattern matching the words of a query, ignoring the
+// operators of boolean searches.
+func searchTerms(query string) *regexp.Regexp {
+	var words []string
+	for _, word := range strings.Fields(query) {
+		word = strings.Trim(word, `+-~<>()"*@`)
+		if len([]rune(word)) > 1 {
+			words = append(words, regexp.QuoteMeta(word))
+		}
+	}
+	if len(words) == 0 {
+		return nil
+	}
+	return regexp.MustCompile(`(?i)` + strings.Join(words, "|"))
+}
+
+// snippet cuts the text around the first search term and marks every term in
+// it. The result is HTML escaped.
+func snippet(text string, terms *regexp.Regexp) string {
+	start, end := 0, len(text)
+	if terms != nil {
+		if match := terms.FindStringIndex(text); match != nil {
+			start = match[0] - snippetContext
+		}
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end > start+2*snippetContext {
+		end = start + 2*snippetContext
+	}
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start++
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end--
+	}
+
+	window := text[start:end]
+	var builder strings.Builder
+	if start > 0 {
+		builder.WriteString("…")
+	}
+	last := 0
+	if terms != nil {
+		for _, match := range terms.FindAllStringIndex(window, -1) {
+			builder.WriteString(html.EscapeString(window[last:match[0]]))
+			builder.WriteString("<mark>" + html.EscapeString(window[match[0]:match[1]]) + "</mark>")
+			last = match[1]
+		}
+	}
+	builder.WriteString(html.EscapeString(window[last:]))
+	if end < len(text) {
+		builder.WriteString("…")
+	}
+	return builder.String()
+}
+
+// FindState returns what the user did with the article, an unread state when
+// nothing yet.
+func (as *ArticleService) FindState(userID int, id string) (models.ArticleState, error) {
+	_, err := as.ownedArticle(userID, id)
+	if err != nil {
+		return models.ArticleState{}, err
+	}
+
+	state, err := as.stateRepository.FindOne(userID, id)
+	if err == sql.ErrNoRows {
+		return models.ArticleState{UserID: userID, ArticleID: id}, nil
+	}
+	if err != nil {
+		return models.ArticleState{}, errors.New("Failed to find article state: " + err.Error())
+	}
+	return state, nil
+}
 
+// UpdateState changes the flags given, recording when each one was set.
+func (as *ArticleService) UpdateState(userID int, id string, stateDto dto.UpdateArticleStateInput) (models.ArticleState, error) {
+	state, err := as.FindState(userID, id)
 	if err != nil {
-		return dto.FindOneArticleOutput{}, errors.New("Article not found: " + err.Error())
+		return models.ArticleState{}, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	setFlag(&state.Read, &state.ReadAt, stateDto.Read, now)
+	setFlag(&state.Starred, &state.StarredAt, stateDto.Starred, now)
+	setFlag(&state.Archived, &state.ArchivedAt, stateDto.Archived, now)
+
+	err = as.stateRepository.Save(state)
+	if err != nil {
+		return models.ArticleState{}, errors.New("Failed to save article state: " + err.Error())
+	}
+	return state, nil
+}
+
+func setFlag(flag *bool, at *time.Time, value *bool, now time.Time) {
+	if value == nil || *value == *flag {
+		return
+	}
+	*flag = *value
+	if *value {
+		*at = now
+	} else {
+		*at = time.Time{}
+	}
+}
+
+// MarkRead marks as read the articles of the user's sources, all of them or
+// those of one source, published before a date when one is given.
+func (as *ArticleService) MarkRead(userID int, markDto dto.MarkArticlesReadInput) (dto.MarkArticlesReadOutput, error) {
+	filter := models.ArticleFilter{UserID: userID, SourceID: markDto.SourceID, To: markDto.Before}
+
+	marked, err := as.stateRepository.MarkRead(userID, filter, time.Now().UTC().Truncate(time.Second))
+	if err != nil {
+		return dto.MarkArticlesReadOutput{}, errors.New("Failed to mark articles as read: " + err.Error())
+	}
+	return dto.MarkArticlesReadOutput{Marked: marked}, nil
+}
+
+// FindAll returns the articles of the user's sources matching the filter.
+func (as *ArticleService) FindAll(userID int, filter models.ArticleFilter, page, limit int, sort string) ([]models.Article, error) {
+	filter.UserID = userID
+	articles, err := as.articleRepository.FindAll(filter, page, limit, sort)
+	if err != nil {
+		return nil, errors.New("Articles not found: " + err.Error())
+	}
+	return articles, nil
+}
+
+func (as *ArticleService) FindOne(userID int, id string) (dto.FindOneArticleOutput, error) {
+	article, err := as.ownedArticle(userID, id)
+	if err != nil {
+		return dto.FindOneArticleOutput{}, err
 	}
 
 	return dto.FindOneArticleOutput{
-		ID:          article.ID,
-		Title:       article.Title,
-		Description: article.Description,
-		Content:     article.Content,
-		Link:        article.Link,
-		PubDate:     article.PubDate,
-		Author:      article.Author,
-		SourceID:    article.SourceID,
+		ID:               article.ID,
+		Title:            article.Title,
+		Description:      article.Description,
+		Content:          article.Content,
+		Link:             article.Link,
+		PubDate:          article.PubDate,
+		Author:           article.Author,
+		SourceID:         article.SourceID,
+		Guid:             article.Guid,
+		PubDateSource:    article.PubDateSource,
+		ExtractedContent: article.ExtractedContent,
+		ExtractedAt:      article.ExtractedAt,
 	}, nil
 }
 
-func (as *ArticleService) Create(articleDto dto.CreateArticleInput) (dto.CreateArticleOutput, error) {
-	id := as.GenerateArticleID(articleDto.Title, articleDto.Link)
+func (as *ArticleService) Create(userID int, articleDto dto.CreateArticleInput) (dto.CreateArticleOutput, error) {
+	if err := as.ownsSource(userID, articleDto.SourceID); err != nil {
+		return dto.CreateArticleOutput{}, err
+	}
+
+	id := as.GenerateArticleID(articleDto.SourceID, articleDto.Guid, articleDto.Link, articleDto.Title)
 	article := models.Article{
-		ID:          id,
-		Title:       articleDto.Title,
-		Description: articleDto.Description,
-		Content:     articleDto.Content,
-		Link:        articleDto.Link,
-		PubDate:     articleDto.PubDate,
-		Author:      articleDto.Author,
-		SourceID:    articleDto.SourceID,
+		ID:            id,
+		Title:         articleDto.Title,
+		Description:   articleDto.Description,
+		Content:       articleDto.Content,
+		Link:          articleDto.Link,
+		PubDate:       articleDto.PubDate,
+		Author:        articleDto.Author,
+		SourceID:      articleDto.SourceID,
+		Guid:          articleDto.Guid,
+		PubDateSource: articleDto.PubDateSource,
 	}
+	sanitizeArticle(&article)
+	article.ContentHash = contentHash(article)
+	setPubDate(&article)
 
 	articleSaved, err := as.articleRepository.Create(article)
 
@@ -78,23 +597,46 @@ func (as *ArticleService) Create(articleDto dto.CreateArticleInput) (dto.CreateA
 		PubDate:     articleSaved.PubDate,
 		Author:      articleSaved.Author,
 		SourceID:    articleSaved.SourceID,
+		Guid:        articleSaved.Guid,
 	}, nil
 }
 
-func (as *ArticleService) Update(id string, articleDto dto.UpdateArticleInput) (dto.UpdateArticleOutput, error) {
+func (as *ArticleService) Update(userID int, id string, articleDto dto.UpdateArticleInput) (dto.UpdateArticleOutput, error) {
+	existing, err := as.ownedArticle(userID, id)
+	if err != nil {
+		return dto.UpdateArticleOutput{}, err
+	}
+	if err := as.ownsSource(userID, articleDto.SourceID); err != nil {
+		return dto.UpdateArticleOutput{}, err
+	}
+
 	article := models.Article{
-		Title:       articleDto.Title,
-		Description: articleDto.Description,
-		Content:     articleDto.Content,
-		Link:        articleDto.Link,
-		PubDate:     articleDto.PubDate,
-		SourceID:    articleDto.SourceID,
+		Title:         articleDto.Title,
+		Description:   articleDto.Description,
+		Content:       articleDto.Content,
+		Link:          articleDto.Link,
+		PubDate:       articleDto.PubDate,
+		Author:        articleDto.Author,
+		SourceID:      articleDto.SourceID,
+		Guid:          existing.Guid,
+		PubDateSource: models.PubDatePublished,
+	}
+	if article.PubDate.IsZero() {
+		article.PubDate = existing.PubDate
+		article.PubDateSource = existing.PubDateSource
 	}
+	article.ExtractedContent = existing.ExtractedContent
+	sanitizeArticle(&article)
+	article.ContentHash = contentHash(article)
 
-	articleSaved, err := as.articleRepository.Update(id, article)
+	err = as.revise(existing, article)
+	if err != nil {
+		return dto.UpdateArticleOutput{}, err
+	}
 
+	articleSaved, err := as.articleRepository.FindOne(id)
 	if err != nil {
-		return dto.UpdateArticleOutput{}, errors.New("Article not updated: " + err.Error())
+		return dto.UpdateArticleOutput{}, errors.New("Article not found: " + err.Error())
 	}
 
 	return dto.UpdateArticleOutput{
@@ -106,10 +648,15 @@ func (as *ArticleService) Update(id string, articleDto dto.UpdateArticleInput) (
 		PubDate:     articleSaved.PubDate,
 		Author:      articleSaved.Author,
 		SourceID:    articleSaved.SourceID,
+		Guid:        articleSaved.Guid,
 	}, nil
 }
 
-func (as *ArticleService) Delete(id string) error {
+func (as *ArticleService) Delete(userID int, id string) error {
+	if _, err := as.ownedArticle(userID, id); err != nil {
+		return err
+	}
+
 	err := as.articleRepository.Delete(id)
 	if err != nil {
 		return errors.New("Article not deleted: " + err.Error())
@@ -117,9 +664,15 @@ func (as *ArticleService) Delete(id string) error {
 	return nil
 }
 
-func (as *ArticleService) FindAllBySourceId(sourceID int) ([]models.Article, error) {
-	articles, err := as.articleRepository.FindAllBySourceId(sourceID)
+// FindAllBySourceId returns the articles of one of the user's sources matching
+// the filter, newest first.
+func (as *ArticleService) FindAllBySourceId(userID, sourceID int, filter models.ArticleFilter) ([]models.Article, error) {
+	if err := as.ownsSource(userID, sourceID); err != nil {
+		return nil, err
+	}
 
+	filter.SourceID = sourceID
+	articles, err := as.articleRepository.FindFiltered(filter, 0, 0)
 	if err != nil {
 		return nil, errors.New("Articles not found: " + err.Error())
 	}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"fmt"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"strings"
+	"testing"
+)
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		name    string
+		article models.Article
+		want    string
+	}{
+		{
+			name:    "description only",
+			article: models.Article{Description: "<p>Resumo da matéria</p>"},
+			want:    "Resumo da matéria",
+		},
+		{
+			name:    "description and content",
+			article: models.Article{Description: "<p>Resumo</p>", Content: "<p>Texto completo</p>"},
+			want:    "Resumo\nTexto completo",
+		},
+		{
+			name:    "description repeated by the content",
+			article: models.Article{Description: "O início", Content: "<p>O início da matéria completa</p>"},
+			want:    "O início da matéria completa",
+		},
+		{
+			name:    "extracted content covering the feed content",
+			article: models.Article{Description: "Resumo", Content: "Parte", ExtractedContent: "<p>Uma Parte da página</p>"},
+			want:    "Resumo\nUma Parte da página",
+		},
+		{
+			name:    "extracted content repeating everything",
+			article: models.Article{Description: "Resumo", Content: "Parte", ExtractedContent: "<p>Resumo</p><p>Uma Parte</p>"},
+			want:    "Resumo\nUma Parte",
+		},
+		{
+			name:    "no text",
+			article: models.Article{Description: "<img src=\"https://example.com/a.png\">"},
+			want:    "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := plainText(test.article); got != test.want {
+				t.Errorf("plainText() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	long := strings.Repeat("palavra ", 40)
+	tests := []struct {
+		name  string
+		text  string
+		query string
+		want  string
+	}{
+		{
+			name:  "marks every term ignoring case",
+			text:  "Eleições municipais: o calendário das eleições",
+			query: "eleições",
+			want:  "<mark>Eleições</mark> municipais: o calendário das <mark>eleições</mark>",
+		},
+		{
+			name:  "ignores boolean operators",
+			text:  "Chuva forte em São Paulo",
+			query: `+chuva -"Rio"`,
+			want:  "<mark>Chuva</mark> forte em São Paulo",
+		},
+		{
+			name:  "cuts around the first term",
+			text:  long + "alvo " + long,
+			query: "alvo",
+			want:  "…" + long[len(long)-snippetContext:] + "<mark>alvo</mark> " + long[:snippetContext-len("alvo ")] + "…",
+		},
+		{
+			name:  "escapes html",
+			text:  "a <b>tag</b> & texto",
+			query: "tag",
+			want:  "a &lt;b&gt;<mark>tag</mark>&lt;/b&gt; &amp; texto",
+		},
+		{
+			name:  "without terms",
+			text:  "Texto curto",
+			query: "",
+			want:  "Texto curto",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := snippet(test.text, searchTerms(test.query)); got != test.want {
+				t.Errorf("snippet() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSnippetKeepsRunesWhole(t *testing.T) {
+	text := strings.Repeat("ç", 200) + " alvo " + strings.Repeat("ã", 200)
+	got := snippet(text, searchTerms("alvo"))
+	if !strings.Contains(got, "<mark>alvo</mark>") || strings.ContainsRune(got, '�') {
+		t.Errorf("snippet() = %q", got)
+	}
+}
+
+type searchArticles struct {
+	interfaces.ArticleRepositoryInterface
+	matches []models.ArticleMatch
+}
+
+func (r searchArticles) Search(filter models.ArticleFilter, sort string, page, limit int) ([]models.ArticleMatch, int, error) {
+	return r.matches, len(r.matches), nil
+}
+
+func TestSearchFindsTermsOfTheDescription(t *testing.T) {
+	article := models.Article{
+		Description: "<p>Entrevista com a prefeita</p>",
+		Content:     "<p>A conversa tratou do orçamento da cidade.</p>",
+	}
+	article.PlainText = plainText(article)
+	articles := NewArticleService(searchArticles{matches: []models.ArticleMatch{{Article: article}}}, nil, nil, nil)
+
+	output, err := articles.Search(models.ArticleFilter{Query: "prefeita"}, "", 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Sort != models.ArticleSortRelevance {
+		t.Errorf("sort = %q, want %q", output.Sort, models.ArticleSortRelevance)
+	}
+	if len(output.Results) != 1 || !strings.Contains(output.Results[0].Snippet, "<mark>prefeita</mark>") {
+		t.Errorf("results = %+v, want a snippet marking the term of the description", output.Results)
+	}
+}
+
+type backfillArticles struct {
+	interfaces.ArticleRepositoryInterface
+	articles []models.Article
+	updated  map[string]string
+}
+
+func (r *backfillArticles) FindWithoutPlainText(afterID string, limit int) ([]models.Article, error) {
+	var articles []models.Article
+	for _, article := range r.articles {
+		if article.PlainText == "" && article.ID > afterID && len(articles) < limit {
+			articles = append(articles, article)
+		}
+	}
+	return articles, nil
+}
+
+func (r *backfillArticles) UpdatePlainText(id, plainText string) error {
+	r.updated[id] = plainText
+	return nil
+}
+
+func TestBackfillPlainText(t *testing.T) {
+	articles := &backfillArticles{updated: map[string]string{}}
+	for i := 0; i < plainTextBatchSize+2; i++ {
+		articles.articles = append(articles.articles, models.Article{ID: fmt.Sprintf("a%04d", i), Description: fmt.Sprintf("<p>Texto %d</p>", i)})
+	}
+	articles.articles = append(articles.articles,
+		models.Article{ID: "b1", Content: "<p>Conteúdo</p>", ExtractedContent: "<p>Página completa</p>"},
+		models.Article{ID: "b2", Title: "Sem texto"},
+		models.Article{ID: "b3", Description: "Já indexado", PlainText: "Já indexado"},
+	)
+	service := NewArticleService(articles, nil, nil, nil)
+
+	service.BackfillPlainText()
+	if len(articles.updated) != plainTextBatchSize+3 {
+		t.Fatalf("updated %d articles, want %d", len(articles.updated), plainTextBatchSize+3)
+	}
+	if got := articles.updated["a0501"]; got != "Texto 501" {
+		t.Errorf("plain text of the last batch = %q, want %q", got, "Texto 501")
+	}
+	if got := articles.updated["b1"]; got != "Conteúdo\nPágina completa" {
+		t.Errorf("plain text = %q, want the content and the extracted content", got)
+	}
+	if _, found := articles.updated["b2"]; found {
+		t.Errorf("article without text was updated")
+	}
+	if _, found := articles.updated["b3"]; found {
+		t.Errorf("article with plain text was updated")
+	}
+}
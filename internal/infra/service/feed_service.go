@@ -0,0 +1,121 @@
+package service
+
+import (
+	"crypto/subtle"
+	"errors"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/syndication"
+	"time"
+)
+
+type FeedService struct {
+	articleRepository interfaces.ArticleRepositoryInterface
+	sourceRepository  interfaces.SourceRepositoryInterface
+	userRepository    interfaces.UserRepositoryInterface
+	searchRepository  interfaces.SavedSearchRepositoryInterface
+}
+
+func NewFeedService(articleRepository interfaces.ArticleRepositoryInterface, sourceRepository interfaces.SourceRepositoryInterface, userRepository interfaces.UserRepositoryInterface, searchRepository interfaces.SavedSearchRepositoryInterface) *FeedService {
+	return &FeedService{
+		articleRepository: articleRepository,
+		sourceRepository:  sourceRepository,
+		userRepository:    userRepository,
+		searchRepository:  searchRepository,
+	}
+}
+
+func (fs *FeedService) UserFeed(userID int, token string, page, limit int) (syndication.Feed, error) {
+	user, err := fs.authorize(userID, token)
+	if err != nil {
+		return syndication.Feed{}, err
+	}
+
+	articles, err := fs.articleRepository.FindFiltered(models.ArticleFilter{UserID: user.ID}, page, limit)
+	if err != nil {
+		return syndication.Feed{}, errors.New("Failed to find articles: " + err.Error())
+	}
+
+	return syndication.Feed{
+		Title:       "Smart News Fetcher - " + user.Username,
+		Description: "Articles from all sources of " + user.Username,
+		Items:       feedItems(articles),
+	}, nil
+}
+
+func (fs *FeedService) SourceFeed(sourceID int, token string, page, limit int) (syndication.Feed, error) {
+	source, err := fs.sourceRepository.FindOne(sourceID)
+	if err != nil {
+		return syndication.Feed{}, models.ErrInvalidFeedToken
+	}
+
+	_, err = fs.authorize(source.UserID, token)
+	if err != nil {
+		return syndication.Feed{}, err
+	}
+
+	articles, err := fs.articleRepository.FindFiltered(models.ArticleFilter{SourceID: source.ID}, page, limit)
+	if err != nil {
+		return syndication.Feed{}, errors.New("Failed to find articles: " + err.Error())
+	}
+
+	return syndication.Feed{
+		Title:       source.Name,
+		Description: "Articles from " + source.Name,
+		HomeURL:     source.Url,
+		Items:       feedItems(articles),
+	}, nil
+}
+
+func (fs *FeedService) SavedSearchFeed(searchID int, token string, page, limit int) (syndication.Feed, error) {
+	search, err := fs.searchRepository.FindOne(searchID)
+	if err != nil {
+		return syndication.Feed{}, models.ErrInvalidFeedToken
+	}
+
+	_, err = fs.authorize(search.UserID, token)
+	if err != nil {
+		return syndication.Feed{}, err
+	}
+
+	articles, err := fs.articleRepository.FindFiltered(search.Filter(time.Now()), page, limit)
+	if err != nil {
+		return syndication.Feed{}, errors.New("Failed to find articles: " + err.Error())
+	}
+
+	return syndication.Feed{
+		Title:       search.Name,
+		Description: "Articles matching the saved search " + search.Name,
+		Items:       feedItems(articles),
+	}, nil
+}
+
+// authorize checks the token against the user's feed token. Unknown and
+// disabled users get the same error as a wrong token so feed URLs cannot be
+// used to probe ids.
+func (fs *FeedService) authorize(userID int, token string) (*models.User, error) {
+	user, err := fs.userRepository.FindById(userID)
+	if err != nil || user.Disabled || user.FeedToken == "" || token == "" {
+		return nil, models.ErrInvalidFeedToken
+	}
+	if subtle.ConstantTimeCompare([]byte(user.FeedToken), []byte(token)) != 1 {
+		return nil, models.ErrInvalidFeedToken
+	}
+	return user, nil
+}
+
+func feedItems(articles []models.Article) []syndication.Item {
+	items := make([]syndication.Item, 0, len(articles))
+	for _, article := range articles {
+		items = append(items, syndication.Item{
+			ID:          article.ID,
+			Title:       article.Title,
+			Link:        article.Link,
+			Description: article.Description,
+			Content:     article.Content,
+			Author:      article.Author,
+			Published:   article.PubDate,
+		})
+	}
+	return items
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"testing"
+)
+
+type feedArticles struct {
+	interfaces.ArticleRepositoryInterface
+}
+
+func (feedArticles) FindFiltered(filter models.ArticleFilter, page, limit int) ([]models.Article, error) {
+	return []models.Article{{ID: "a1", Title: "First"}}, nil
+}
+
+type feedSources struct {
+	interfaces.SourceRepositoryInterface
+}
+
+func (feedSources) FindOne(id int) (models.Source, error) {
+	return models.Source{ID: id, UserID: 2, Name: "Source"}, nil
+}
+
+func TestFeedsOfDisabledUsers(t *testing.T) {
+	users := &roleUsers{users: map[int]models.User{
+		2: {ID: 2, Username: "reader", FeedToken: "token"},
+	}}
+	feeds := NewFeedService(feedArticles{}, feedSources{}, users, nil)
+
+	if _, err := feeds.UserFeed(2, "token", 1, 10); err != nil {
+		t.Fatalf("UserFeed() error = %v", err)
+	}
+	if _, err := feeds.UserFeed(2, "other", 1, 10); err != models.ErrInvalidFeedToken {
+		t.Errorf("UserFeed() error = %v with a wrong token, want %v", err, models.ErrInvalidFeedToken)
+	}
+
+	user := users.users[2]
+	user.Disabled = true
+	users.users[2] = user
+	if _, err := feeds.UserFeed(2, "token", 1, 10); err != models.ErrInvalidFeedToken {
+		t.Errorf("UserFeed() error = %v for a disabled user, want %v", err, models.ErrInvalidFeedToken)
+	}
+	if _, err := feeds.SourceFeed(5, "token", 1, 10); err != models.ErrInvalidFeedToken {
+		t.Errorf("SourceFeed() error = %v for a disabled user, want %v", err, models.ErrInvalidFeedToken)
+	}
+}
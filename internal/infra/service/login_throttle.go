@@ -0,0 +1,246 @@
+package service
+
+import (
+	"fmt"
+	"github.com/google/logger"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/email"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
+	"html"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultLoginMaxAttempts   = 10
+	defaultLoginIPMaxAttempts = 50
+	defaultLoginLockout       = 15 * time.Minute
+	// Failed attempts below the free limit are not delayed, so typos do not
+	// slow down legitimate users.
+	loginFreeAttempts = 3
+	loginBaseDelay    = time.Second
+	loginMaxDelay     = time.Minute
+	loginSweepEvery   = time.Minute
+)
+
+type LoginThrottleOptions struct {
+	// MaxAttempts is how many failed attempts lock an account.
+	MaxAttempts int
+	// IPMaxAttempts is how many failed attempts, on any account, lock an IP.
+	IPMaxAttempts int
+	// Lockout is how long locks last, and how long failed attempts are
+	// remembered.
+	Lockout time.Duration
+}
+
+type attemptState struct {
+	failures    int
+	lastFailure time.Time
+	retryAt     time.Time
+	// inFlight counts the attempts allowed whose password is still being
+	// checked. They may all turn out to be failures.
+	inFlight int
+}
+
+// LoginThrottle tracks failed password checks per account and per IP. Each
+// failure past the first few delays the next attempt exponentially, and too
+// many failures lock the account or IP for a while. Attempts are refused
+// before the password is compared, so hammering the API cannot burn CPU on
+// bcrypt either. Accounts are tracked by email whether they exist or not, so
+// lockouts do not reveal which emails are registered.
+type LoginThrottle struct {
+	mu             sync.Mutex
+	attempts       map[string]*attemptState
+	lastSweep      time.Time
+	userRepository interfaces.UserRepositoryInterface
+	emailService   interfaces.EmailService
+	options        LoginThrottleOptions
+}
+
+func NewLoginThrottle(userRepository interfaces.UserRepositoryInterface, emailService interfaces.EmailService, options LoginThrottleOptions) *LoginThrottle {
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = defaultLoginMaxAttempts
+	}
+	if options.IPMaxAttempts <= 0 {
+		options.IPMaxAttempts = defaultLoginIPMaxAttempts
+	}
+	if options.Lockout <= 0 {
+		options.Lockout = defaultLoginLockout
+	}
+	return &LoginThrottle{
+		attempts:       make(map[string]*attemptState),
+		userRepository: userRepository,
+		emailService:   emailService,
+		options:        options,
+	}
+}
+
+func accountKey(emailAddress string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(emailAddress))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Allow reports whether the account and IP may try a password now, and if
+// not, how long until they may. An allowed attempt is reserved until it is
+// settled with Success, Failure or Release, so concurrent requests cannot all
+// get through before the first failure is counted: past the free attempts,
+// only one password is checked at a time.
+func (lt *LoginThrottle) Allow(emailAddress, ip string) (time.Duration, bool) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	now := time.Now()
+	lt.sweep(now)
+
+	keys := []string{accountKey(emailAddress), ipKey(ip)}
+	var wait time.Duration
+	for _, key := range keys {
+		state, exists := lt.attempts[key]
+		if !exists {
+			continue
+		}
+		remaining := state.retryAt.Sub(now)
+		if remaining <= 0 && state.inFlight > 0 && lt.failures(state, now)+state.inFlight >= loginFreeAttempts {
+			remaining = loginBaseDelay
+		}
+		if remaining > wait {
+			wait = remaining
+		}
+	}
+	if wait > 0 {
+		return wait, false
+	}
+
+	for _, key := range keys {
+		lt.state(key).inFlight++
+	}
+	return 0, true
+}
+
+// Failure records a wrong password for the account from the IP. The owner of
+// the account is emailed when it gets locked.
+func (lt *LoginThrottle) Failure(emailAddress, ip string) {
+	lt.mu.Lock()
+	now := time.Now()
+	lt.release(accountKey(emailAddress), ipKey(ip))
+	accountLocked := lt.fail(accountKey(emailAddress), lt.options.MaxAttempts, now)
+	if lt.fail(ipKey(ip), lt.options.IPMaxAttempts, now) {
+		logger.Warningf("Locked login attempts from IP %s for %s", ip, lt.options.Lockout)
+	}
+	lt.mu.Unlock()
+
+	if accountLocked {
+		logger.Warningf("Locked login attempts for account %s for %s", emailAddress, lt.options.Lockout)
+		go lt.notifyLockout(emailAddress, ip)
+	}
+}
+
+// Success forgets the failed attempts of the account. Those of the IP are kept
+// so an attacker cannot clear them by logging into an account of their own.
+func (lt *LoginThrottle) Success(emailAddress, ip string) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	lt.release(accountKey(emailAddress), ipKey(ip))
+	state := lt.attempts[accountKey(emailAddress)]
+	if state == nil {
+		return
+	}
+	if state.inFlight == 0 {
+		delete(lt.attempts, accountKey(emailAddress))
+		return
+	}
+	state.failures = 0
+	state.retryAt = time.Time{}
+}
+
+// Release gives back an attempt whose password could not be checked, without
+// counting it as a failure.
+func (lt *LoginThrottle) Release(emailAddress, ip string) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	lt.release(accountKey(emailAddress), ipKey(ip))
+}
+
+func (lt *LoginThrottle) release(keys ...string) {
+	for _, key := range keys {
+		if state, exists := lt.attempts[key]; exists && state.inFlight > 0 {
+			state.inFlight--
+		}
+	}
+}
+
+func (lt *LoginThrottle) state(key string) *attemptState {
+	state, exists := lt.attempts[key]
+	if !exists {
+		state = &attemptState{}
+		lt.attempts[key] = state
+	}
+	return state
+}
+
+// failures returns the failures of the key that are still remembered.
+func (lt *LoginThrottle) failures(state *attemptState, now time.Time) int {
+	if now.Sub(state.lastFailure) > lt.options.Lockout {
+		return 0
+	}
+	return state.failures
+}
+
+// fail counts a failure under the key and reports whether it locked the key.
+func (lt *LoginThrottle) fail(key string, maxAttempts int, now time.Time) bool {
+	state := lt.state(key)
+	state.failures = lt.failures(state, now) + 1
+	state.lastFailure = now
+
+	if state.failures >= maxAttempts {
+		state.failures = 0
+		state.retryAt = now.Add(lt.options.Lockout)
+		return true
+	}
+	if state.failures > loginFreeAttempts {
+		delay := loginBaseDelay << uint(state.failures-loginFreeAttempts-1)
+		if delay > loginMaxDelay || delay <= 0 {
+			delay = loginMaxDelay
+		}
+		state.retryAt = now.Add(delay)
+	}
+	return false
+}
+
+// sweep drops the keys whose failures and locks are over, so the map does not
+// grow with every email and IP ever tried.
+func (lt *LoginThrottle) sweep(now time.Time) {
+	if now.Sub(lt.lastSweep) < loginSweepEvery {
+		return
+	}
+	lt.lastSweep = now
+
+	for key, state := range lt.attempts {
+		if state.inFlight == 0 && now.After(state.retryAt) && now.Sub(state.lastFailure) > lt.options.Lockout {
+			delete(lt.attempts, key)
+		}
+	}
+}
+
+func (lt *LoginThrottle) notifyLockout(emailAddress, ip string) {
+	user, err := lt.userRepository.FindByEmail(emailAddress)
+	if err != nil {
+		return
+	}
+
+	minutes := fmt.Sprintf("%d minutes", int(lt.options.Lockout.Minutes()))
+	message := email.Message{
+		ToEmail:          user.Email,
+		Subject:          "Too many failed logins on your Smart News Fetcher account",
+		PlainTextContent: "Hi " + user.Username + ", your account was locked for " + minutes + " after too many failed login attempts, the last one from " + ip + ". If it was not you, consider changing your password.",
+		HtmlContent:      "<p>Hi " + html.EscapeString(user.Username) + ", your account was locked for " + minutes + " after too many failed login attempts, the last one from " + html.EscapeString(ip) + ".</p><p>If it was not you, consider changing your password.</p>",
+	}
+	if err := lt.emailService.Send(message); err != nil {
+		logger.Errorf("Failed to send lockout email to user %d: %v", user.ID, err)
+	}
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"errors"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/email"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	throttledEmail = "reader@example.com"
+	throttledIP    = "203.0.113.7"
+)
+
+type throttleUsers struct {
+	interfaces.UserRepositoryInterface
+}
+
+func (throttleUsers) FindByEmail(emailAddress string) (*models.User, error) {
+	if emailAddress != throttledEmail {
+		return nil, errors.New("user not found")
+	}
+	return &models.User{ID: 1, Username: "reader", Email: throttledEmail}, nil
+}
+
+type sentMessages chan email.Message
+
+func (s sentMessages) Send(message email.Message) error {
+	s <- message
+	return nil
+}
+
+func newTestThrottle(options LoginThrottleOptions) (*LoginThrottle, sentMessages) {
+	sent := make(sentMessages, 10)
+	return NewLoginThrottle(throttleUsers{}, sent, options), sent
+}
+
+func TestLoginThrottleDelaysProgressively(t *testing.T) {
+	throttle, _ := newTestThrottle(LoginThrottleOptions{MaxAttempts: 100, IPMaxAttempts: 100, Lockout: time.Hour})
+
+	for i := 0; i < loginFreeAttempts; i++ {
+		throttle.Failure(throttledEmail, throttledIP)
+		if wait, ok := throttle.Allow(throttledEmail, throttledIP); !ok {
+			t.Fatalf("attempt %d delayed by %s within the free attempts", i+1, wait)
+		}
+	}
+
+	previous := time.Duration(0)
+	for _, want := range []time.Duration{loginBaseDelay, 2 * loginBaseDelay, 4 * loginBaseDelay, 8 * loginBaseDelay} {
+		throttle.Failure(throttledEmail, throttledIP)
+		wait, ok := throttle.Allow(throttledEmail, throttledIP)
+		if ok {
+			t.Fatalf("attempt allowed right after a delayed failure")
+		}
+		if wait <= previous || wait > want || wait < want-time.Second/2 {
+			t.Fatalf("wait = %s, want about %s", wait, want)
+		}
+		previous = wait
+	}
+
+	if _, ok := throttle.Allow("other@example.com", "198.51.100.1"); !ok {
+		t.Errorf("other accounts and IPs were delayed")
+	}
+	if _, ok := throttle.Allow("other@example.com", throttledIP); ok {
+		t.Errorf("other accounts from the same IP were not delayed")
+	}
+
+	throttle.Success(throttledEmail, throttledIP)
+	if _, ok := throttle.Allow(throttledEmail, "198.51.100.1"); !ok {
+		t.Errorf("account still delayed after a successful login")
+	}
+}
+
+func TestLoginThrottleDelayIsCapped(t *testing.T) {
+	throttle, _ := newTestThrottle(LoginThrottleOptions{MaxAttempts: 100, IPMaxAttempts: 100, Lockout: time.Hour})
+
+	for i := 0; i < 40; i++ {
+		throttle.Failure(throttledEmail, throttledIP)
+	}
+	wait, ok := throttle.Allow(throttledEmail, throttledIP)
+	if ok || wait > loginMaxDelay || wait < loginMaxDelay-time.Second {
+		t.Errorf("wait = %s, %t, want about %s", wait, ok, loginMaxDelay)
+	}
+}
+
+func TestLoginThrottleLockoutExpires(t *testing.T) {
+	lockout := 100 * time.Millisecond
+	throttle, _ := newTestThrottle(LoginThrottleOptions{MaxAttempts: 2, IPMaxAttempts: 100, Lockout: lockout})
+
+	throttle.Failure(throttledEmail, throttledIP)
+	if _, ok := throttle.Allow(throttledEmail, throttledIP); !ok {
+		t.Fatalf("account locked before reaching the maximum attempts")
+	}
+	throttle.Failure(throttledEmail, throttledIP)
+	wait, ok := throttle.Allow(throttledEmail, throttledIP)
+	if ok || wait > lockout {
+		t.Fatalf("Allow = %s, %t, want locked for up to %s", wait, ok, lockout)
+	}
+
+	time.Sleep(lockout + 20*time.Millisecond)
+	if wait, ok := throttle.Allow(throttledEmail, throttledIP); !ok {
+		t.Errorf("account still locked for %s after the lockout", wait)
+	}
+}
+
+func TestLoginThrottleLocksIP(t *testing.T) {
+	throttle, sent := newTestThrottle(LoginThrottleOptions{MaxAttempts: 100, IPMaxAttempts: 3, Lockout: time.Hour})
+
+	for _, account := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		throttle.Failure(account, throttledIP)
+	}
+	if _, ok := throttle.Allow("d@example.com", throttledIP); ok {
+		t.Errorf("IP not locked after failing on many accounts")
+	}
+	if _, ok := throttle.Allow("d@example.com", "198.51.100.1"); !ok {
+		t.Errorf("account locked by the failures of an IP")
+	}
+
+	select {
+	case message := <-sent:
+		t.Errorf("email sent to %s for an IP lockout", message.ToEmail)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestLoginThrottleNotifiesOncePerLockout(t *testing.T) {
+	throttle, sent := newTestThrottle(LoginThrottleOptions{MaxAttempts: 5, IPMaxAttempts: 100, Lockout: time.Hour})
+
+	for i := 0; i < 5; i++ {
+		throttle.Failure(throttledEmail, throttledIP)
+	}
+
+	select {
+	case message := <-sent:
+		if message.ToEmail != throttledEmail {
+			t.Errorf("email sent to %s, want %s", message.ToEmail, throttledEmail)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no email sent when the account was locked")
+	}
+
+	// Attempts are refused before reaching the throttle while locked, but
+	// failures already under way must not send the email again.
+	throttle.Failure(throttledEmail, throttledIP)
+	select {
+	case <-sent:
+		t.Errorf("email sent again for the same lockout")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestLoginThrottleReservesConcurrentAttempts(t *testing.T) {
+	throttle, _ := newTestThrottle(LoginThrottleOptions{MaxAttempts: 100, IPMaxAttempts: 100, Lockout: time.Hour})
+
+	const attempts = 50
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	wg.Add(attempts)
+	for i := 0; i < attempts; i++ {
+		go func() {
+			defer wg.Done()
+			if _, ok := throttle.Allow(throttledEmail, throttledIP); ok {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != loginFreeAttempts {
+		t.Fatalf("%d concurrent attempts allowed, want %d", allowed, loginFreeAttempts)
+	}
+
+	// Once the free attempts failed, the next one waits for the delay.
+	for i := 0; i < allowed; i++ {
+		throttle.Failure(throttledEmail, throttledIP)
+	}
+	if _, ok := throttle.Allow(throttledEmail, throttledIP); !ok {
+		t.Fatalf("attempt refused after the free attempts were settled")
+	}
+	throttle.Failure(throttledEmail, throttledIP)
+	if wait, ok := throttle.Allow(throttledEmail, throttledIP); ok || wait <= 0 {
+		t.Errorf("Allow = %s, %t after a delayed failure, want a delay", wait, ok)
+	}
+}
+
+func TestLoginThrottleAllowsOneAttemptAtATimePastTheFreeAttempts(t *testing.T) {
+	throttle, _ := newTestThrottle(LoginThrottleOptions{MaxAttempts: 100, IPMaxAttempts: 100, Lockout: time.Hour})
+	for i := 0; i < loginFreeAttempts-1; i++ {
+		throttle.Failure(throttledEmail, throttledIP)
+	}
+
+	if _, ok := throttle.Allow(throttledEmail, throttledIP); !ok {
+		t.Fatalf("last free attempt refused")
+	}
+	if _, ok := throttle.Allow(throttledEmail, throttledIP); ok {
+		t.Fatalf("attempt allowed while the last free attempt is being checked")
+	}
+
+	// An attempt that could not be checked gives its place back.
+	throttle.Release(throttledEmail, throttledIP)
+	if _, ok := throttle.Allow(throttledEmail, throttledIP); !ok {
+		t.Fatalf("attempt refused after the previous one was released")
+	}
+	throttle.Success(throttledEmail, throttledIP)
+	if _, ok := throttle.Allow(throttledEmail, "198.51.100.1"); !ok {
+		t.Errorf("account still throttled after a successful login")
+	}
+}
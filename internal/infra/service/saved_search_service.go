@@ -0,0 +1,277 @@
+package service
+
+import (
+	"errors"
+	"github.com/google/logger"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/dto"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/email"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	digestInterval = 24 * time.Hour
+	digestLimit    = 10
+)
+
+type SavedSearchService struct {
+	savedSearchRepository interfaces.SavedSearchRepositoryInterface
+	sourceRepository      interfaces.SourceRepositoryInterface
+	userRepository        interfaces.UserRepositoryInterface
+	articleService        interfaces.ArticleServiceInterface
+	emailService          interfaces.EmailService
+}
+
+func NewSavedSearchService(savedSearchRepository interfaces.SavedSearchRepositoryInterface, sourceRepository interfaces.SourceRepositoryInterface, userRepository interfaces.UserRepositoryInterface, articleService interfaces.ArticleServiceInterface, emailService interfaces.EmailService) *SavedSearchService {
+	return &SavedSearchService{
+		savedSearchRepository: savedSearchRepository,
+		sourceRepository:      sourceRepository,
+		userRepository:        userRepository,
+		articleService:        articleService,
+		emailService:          emailService,
+	}
+}
+
+func (ss *SavedSearchService) FindByUserId(userID int) ([]models.SavedSearch, error) {
+	searches, err := ss.savedSearchRepository.FindByUserId(userID)
+	if err != nil {
+		return []models.SavedSearch{}, errors.New("Failed to find saved searches: " + err.Error())
+	}
+	return searches, nil
+}
+
+// FindOne returns the saved search when it belongs to the user. Searches of
+// other users are reported as not found.
+func (ss *SavedSearchService) FindOne(userID, id int) (models.SavedSearch, error) {
+	search, err := ss.savedSearchRepository.FindOne(id)
+	if err != nil || search.UserID != userID {
+		return models.SavedSearch{}, models.ErrSavedSearchNotFound
+	}
+	return search, nil
+}
+
+func (ss *SavedSearchService) Create(userID int, searchDto dto.CreateSavedSearchInput) (models.SavedSearch, error) {
+	search := models.SavedSearch{
+		UserID:       userID,
+		Name:         strings.TrimSpace(searchDto.Name),
+		Query:        strings.TrimSpace(searchDto.Query),
+		SourceIDs:    uniqueIDs(searchDto.SourceIDs),
+		Author:       strings.TrimSpace(searchDto.Author),
+		Days:         searchDto.Days,
+		InNewsletter: searchDto.InNewsletter,
+	}
+	if err := ss.validate(search); err != nil {
+		return models.SavedSearch{}, err
+	}
+
+	created, err := ss.savedSearchRepository.Create(search)
+	if err != nil {
+		return models.SavedSearch{}, errors.New("Failed to save search: " + err.Error())
+	}
+	return created, nil
+}
+
+func (ss *SavedSearchService) Update(userID, id int, searchDto dto.UpdateSavedSearchInput) (models.SavedSearch, error) {
+	search, err := ss.FindOne(userID, id)
+	if err != nil {
+		return models.SavedSearch{}, err
+	}
+
+	search.Name = strings.TrimSpace(searchDto.Name)
+	search.Query = strings.TrimSpace(searchDto.Query)
+	search.SourceIDs = uniqueIDs(searchDto.SourceIDs)
+	search.Author = strings.TrimSpace(searchDto.Author)
+	search.Days = searchDto.Days
+	search.InNewsletter = searchDto.InNewsletter
+	if err := ss.validate(search); err != nil {
+		return models.SavedSearch{}, err
+	}
+
+	updated, err := ss.savedSearchRepository.Update(id, search)
+	if err != nil {
+		return models.SavedSearch{}, errors.New("Failed to update saved search: " + err.Error())
+	}
+	return updated, nil
+}
+
+func (ss *SavedSearchService) Delete(userID, id int) error {
+	if _, err := ss.FindOne(userID, id); err != nil {
+		return err
+	}
+
+	err := ss.savedSearchRepository.Delete(id)
+	if err != nil {
+		return errors.New("Failed to delete saved search: " + err.Error())
+	}
+	return nil
+}
+
+// validate checks that the search narrows the articles somehow, only refers
+// to sources of its owner, and is only mailed to verified addresses.
+func (ss *SavedSearchService) validate(search models.SavedSearch) error {
+	if search.Name == "" {
+		return errors.New("Saved search name is required")
+	}
+	if search.Query == "" && len(search.SourceIDs) == 0 && search.Author == "" {
+		return errors.New("Saved search needs a query, sources or an author")
+	}
+	if search.InNewsletter {
+		user, err := ss.userRepository.FindById(search.UserID)
+		if err != nil {
+			return errors.New("User not found: " + err.Error())
+		}
+		if !user.EmailVerified {
+			return models.ErrEmailNotVerified
+		}
+	}
+	if len(search.SourceIDs) == 0 {
+		return nil
+	}
+
+	sources, err := ss.sourceRepository.FindByUserId(search.UserID)
+	if err != nil {
+		return errors.New("Failed to find sources: " + err.Error())
+	}
+	owned := make(map[int]bool, len(sources))
+	for _, source := range sources {
+		owned[source.ID] = true
+	}
+	for _, sourceID := range search.SourceIDs {
+		if !owned[sourceID] {
+			return errors.New("Source not found: " + strconv.Itoa(sourceID))
+		}
+	}
+	return nil
+}
+
+func uniqueIDs(ids []int) []int {
+	unique := []int{}
+	seen := make(map[int]bool)
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// Results runs the saved search. Searches without a query can only be ordered
+// by date.
+func (ss *SavedSearchService) Results(userID, id int, sort string, page, limit int) (dto.SearchArticlesOutput, error) {
+	search, err := ss.FindOne(userID, id)
+	if err != nil {
+		return dto.SearchArticlesOutput{}, err
+	}
+
+	if search.Query == "" {
+		sort = models.ArticleSortDate
+	}
+	return ss.articleService.Search(search.Filter(time.Now()), sort, page, limit)
+}
+
+// SendDigests emails every user the articles of the last day matching their
+// saved searches included in the newsletter.
+func (ss *SavedSearchService) SendDigests() {
+	searches, err := ss.savedSearchRepository.FindInNewsletter()
+	if err != nil {
+		logger.Errorf("Failed to find saved searches: %v", err)
+		return
+	}
+
+	byUser := make(map[int][]models.SavedSearch)
+	var userIDs []int
+	for _, search := range searches {
+		if _, found := byUser[search.UserID]; !found {
+			userIDs = append(userIDs, search.UserID)
+		}
+		byUser[search.UserID] = append(byUser[search.UserID], search)
+	}
+
+	now := time.Now()
+	for _, userID := range userIDs {
+		err := ss.sendDigest(userID, byUser[userID], now)
+		if err != nil {
+			logger.Errorf("Failed to send saved search digest to user %d: %v", userID, err)
+		}
+	}
+}
+
+func (ss *SavedSearchService) sendDigest(userID int, searches []models.SavedSearch, now time.Time) error {
+	user, err := ss.userRepository.FindById(userID)
+	if err != nil {
+		return errors.New("User not found: " + err.Error())
+	}
+	if user.Disabled {
+		logger.Info("User disabled, skipping saved search digest of user: " + strconv.Itoa(userID))
+		return nil
+	}
+	if !user.EmailVerified {
+		logger.Info("Email not verified, skipping saved search digest of user: " + strconv.Itoa(userID))
+		return nil
+	}
+
+	textContent := ""
+	htmlContent := ""
+	found := 0
+	for _, search := range searches {
+		filter := search.Filter(now)
+		if since := now.Add(-digestInterval); filter.From.Before(since) {
+			filter.From = since
+		}
+		sort := models.ArticleSortRelevance
+		if search.Query == "" {
+			sort = models.ArticleSortDate
+		}
+
+		results, err := ss.articleService.Search(filter, sort, 1, digestLimit)
+		if err != nil {
+			return err
+		}
+		if len(results.Results) == 0 {
+			continue
+		}
+		found += len(results.Results)
+
+		textContent += search.Name + " (" + strconv.Itoa(results.Total) + " articles):\n\n"
+		htmlContent += "<h2>" + html.EscapeString(search.Name) + " (" + strconv.Itoa(results.Total) + " articles)</h2>"
+		for _, match := range results.Results {
+			textContent += "- " + match.Title + "\n"
+			textContent += match.Link + "\n\n"
+
+			htmlContent += "<p><b><a href=\"" + html.EscapeString(match.Link) + "\">" + html.EscapeString(match.Title) + "</a></b><br>"
+			htmlContent += match.Snippet + "</p>"
+		}
+		textContent += "------------------------------------------------------\n\n"
+		htmlContent += "<hr>"
+	}
+	if found == 0 {
+		return nil
+	}
+
+	message := email.Message{
+		ToEmail:          user.Email,
+		Subject:          "Newsletter: Saved searches - Day " + now.Format("2006-01-02") + "!",
+		PlainTextContent: textContent,
+		HtmlContent:      htmlContent,
+	}
+	err = ss.emailService.Send(message)
+	if err != nil {
+		return errors.New("Failed to send email: " + err.Error())
+	}
+	return nil
+}
+
+// StartDigest sends the saved search digest once a day.
+func (ss *SavedSearchService) StartDigest() {
+	ticker := time.NewTicker(digestInterval)
+	go func() {
+		for range ticker.C {
+			ss.SendDigests()
+		}
+	}()
+}
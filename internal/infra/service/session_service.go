@@ -0,0 +1,199 @@
+package service
+
+import (
+	"errors"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/auth"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/dto"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"github.com/matheusvidal21/smart-news-fetcher/pkg/utils"
+	"time"
+)
+
+const (
+	refreshTokenSize       = 32
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+	maxUserAgentLength     = 255
+)
+
+type SessionOptions struct {
+	RefreshTokenTTL time.Duration
+}
+
+// SessionService issues the access and refresh tokens of each login and
+// decides whether the access tokens presented to the API are still valid.
+type SessionService struct {
+	sessionRepository interfaces.SessionRepositoryInterface
+	userRepository    interfaces.UserRepositoryInterface
+	jwtService        auth.JWTServiceInterface
+	options           SessionOptions
+}
+
+func NewSessionService(sessionRepository interfaces.SessionRepositoryInterface, userRepository interfaces.UserRepositoryInterface, jwtService auth.JWTServiceInterface, options SessionOptions) *SessionService {
+	if options.RefreshTokenTTL <= 0 {
+		options.RefreshTokenTTL = defaultRefreshTokenTTL
+	}
+	return &SessionService{
+		sessionRepository: sessionRepository,
+		userRepository:    userRepository,
+		jwtService:        jwtService,
+		options:           options,
+	}
+}
+
+// Start opens a session for a user who just proved their identity.
+func (ss *SessionService) Start(userID int, client dto.SessionClient) (dto.LoginUserOutput, error) {
+	user, err := ss.userRepository.FindById(userID)
+	if err != nil {
+		return dto.LoginUserOutput{}, models.ErrUserNotFound
+	}
+	if user.Disabled {
+		return dto.LoginUserOutput{}, models.ErrUserDisabled
+	}
+
+	now := time.Now().UTC()
+	if err := ss.sessionRepository.DeleteExpired(user.ID, user.TokenVersion, now); err != nil {
+		return dto.LoginUserOutput{}, errors.New("Failed to clean up sessions: " + err.Error())
+	}
+
+	refreshToken, err := utils.RandomToken(refreshTokenSize)
+	if err != nil {
+		return dto.LoginUserOutput{}, errors.New("Failed to generate refresh token: " + err.Error())
+	}
+	session, err := ss.sessionRepository.Create(models.Session{
+		UserID:       user.ID,
+		TokenHash:    utils.HashToken(refreshToken),
+		TokenVersion: user.TokenVersion,
+		UserAgent:    truncate(client.UserAgent, maxUserAgentLength),
+		IP:           client.IP,
+		CreatedAt:    now,
+		LastUsedAt:   now,
+		ExpiresAt:    now.Add(ss.options.RefreshTokenTTL),
+	})
+	if err != nil {
+		return dto.LoginUserOutput{}, errors.New("Failed to create session: " + err.Error())
+	}
+	return ss.tokens(user, session, refreshToken)
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh
+// token. Presenting a refresh token that was already exchanged revokes the
+// session, since either the client or an attacker holds a stolen copy.
+func (ss *SessionService) Refresh(refreshToken string, client dto.SessionClient) (dto.LoginUserOutput, error) {
+	presentedHash := utils.HashToken(refreshToken)
+	session, err := ss.sessionRepository.FindByTokenHash(presentedHash)
+	if err != nil {
+		return dto.LoginUserOutput{}, models.ErrInvalidRefreshToken
+	}
+
+	now := time.Now().UTC()
+	if session.TokenHash != presentedHash {
+		if err := ss.sessionRepository.Revoke(session.ID, now); err != nil {
+			return dto.LoginUserOutput{}, errors.New("Failed to revoke session: " + err.Error())
+		}
+		return dto.LoginUserOutput{}, models.ErrInvalidRefreshToken
+	}
+
+	user, err := ss.userRepository.FindById(session.UserID)
+	if err != nil {
+		return dto.LoginUserOutput{}, models.ErrInvalidRefreshToken
+	}
+	if user.Disabled {
+		return dto.LoginUserOutput{}, models.ErrUserDisabled
+	}
+	if !session.Active(now) || session.TokenVersion != user.TokenVersion {
+		return dto.LoginUserOutput{}, models.ErrInvalidRefreshToken
+	}
+
+	newToken, err := utils.RandomToken(refreshTokenSize)
+	if err != nil {
+		return dto.LoginUserOutput{}, errors.New("Failed to generate refresh token: " + err.Error())
+	}
+	session.TokenHash = utils.HashToken(newToken)
+	session.UserAgent = truncate(client.UserAgent, maxUserAgentLength)
+	session.IP = client.IP
+	session.LastUsedAt = now
+	session.ExpiresAt = now.Add(ss.options.RefreshTokenTTL)
+
+	rotated, err := ss.sessionRepository.Rotate(session, presentedHash)
+	if err != nil {
+		return dto.LoginUserOutput{}, errors.New("Failed to rotate refresh token: " + err.Error())
+	}
+	if !rotated {
+		return dto.LoginUserOutput{}, models.ErrInvalidRefreshToken
+	}
+	return ss.tokens(user, session, newToken)
+}
+
+func (ss *SessionService) tokens(user *models.User, session models.Session, refreshToken string) (dto.LoginUserOutput, error) {
+	token, err := ss.jwtService.GenerateToken(auth.Identity{
+		UserID:       user.ID,
+		Email:        user.Email,
+		Role:         user.Role,
+		SessionID:    session.ID,
+		TokenVersion: user.TokenVersion,
+	})
+	if err != nil {
+		return dto.LoginUserOutput{}, errors.New("failed to generate token: " + err.Error())
+	}
+
+	return dto.LoginUserOutput{
+		Token:                 token,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: session.ExpiresAt,
+	}, nil
+}
+
+// Validate checks that the session of an access token was not revoked or
+// ended by a password change, and that its user can still use the API.
+func (ss *SessionService) Validate(identity auth.Identity) error {
+	session, err := ss.sessionRepository.FindOne(identity.SessionID)
+	if err != nil || session.UserID != identity.UserID || !session.Active(time.Now().UTC()) {
+		return models.ErrSessionExpired
+	}
+
+	user, err := ss.userRepository.FindById(identity.UserID)
+	if err != nil {
+		return models.ErrSessionExpired
+	}
+	if user.Disabled {
+		return models.ErrUserDisabled
+	}
+	if user.TokenVersion != identity.TokenVersion || user.TokenVersion != session.TokenVersion {
+		return models.ErrSessionExpired
+	}
+	return nil
+}
+
+// FindByUserId lists the sessions of the user that can still be used, marking
+// the one the request comes from.
+func (ss *SessionService) FindByUserId(userID, currentSessionID int) ([]models.Session, error) {
+	user, err := ss.userRepository.FindById(userID)
+	if err != nil {
+		return []models.Session{}, models.ErrUserNotFound
+	}
+
+	sessions, err := ss.sessionRepository.FindActiveByUserId(userID, user.TokenVersion, time.Now().UTC())
+	if err != nil {
+		return []models.Session{}, errors.New("Failed to find sessions: " + err.Error())
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// Revoke ends one of the user's sessions. Sessions of other users are
+// reported as not found.
+func (ss *SessionService) Revoke(userID, id int) error {
+	session, err := ss.sessionRepository.FindOne(id)
+	if err != nil || session.UserID != userID {
+		return models.ErrSessionNotFound
+	}
+
+	err = ss.sessionRepository.Revoke(id, time.Now().UTC())
+	if err != nil {
+		return errors.New("Failed to revoke session: " + err.Error())
+	}
+	return nil
+}
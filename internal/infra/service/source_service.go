@@ -1,86 +1,152 @@
 package service
 
 import (
+	"bytes"
 	"errors"
 	"github.com/google/logger"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/dto"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/email"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/fetcher"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/opml"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/sanitize"
+	"github.com/mmcdole/gofeed"
+	"html"
+	"io"
+	"net/url"
+	"strconv"
 	"time"
 )
 
+const (
+	defaultImportInterval = 1
+	maxSourceNameLength   = 100
+	maxCategoryLength     = 255
+	importStatusImported  = "imported"
+	importStatusDuplicate = "duplicate"
+	importStatusFailed    = "failed"
+)
+
+var errDuplicateSource = errors.New("source already exists")
+
 type SourceService struct {
-	sourceRepository interfaces.SourceRepositoryInterface
-	userService      interfaces.UserServiceInterface
-	emailService     interfaces.EmailService
-	fetcher          interfaces.FetcherInterface
-	quitChannels     map[int]chan struct{}
+	sourceRepository   interfaces.SourceRepositoryInterface
+	fetchRunRepository interfaces.FetchRunRepositoryInterface
+	stateRepository    interfaces.ArticleStateRepositoryInterface
+	userService        interfaces.UserServiceInterface
+	emailService       interfaces.EmailService
+	fetcher            interfaces.FetcherInterface
+	scheduler          interfaces.SchedulerInterface
+	quitChannels       map[int]chan struct{}
 }
 
-func NewSourceService(sourceRepository interfaces.SourceRepositoryInterface, userService interfaces.UserServiceInterface, emailService interfaces.EmailService, fetcher interfaces.FetcherInterface) *SourceService {
+func NewSourceService(sourceRepository interfaces.SourceRepositoryInterface, fetchRunRepository interfaces.FetchRunRepositoryInterface, stateRepository interfaces.ArticleStateRepositoryInterface, userService interfaces.UserServiceInterface, emailService interfaces.EmailService, fetcher interfaces.FetcherInterface, scheduler interfaces.SchedulerInterface) *SourceService {
 	return &SourceService{
-		sourceRepository: sourceRepository,
-		userService:      userService,
-		emailService:     emailService,
-		fetcher:          fetcher,
-		quitChannels:     make(map[int]chan struct{}),
+		sourceRepository:   sourceRepository,
+		fetchRunRepository: fetchRunRepository,
+		stateRepository:    stateRepository,
+		userService:        userService,
+		emailService:       emailService,
+		fetcher:            fetcher,
+		scheduler:          scheduler,
+		quitChannels:       make(map[int]chan struct{}),
 	}
 }
 
-func (sr *SourceService) FindAll(page, limit int, sort string) ([]models.Source, error) {
-	sources, err := sr.sourceRepository.FindAll(page, limit, sort)
+// FindAll lists the sources of the user with the number of articles not read
+// yet in each.
+func (sr *SourceService) FindAll(userID, page, limit int, sort string) ([]models.Source, error) {
+	sources, err := sr.sourceRepository.FindAll(userID, page, limit, sort)
 	if err != nil {
 		return []models.Source{}, errors.New("Failed to find sources: " + err.Error())
 	}
-	return sources, nil
+	return sr.withUnreadCounts(userID, sources)
 }
 
-func (sr *SourceService) FindOne(id int) (dto.FindOneSourceOutput, error) {
+// ownedSource returns the source when it belongs to the user.
+func (sr *SourceService) ownedSource(userID, id int) (models.Source, error) {
 	source, err := sr.sourceRepository.FindOne(id)
+	if err != nil || source.UserID != userID {
+		return models.Source{}, models.ErrSourceNotFound
+	}
+	return source, nil
+}
 
+func (sr *SourceService) withUnreadCounts(readerID int, sources []models.Source) ([]models.Source, error) {
+	ids := make([]int, 0, len(sources))
+	for _, source := range sources {
+		ids = append(ids, source.ID)
+	}
+
+	counts, err := sr.stateRepository.CountUnread(readerID, ids)
 	if err != nil {
-		return dto.FindOneSourceOutput{}, errors.New("Failed to find source: " + err.Error())
+		return []models.Source{}, errors.New("Failed to count unread articles: " + err.Error())
+	}
+	for i := range sources {
+		sources[i].UnreadCount = counts[sources[i].ID]
+	}
+	return sources, nil
+}
+
+func (sr *SourceService) FindOne(userID, id int) (dto.FindOneSourceOutput, error) {
+	source, err := sr.ownedSource(userID, id)
+	if err != nil {
+		return dto.FindOneSourceOutput{}, err
 	}
 	return dto.FindOneSourceOutput{
-		ID:             source.ID,
-		Name:           source.Name,
-		Url:            source.Url,
-		UpdateInterval: source.UpdateInterval,
-		UserID:         source.UserID,
-		SavedAt:        source.SavedAt,
+		ID:                  source.ID,
+		Name:                source.Name,
+		Url:                 source.Url,
+		UpdateInterval:      source.UpdateInterval,
+		Category:            source.Category,
+		FullContent:         source.FullContent,
+		UserID:              source.UserID,
+		SavedAt:             source.SavedAt,
+		ConsecutiveFailures: source.ConsecutiveFailures,
+		Disabled:            source.Disabled,
+		FetchStatus:         source.FetchStatus,
+		LastError:           source.LastError,
 	}, nil
 }
 
 func (sr *SourceService) Create(sourceDto dto.CreateSourceInput) (dto.CreateSourceOutput, error) {
-	user, err := sr.userService.FindById(sourceDto.UserID)
+	user, err := sr.userService.FindById(sourceDto.UserID, sourceDto.UserID)
 	if err != nil {
 		return dto.CreateSourceOutput{}, errors.New("User not found: " + err.Error())
 	}
 
 	sources, _ := sr.sourceRepository.FindByUserId(sourceDto.UserID)
-	for _, source := range sources {
-		if source.Url == sourceDto.Url {
-			return dto.CreateSourceOutput{}, errors.New("Source already exists: " + sourceDto.Url)
-		}
+	if sourceExists(sources, sourceDto.Url) {
+		return dto.CreateSourceOutput{}, errors.New("Source already exists: " + sourceDto.Url)
 	}
 
 	feed, err := sr.fetcher.ParseFeed(sourceDto.Url)
+	if err != nil && fetcher.ErrorKind(err) == fetcher.ErrorKindParse {
+		candidates, discoverErr := sr.fetcher.DiscoverFeeds(sourceDto.Url)
+		if discoverErr != nil {
+			return dto.CreateSourceOutput{}, errors.New("Failed to parse source feed: " + err.Error())
+		}
+		if len(candidates) == 0 {
+			return dto.CreateSourceOutput{}, errors.New("No feed found at: " + sourceDto.Url)
+		}
+		if len(candidates) > 1 {
+			return dto.CreateSourceOutput{DiscoveredFeeds: candidates}, nil
+		}
+
+		sourceDto.Url = candidates[0].Url
+		if sourceExists(sources, sourceDto.Url) {
+			return dto.CreateSourceOutput{}, errors.New("Source already exists: " + sourceDto.Url)
+		}
+		feed, err = sr.fetcher.ParseFeed(sourceDto.Url)
+	}
 	if err != nil {
 		return dto.CreateSourceOutput{}, errors.New("Failed to parse source feed: " + err.Error())
 	}
 
-	source := models.Source{
-		Name:           sourceDto.Name,
-		Url:            sourceDto.Url,
-		UserID:         sourceDto.UserID,
-		UpdateInterval: sourceDto.UpdateInterval,
-		SavedAt:        time.Now(),
-	}
-
-	sourceSaved, err := sr.sourceRepository.Create(source)
+	sourceSaved, err := sr.save(sourceDto, feed)
 	if err != nil {
-		return dto.CreateSourceOutput{}, errors.New("Failed to save source: " + err.Error())
+		return dto.CreateSourceOutput{}, err
 	}
 
 	message := email.Message{
@@ -95,22 +161,181 @@ func (sr *SourceService) Create(sourceDto dto.CreateSourceInput) (dto.CreateSour
 		return dto.CreateSourceOutput{}, errors.New("Failed to send email: " + err.Error())
 	}
 
-	sr.fetcher.StoreFeed(sourceSaved.ID, feed)
-
 	return dto.CreateSourceOutput{
 		ID:             sourceSaved.ID,
 		Name:           sourceSaved.Name,
 		Url:            sourceSaved.Url,
 		UpdateInterval: sourceSaved.UpdateInterval,
+		Category:       sourceSaved.Category,
+		FullContent:    sourceSaved.FullContent,
 		UserID:         sourceSaved.UserID,
 		SavedAt:        sourceSaved.SavedAt,
 	}, nil
 }
 
-func (sr *SourceService) Update(id int, sourceDto dto.UpdateSourceInput) (dto.UpdateSourceOutput, error) {
-	currentSource, err := sr.sourceRepository.FindOne(id)
+func (sr *SourceService) save(sourceDto dto.CreateSourceInput, feed *gofeed.Feed) (models.Source, error) {
+	source := models.Source{
+		Name:           sourceDto.Name,
+		Url:            sourceDto.Url,
+		UserID:         sourceDto.UserID,
+		UpdateInterval: sourceDto.UpdateInterval,
+		Category:       sourceDto.Category,
+		FullContent:    sourceDto.FullContent,
+		SavedAt:        time.Now(),
+	}
+	source.ETag, source.LastModified = sr.fetcher.FeedValidators(source.Url)
+
+	sourceSaved, err := sr.sourceRepository.Create(source)
+	if err != nil {
+		return models.Source{}, errors.New("Failed to save source: " + err.Error())
+	}
+
+	sr.fetcher.StoreFeed(sourceSaved.ID, feed)
+	sr.scheduler.Schedule(sourceSaved)
+	return sourceSaved, nil
+}
+
+// ImportOPML creates a source for every feed of the OPML document that the
+// user does not follow yet. Folders become the category of their feeds.
+func (sr *SourceService) ImportOPML(userID int, document io.Reader) (dto.ImportOPMLOutput, error) {
+	user, err := sr.userService.FindById(userID, userID)
+	if err != nil {
+		return dto.ImportOPMLOutput{}, errors.New("User not found: " + err.Error())
+	}
+
+	parsed, err := opml.Parse(document)
+	if err != nil {
+		return dto.ImportOPMLOutput{}, errors.New("Failed to parse OPML file: " + err.Error())
+	}
+
+	sources, _ := sr.sourceRepository.FindByUserId(userID)
+	output := dto.ImportOPMLOutput{Entries: []dto.ImportOPMLEntry{}}
+	for _, entry := range parsed.Entries() {
+		result := dto.ImportOPMLEntry{
+			Name:     truncate(entry.Title, maxSourceNameLength),
+			Url:      entry.XMLURL,
+			Category: truncate(entry.Category, maxCategoryLength),
+		}
+		if result.Name == "" {
+			result.Name = truncate(entry.XMLURL, maxSourceNameLength)
+		}
+
+		source, err := sr.importEntry(userID, sources, result)
+		switch {
+		case err == errDuplicateSource:
+			result.Status = importStatusDuplicate
+			result.Error = "Source already exists: " + result.Url
+			output.Duplicates++
+		case err != nil:
+			result.Status = importStatusFailed
+			result.Error = err.Error()
+			output.Failed++
+		default:
+			result.Status = importStatusImported
+			result.SourceID = source.ID
+			sources = append(sources, source)
+			output.Imported++
+		}
+		output.Entries = append(output.Entries, result)
+	}
+
+	if output.Imported > 0 {
+		message := email.Message{
+			ToEmail:          user.Email,
+			Subject:          "Smart News Fetcher - Sources imported",
+			PlainTextContent: strconv.Itoa(output.Imported) + " sources have been imported from your OPML file.",
+			HtmlContent:      "<p>" + strconv.Itoa(output.Imported) + " sources have been imported from your OPML file.</p>",
+		}
+		err = sr.emailService.Send(message)
+		if err != nil {
+			logger.Errorf("Failed to send email: %v", err)
+		}
+	}
+
+	logger.Info("OPML import for user " + strconv.Itoa(userID) + ": " + strconv.Itoa(output.Imported) + " imported, " +
+		strconv.Itoa(output.Duplicates) + " duplicates, " + strconv.Itoa(output.Failed) + " failed")
+	return output, nil
+}
+
+func (sr *SourceService) importEntry(userID int, sources []models.Source, entry dto.ImportOPMLEntry) (models.Source, error) {
+	if err := dto.Validate.Var(entry.Url, "required,url"); err != nil {
+		return models.Source{}, errors.New("Invalid feed url: " + entry.Url)
+	}
+	if sourceExists(sources, entry.Url) {
+		return models.Source{}, errDuplicateSource
+	}
+
+	feed, err := sr.fetcher.ParseFeed(entry.Url)
+	if err != nil {
+		return models.Source{}, errors.New("Failed to parse source feed: " + err.Error())
+	}
+
+	return sr.save(dto.CreateSourceInput{
+		Name:           entry.Name,
+		Url:            entry.Url,
+		UserID:         userID,
+		UpdateInterval: defaultImportInterval,
+		Category:       entry.Category,
+	}, feed)
+}
+
+func (sr *SourceService) ExportOPML(userID int) ([]byte, error) {
+	user, err := sr.userService.FindById(userID, userID)
+	if err != nil {
+		return nil, errors.New("User not found: " + err.Error())
+	}
+
+	sources, err := sr.sourceRepository.FindByUserId(userID)
+	if err != nil {
+		return nil, errors.New("Failed to find sources: " + err.Error())
+	}
+
+	feeds := make([]opml.Feed, 0, len(sources))
+	for _, source := range sources {
+		feeds = append(feeds, opml.Feed{Title: source.Name, XMLURL: source.Url, Category: source.Category})
+	}
+
+	document := opml.Export("Smart News Fetcher - "+user.Username, feeds)
+	document.Head.OwnerName = user.Username
+	document.Head.OwnerEmail = user.Email
+
+	var buffer bytes.Buffer
+	err = document.Write(&buffer)
+	if err != nil {
+		return nil, errors.New("Failed to write OPML document: " + err.Error())
+	}
+	return buffer.Bytes(), nil
+}
+
+func truncate(value string, length int) string {
+	runes := []rune(value)
+	if len(runes) <= length {
+		return value
+	}
+	return string(runes[:length])
+}
+
+func (sr *SourceService) Discover(url string) ([]models.FeedCandidate, error) {
+	candidates, err := sr.fetcher.DiscoverFeeds(url)
 	if err != nil {
-		return dto.UpdateSourceOutput{}, errors.New("Failed to find source: " + err.Error())
+		return nil, errors.New("Failed to discover feeds: " + err.Error())
+	}
+	return candidates, nil
+}
+
+func sourceExists(sources []models.Source, url string) bool {
+	for _, source := range sources {
+		if source.Url == url {
+			return true
+		}
+	}
+	return false
+}
+
+func (sr *SourceService) Update(userID, id int, sourceDto dto.UpdateSourceInput) (dto.UpdateSourceOutput, error) {
+	currentSource, err := sr.ownedSource(userID, id)
+	if err != nil {
+		return dto.UpdateSourceOutput{}, err
 	}
 
 	if !(currentSource.Url == sourceDto.Url) {
@@ -119,69 +344,132 @@ func (sr *SourceService) Update(id int, sourceDto dto.UpdateSourceInput) (dto.Up
 			return dto.UpdateSourceOutput{}, errors.New("Failed to parse source feed: " + err.Error())
 		}
 		sr.fetcher.StoreFeed(id, feed)
+
+		err = sr.sourceRepository.UpdateCacheValidators(id, "", "")
+		if err != nil {
+			return dto.UpdateSourceOutput{}, errors.New("Failed to reset source cache validators: " + err.Error())
+		}
+	}
+
+	category := sourceDto.Category
+	if category == "" {
+		category = currentSource.Category
+	}
+
+	fullContent := currentSource.FullContent
+	if sourceDto.FullContent != nil {
+		fullContent = *sourceDto.FullContent
 	}
 
 	source := models.Source{
 		Name:           sourceDto.Name,
 		UpdateInterval: sourceDto.UpdateInterval,
 		Url:            sourceDto.Url,
+		Category:       category,
+		FullContent:    fullContent,
 	}
 
 	sourceUpdated, err := sr.sourceRepository.Update(id, source)
 	if err != nil {
 		return dto.UpdateSourceOutput{}, errors.New("Failed to update source: " + err.Error())
 	}
+	sr.scheduler.Reschedule(sourceUpdated)
+
 	return dto.UpdateSourceOutput{
 		ID:             sourceUpdated.ID,
 		Name:           sourceUpdated.Name,
 		Url:            sourceUpdated.Url,
 		UserID:         sourceUpdated.UserID,
 		UpdateInterval: sourceUpdated.UpdateInterval,
+		Category:       sourceUpdated.Category,
+		FullContent:    sourceUpdated.FullContent,
 		SavedAt:        sourceUpdated.SavedAt,
 	}, err
 }
 
-func (sr *SourceService) Delete(id int) error {
+func (sr *SourceService) Delete(userID, id int) error {
+	if _, err := sr.ownedSource(userID, id); err != nil {
+		return err
+	}
+
 	err := sr.sourceRepository.Delete(id)
 	if err != nil {
 		return errors.New("Failed to delete source: " + err.Error())
 	}
+	sr.scheduler.Cancel(id)
 	return nil
 }
 
-func (sr *SourceService) LoadFeed(id int) error {
-	source, err := sr.sourceRepository.FindOne(id)
+func (sr *SourceService) LoadFeed(userID, id int) error {
+	source, err := sr.ownedSource(userID, id)
 	if err != nil {
-		return errors.New("Source not found: " + err.Error())
+		return err
 	}
 
-	feed, err := sr.fetcher.LoadFeed(source.ID)
+	sr.scheduler.Schedule(source)
+	return nil
+}
+
+func (sr *SourceService) Resume(userID, id int) error {
+	_, err := sr.ownedSource(userID, id)
 	if err != nil {
-		feed, err = sr.fetcher.ParseFeed(source.Url)
-		if err != nil {
-			return errors.New("Failed to load feed: " + err.Error())
-		}
+		return err
+	}
 
-		sr.fetcher.StoreFeed(source.ID, feed)
+	err = sr.sourceRepository.UpdateFetchState(id, 0, false, "", "")
+	if err != nil {
+		return errors.New("Failed to resume source: " + err.Error())
+	}
+
+	source, err := sr.sourceRepository.FindOne(id)
+	if err != nil {
+		return errors.New("Source not found: " + err.Error())
 	}
 
-	sr.fetcher.StoreFeed(source.ID, feed)
-	sr.fetcher.StartScheduler(source, feed)
+	sr.scheduler.Schedule(source)
 	return nil
 }
 
-func (sr *SourceService) FindByUserId(userId int) ([]models.Source, error) {
+func (sr *SourceService) FindFetchRuns(userID, id, page, limit int) ([]models.FetchRun, error) {
+	_, err := sr.ownedSource(userID, id)
+	if err != nil {
+		return []models.FetchRun{}, err
+	}
+
+	runs, err := sr.fetchRunRepository.FindBySourceId(id, page, limit)
+	if err != nil {
+		return []models.FetchRun{}, errors.New("Failed to find fetch runs: " + err.Error())
+	}
+	return runs, nil
+}
+
+// FindByUserId lists the sources of a user, which only that user may do.
+func (sr *SourceService) FindByUserId(currentUserID, userId int) ([]models.Source, error) {
+	if currentUserID != userId {
+		return []models.Source{}, models.ErrForbidden
+	}
+
 	sources, err := sr.sourceRepository.FindByUserId(userId)
 	if err != nil {
 		return []models.Source{}, errors.New("Failed to find sources: " + err.Error())
 	}
-	return sources, nil
+	return sr.withUnreadCounts(userId, sources)
 }
 
-func (sr *SourceService) SubscribeToNewsletter(id int) error {
-	source, err := sr.sourceRepository.FindOne(id)
+// SubscribeToNewsletter sends the articles of the source to the user every
+// day, once they verified their email address.
+func (sr *SourceService) SubscribeToNewsletter(userID, id int) error {
+	source, err := sr.ownedSource(userID, id)
 	if err != nil {
-		return errors.New("Source not found: " + err.Error())
+		return err
+	}
+
+	user, err := sr.userService.FindById(userID, userID)
+	if err != nil {
+		return errors.New("User not found: " + err.Error())
+	}
+	if !user.EmailVerified {
+		return models.ErrEmailNotVerified
 	}
 
 	if source.Subscriber {
@@ -202,10 +490,10 @@ func (sr *SourceService) SubscribeToNewsletter(id int) error {
 	return nil
 }
 
-func (sr *SourceService) UnsubscribeFromNewsletter(id int) error {
-	source, err := sr.sourceRepository.FindOne(id)
+func (sr *SourceService) UnsubscribeFromNewsletter(userID, id int) error {
+	source, err := sr.ownedSource(userID, id)
 	if err != nil {
-		return errors.New("Source not found: " + err.Error())
+		return err
 	}
 
 	if source.Subscriber == false {
@@ -231,10 +519,23 @@ func (sr *SourceService) StartSubscription(source models.Source) {
 	sr.quitChannels[source.ID] = make(chan struct{})
 
 	go func() {
-		user, _ := sr.userService.FindById(source.UserID)
 		for {
 			select {
 			case <-ticker.C:
+				user, err := sr.userService.FindById(source.UserID, source.UserID)
+				if err != nil {
+					logger.Errorf("Failed to find user of source %d: %v", source.ID, err)
+					continue
+				}
+				if user.Disabled {
+					logger.Info("User disabled, skipping newsletter of source: " + strconv.Itoa(source.ID))
+					continue
+				}
+				if !user.EmailVerified {
+					logger.Info("Email not verified, skipping newsletter of source: " + strconv.Itoa(source.ID))
+					continue
+				}
+
 				feed, err := sr.fetcher.ParseFeed(source.Url)
 				if err != nil {
 					logger.Errorf("Failed to parse source feed: %v", err)
@@ -246,13 +547,18 @@ func (sr *SourceService) StartSubscription(source models.Source) {
 				htmlContent := "<p>Current articles on " + source.Name + ":</p>"
 
 				for _, item := range feed.Items {
+					base, err := url.Parse(item.Link)
+					if err != nil || !base.IsAbs() {
+						base = nil
+					}
+
 					textContent += "- " + item.Title + "\n"
-					textContent += item.Description + "\n"
+					textContent += sanitize.Text(item.Description) + "\n"
 					textContent += item.Link + "\n\n"
 					textContent += "------------------------------------------------------\n\n"
 
-					htmlContent += "<p><b>" + item.Title + "</b><br>"
-					htmlContent += item.Description + "<br>"
+					htmlContent += "<p><b>" + html.EscapeString(item.Title) + "</b><br>"
+					htmlContent += sanitize.HTML(item.Description, base) + "<br>"
 					htmlContent += "<hr>"
 				}
 
@@ -287,3 +593,14 @@ func (sr *SourceService) InitializeSubscription() {
 		sr.StartSubscription(source)
 	}
 }
+
+func (sr *SourceService) InitializeScheduler() {
+	sources, err := sr.sourceRepository.FindAllOfEnabledUsers()
+	if err != nil {
+		logger.Errorf("Failed to initialize scheduler: %v", err)
+		return
+	}
+	for _, source := range sources {
+		sr.scheduler.Schedule(source)
+	}
+}
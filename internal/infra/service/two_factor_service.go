@@ -0,0 +1,268 @@
+package service
+
+import (
+	"errors"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/auth"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/dto"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"github.com/matheusvidal21/smart-news-fetcher/pkg/utils"
+	"strings"
+	"time"
+)
+
+const (
+	totpIssuer          = "Smart News Fetcher"
+	loginChallengeTTL   = 5 * time.Minute
+	recoveryCodeCount   = 10
+	recoveryCodeSize    = 5
+	recoveryCodeDivider = 5
+)
+
+// TwoFactorService manages the TOTP second factor of users: enrollment, the
+// recovery codes and the second step of the login.
+type TwoFactorService struct {
+	userRepository         interfaces.UserRepositoryInterface
+	userTokenRepository    interfaces.UserTokenRepositoryInterface
+	recoveryCodeRepository interfaces.RecoveryCodeRepositoryInterface
+	jwtService             auth.JWTServiceInterface
+}
+
+func NewTwoFactorService(userRepository interfaces.UserRepositoryInterface, userTokenRepository interfaces.UserTokenRepositoryInterface, recoveryCodeRepository interfaces.RecoveryCodeRepositoryInterface, jwtService auth.JWTServiceInterface) *TwoFactorService {
+	return &TwoFactorService{
+		userRepository:         userRepository,
+		userTokenRepository:    userTokenRepository,
+		recoveryCodeRepository: recoveryCodeRepository,
+		jwtService:             jwtService,
+	}
+}
+
+func (ts *TwoFactorService) findUser(userID int) (*models.User, error) {
+	user, err := ts.userRepository.FindById(userID)
+	if err != nil {
+		return nil, models.ErrUserNotFound
+	}
+	return user, nil
+}
+
+// Enroll generates a new secret for the user. It is only required to log in
+// once Confirm proves the user's authenticator app produces its codes.
+func (ts *TwoFactorService) Enroll(userID int) (dto.TwoFactorEnrollOutput, error) {
+	user, err := ts.findUser(userID)
+	if err != nil {
+		return dto.TwoFactorEnrollOutput{}, err
+	}
+	if user.TwoFactorEnabled {
+		return dto.TwoFactorEnrollOutput{}, models.ErrTwoFactorEnabled
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return dto.TwoFactorEnrollOutput{}, errors.New("Failed to generate secret: " + err.Error())
+	}
+	err = ts.userRepository.UpdateTwoFactor(user.ID, secret, false)
+	if err != nil {
+		return dto.TwoFactorEnrollOutput{}, errors.New("Failed to save secret: " + err.Error())
+	}
+
+	return dto.TwoFactorEnrollOutput{
+		Secret: secret,
+		URI:    auth.TOTPURI(secret, totpIssuer, user.Email),
+	}, nil
+}
+
+// Confirm enables the second factor with a code from the enrolled secret and
+// returns the first recovery codes.
+func (ts *TwoFactorService) Confirm(userID int, input dto.TwoFactorConfirmInput) (dto.RecoveryCodesOutput, error) {
+	user, err := ts.findUser(userID)
+	if err != nil {
+		return dto.RecoveryCodesOutput{}, err
+	}
+	if user.TwoFactorEnabled {
+		return dto.RecoveryCodesOutput{}, models.ErrTwoFactorEnabled
+	}
+	if user.TOTPSecret == "" {
+		return dto.RecoveryCodesOutput{}, models.ErrTwoFactorNotEnrolled
+	}
+
+	if err := ts.verifyTOTP(user, input.Code); err != nil {
+		return dto.RecoveryCodesOutput{}, err
+	}
+	codes, err := ts.replaceRecoveryCodes(user.ID)
+	if err != nil {
+		return dto.RecoveryCodesOutput{}, err
+	}
+
+	err = ts.userRepository.UpdateTwoFactor(user.ID, user.TOTPSecret, true)
+	if err != nil {
+		return dto.RecoveryCodesOutput{}, errors.New("Failed to enable two-factor authentication: " + err.Error())
+	}
+	return codes, nil
+}
+
+// Disable removes the second factor, proven by a code or a recovery code.
+func (ts *TwoFactorService) Disable(userID int, input dto.TwoFactorCodeInput) error {
+	user, err := ts.enabledUser(userID)
+	if err != nil {
+		return err
+	}
+	if err := ts.verify(user, input); err != nil {
+		return err
+	}
+
+	err = ts.userRepository.UpdateTwoFactor(user.ID, "", false)
+	if err != nil {
+		return errors.New("Failed to disable two-factor authentication: " + err.Error())
+	}
+	err = ts.recoveryCodeRepository.DeleteByUserId(user.ID)
+	if err != nil {
+		return errors.New("Failed to delete recovery codes: " + err.Error())
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces every recovery code of the user, used or
+// not.
+func (ts *TwoFactorService) RegenerateRecoveryCodes(userID int, input dto.TwoFactorCodeInput) (dto.RecoveryCodesOutput, error) {
+	user, err := ts.enabledUser(userID)
+	if err != nil {
+		return dto.RecoveryCodesOutput{}, err
+	}
+	if err := ts.verify(user, input); err != nil {
+		return dto.RecoveryCodesOutput{}, err
+	}
+	return ts.replaceRecoveryCodes(user.ID)
+}
+
+// Challenge issues the token that stands for the password during the second
+// step of the login. It can only be presented once, right or wrong, so each
+// password check buys a single guess of the code.
+func (ts *TwoFactorService) Challenge(user *models.User) (dto.TwoFactorChallengeOutput, error) {
+	id, err := utils.RandomToken(userTokenIDSize)
+	if err != nil {
+		return dto.TwoFactorChallengeOutput{}, errors.New("Failed to generate challenge: " + err.Error())
+	}
+
+	now := time.Now().UTC()
+	err = ts.userTokenRepository.Create(models.UserToken{
+		ID:        id,
+		UserID:    user.ID,
+		Purpose:   models.TokenPurposeLoginChallenge,
+		CreatedAt: now,
+		ExpiresAt: now.Add(loginChallengeTTL),
+	})
+	if err != nil {
+		return dto.TwoFactorChallengeOutput{}, errors.New("Failed to save challenge: " + err.Error())
+	}
+
+	token, err := ts.jwtService.GenerateActionToken(auth.Action{ID: id, UserID: user.ID, Purpose: models.TokenPurposeLoginChallenge}, loginChallengeTTL)
+	if err != nil {
+		return dto.TwoFactorChallengeOutput{}, errors.New("Failed to sign challenge: " + err.Error())
+	}
+	return dto.TwoFactorChallengeOutput{
+		TwoFactorRequired: true,
+		ChallengeToken:    token,
+		ExpiresAt:         now.Add(loginChallengeTTL),
+	}, nil
+}
+
+// VerifyChallenge completes the login of a challenge with a code or a recovery
+// code, returning the user to open the session for.
+func (ts *TwoFactorService) VerifyChallenge(input dto.TwoFactorLoginInput) (*models.User, error) {
+	action, err := ts.jwtService.ValidateActionToken(input.ChallengeToken, models.TokenPurposeLoginChallenge)
+	if err != nil {
+		return nil, models.ErrInvalidLoginChallenge
+	}
+
+	used, err := ts.userTokenRepository.Use(action.ID, action.UserID, models.TokenPurposeLoginChallenge, time.Now().UTC())
+	if err != nil {
+		return nil, errors.New("Failed to use challenge: " + err.Error())
+	}
+	if !used {
+		return nil, models.ErrInvalidLoginChallenge
+	}
+
+	user, err := ts.userRepository.FindById(action.UserID)
+	if err != nil || !user.TwoFactorEnabled {
+		return nil, models.ErrInvalidLoginChallenge
+	}
+	if user.Disabled {
+		return nil, models.ErrUserDisabled
+	}
+	if err := ts.verify(user, input.TwoFactorCodeInput); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (ts *TwoFactorService) enabledUser(userID int) (*models.User, error) {
+	user, err := ts.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TwoFactorEnabled {
+		return nil, models.ErrTwoFactorNotEnabled
+	}
+	return user, nil
+}
+
+func (ts *TwoFactorService) verify(user *models.User, input dto.TwoFactorCodeInput) error {
+	if input.RecoveryCode == "" {
+		return ts.verifyTOTP(user, input.Code)
+	}
+
+	used, err := ts.recoveryCodeRepository.Use(user.ID, utils.HashToken(normalizeRecoveryCode(input.RecoveryCode)), time.Now().UTC())
+	if err != nil {
+		return errors.New("Failed to use recovery code: " + err.Error())
+	}
+	if !used {
+		return models.ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// verifyTOTP checks the code and records its time step, so a code seen by
+// someone else cannot be replayed.
+func (ts *TwoFactorService) verifyTOTP(user *models.User, code string) error {
+	step, ok := auth.ValidateTOTP(user.TOTPSecret, code, time.Now())
+	if !ok || step <= user.TOTPLastStep {
+		return models.ErrInvalidTwoFactorCode
+	}
+
+	fresh, err := ts.userRepository.UseTOTPStep(user.ID, step)
+	if err != nil {
+		return errors.New("Failed to use two-factor code: " + err.Error())
+	}
+	if !fresh {
+		return models.ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// replaceRecoveryCodes generates new recovery codes, storing only their
+// hashes.
+func (ts *TwoFactorService) replaceRecoveryCodes(userID int) (dto.RecoveryCodesOutput, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for len(codes) < recoveryCodeCount {
+		code, err := utils.RandomToken(recoveryCodeSize)
+		if err != nil {
+			return dto.RecoveryCodesOutput{}, errors.New("Failed to generate recovery code: " + err.Error())
+		}
+		codes = append(codes, code[:recoveryCodeDivider]+"-"+code[recoveryCodeDivider:])
+		hashes = append(hashes, utils.HashToken(code))
+	}
+
+	err := ts.recoveryCodeRepository.Replace(userID, hashes, time.Now().UTC())
+	if err != nil {
+		return dto.RecoveryCodesOutput{}, errors.New("Failed to save recovery codes: " + err.Error())
+	}
+	return dto.RecoveryCodesOutput{RecoveryCodes: codes}, nil
+}
+
+// normalizeRecoveryCode accepts recovery codes typed without the dash or in
+// upper case.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.ReplaceAll(code, "-", "")
+}
@@ -2,43 +2,53 @@ package service
 
 import (
 	"errors"
-	"github.com/matheusvidal21/smart-news-fetcher/internal/auth"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/dto"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/email"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/syndication"
+	"github.com/matheusvidal21/smart-news-fetcher/pkg/utils"
+	"golang.org/x/crypto/bcrypt"
+	"strconv"
 )
 
+const feedTokenSize = 32
+
 type UserService struct {
 	userRepository interfaces.UserRepositoryInterface
 	EmailService   interfaces.EmailService
-	AuthService    auth.JWTServiceInterface
 }
 
-func NewUserService(userRepository interfaces.UserRepositoryInterface, emailService interfaces.EmailService, authService auth.JWTServiceInterface) *UserService {
+func NewUserService(userRepository interfaces.UserRepositoryInterface, emailService interfaces.EmailService) *UserService {
 	return &UserService{
 		userRepository: userRepository,
 		EmailService:   emailService,
-		AuthService:    authService,
 	}
 }
 
-func (us *UserService) FindByEmail(email string) (dto.FindUserByEmailOutput, error) {
-	user, err := us.userRepository.FindByEmail(email)
-
+// FindByEmail returns the current user when the email is theirs. Other users
+// are forbidden, whether they exist or not.
+func (us *UserService) FindByEmail(currentUserID int, email string) (dto.FindUserByEmailOutput, error) {
+	user, err := us.currentUser(currentUserID)
 	if err != nil {
-		return dto.FindUserByEmailOutput{}, errors.New("failed to find user")
+		return dto.FindUserByEmailOutput{}, err
+	}
+	if user.Email != email {
+		return dto.FindUserByEmailOutput{}, models.ErrForbidden
 	}
 
 	return dto.FindUserByEmailOutput{
-		ID:       user.ID,
-		Username: user.Username,
-		Email:    user.Email,
+		ID:               user.ID,
+		Username:         user.Username,
+		Email:            user.Email,
+		Role:             user.Role,
+		EmailVerified:    user.EmailVerified,
+		TwoFactorEnabled: user.TwoFactorEnabled,
 	}, nil
 }
 
 func (us *UserService) Create(userDto dto.CreateUserInput) (dto.CreateUserOutput, error) {
-	_, err := us.FindByEmail(userDto.Email)
+	_, err := us.userRepository.FindByEmail(userDto.Email)
 	if err == nil {
 		return dto.CreateUserOutput{}, errors.New("user already exists")
 	}
@@ -73,10 +83,10 @@ func (us *UserService) Create(userDto dto.CreateUserInput) (dto.CreateUserOutput
 	}, nil
 }
 
-func (us *UserService) Delete(email string) error {
-	_, err := us.FindByEmail(email)
+func (us *UserService) Delete(currentUserID int, email string) error {
+	_, err := us.FindByEmail(currentUserID, email)
 	if err != nil {
-		return errors.New("user not found")
+		return err
 	}
 
 	err = us.userRepository.Delete(email)
@@ -86,34 +96,39 @@ func (us *UserService) Delete(email string) error {
 	return nil
 }
 
-func (us *UserService) Login(userDto dto.LoginUserInput) (dto.LoginUserOutput, error) {
+// Authenticate checks the credentials of a user about to log in.
+// dummyPasswordHash is compared against when the email is unknown, so the
+// response takes as long as for a wrong password.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("smart-news-fetcher"), bcrypt.DefaultCost)
+
+func (us *UserService) Authenticate(userDto dto.LoginUserInput) (*models.User, error) {
 	user, err := us.userRepository.FindByEmail(userDto.Email)
 	if err != nil {
-		return dto.LoginUserOutput{}, errors.New("failed to find user")
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(userDto.Password))
+		return nil, models.ErrInvalidCredentials
 	}
 
 	if !user.ValidatePassword(userDto.Password) {
-		return dto.LoginUserOutput{}, errors.New("invalid password")
+		return nil, models.ErrInvalidCredentials
 	}
-	token, err := us.AuthService.GenerateToken(user.Email)
-	if err != nil {
-		return dto.LoginUserOutput{}, errors.New("failed to generate token: " + err.Error())
+	if user.Disabled {
+		return nil, models.ErrUserDisabled
 	}
-
-	return dto.LoginUserOutput{
-		Token: token,
-	}, nil
+	return user, nil
 }
 
-func (us *UserService) UpdatePassword(userDto dto.UpdateUserPasswordInput) error {
-	user, err := us.userRepository.FindByEmail(userDto.Email)
+func (us *UserService) UpdatePassword(currentUserID int, userDto dto.UpdateUserPasswordInput) error {
+	user, err := us.currentUser(currentUserID)
 	if err != nil {
-		return errors.New("failed to find user")
+		return err
+	}
+	if user.Email != userDto.Email {
+		return models.ErrForbidden
 	}
 
 	ok := user.ValidatePassword(userDto.OldPassword)
 	if !ok {
-		return errors.New("invalid password")
+		return models.ErrInvalidCredentials
 	}
 
 	user.Password = userDto.NewPassword
@@ -122,17 +137,73 @@ func (us *UserService) UpdatePassword(userDto dto.UpdateUserPasswordInput) error
 	if err != nil {
 		return errors.New("failed to update password: " + err.Error())
 	}
+
+	err = us.userRepository.IncrementTokenVersion(user.ID)
+	if err != nil {
+		return errors.New("Password updated but failed to end sessions: " + err.Error())
+	}
 	return nil
 }
 
-func (us *UserService) FindById(id int) (dto.FindUserByIdOutput, error) {
-	user, err := us.userRepository.FindById(id)
+func (us *UserService) FindById(currentUserID, id int) (dto.FindUserByIdOutput, error) {
+	if id != currentUserID {
+		return dto.FindUserByIdOutput{}, models.ErrForbidden
+	}
+
+	user, err := us.currentUser(currentUserID)
 	if err != nil {
-		return dto.FindUserByIdOutput{}, errors.New("failed to find user")
+		return dto.FindUserByIdOutput{}, err
 	}
 	return dto.FindUserByIdOutput{
-		ID:       user.ID,
-		Username: user.Username,
-		Email:    user.Email,
+		ID:               user.ID,
+		Username:         user.Username,
+		Email:            user.Email,
+		Role:             user.Role,
+		EmailVerified:    user.EmailVerified,
+		TwoFactorEnabled: user.TwoFactorEnabled,
+		Disabled:         user.Disabled,
 	}, nil
 }
+
+func (us *UserService) currentUser(id int) (*models.User, error) {
+	user, err := us.userRepository.FindById(id)
+	if err != nil {
+		return nil, errors.New("failed to find user")
+	}
+	return user, nil
+}
+
+// FeedToken returns the token that grants access to the user's published
+// feeds, creating it on first use.
+func (us *UserService) FeedToken(id int) (dto.FeedTokenOutput, error) {
+	user, err := us.userRepository.FindById(id)
+	if err != nil {
+		return dto.FeedTokenOutput{}, errors.New("failed to find user")
+	}
+	if user.FeedToken != "" {
+		return feedTokenOutput(user.ID, user.FeedToken), nil
+	}
+	return us.RegenerateFeedToken(id)
+}
+
+// RegenerateFeedToken replaces the feed token, revoking the previous feed URLs.
+func (us *UserService) RegenerateFeedToken(id int) (dto.FeedTokenOutput, error) {
+	token, err := utils.RandomToken(feedTokenSize)
+	if err != nil {
+		return dto.FeedTokenOutput{}, errors.New("failed to generate feed token: " + err.Error())
+	}
+
+	err = us.userRepository.UpdateFeedToken(id, token)
+	if err != nil {
+		return dto.FeedTokenOutput{}, errors.New("failed to update feed token: " + err.Error())
+	}
+	return feedTokenOutput(id, token), nil
+}
+
+func feedTokenOutput(id int, token string) dto.FeedTokenOutput {
+	feeds := make(map[string]string)
+	for _, format := range []string{syndication.FormatRSS, syndication.FormatAtom, syndication.FormatJSON} {
+		feeds[format] = "/users/" + strconv.Itoa(id) + "/feed." + format + "?token=" + token
+	}
+	return dto.FeedTokenOutput{FeedToken: token, Feeds: feeds}
+}
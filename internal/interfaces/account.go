@@ -0,0 +1,20 @@
+package interfaces
+
+import (
+	"github.com/matheusvidal21/smart-news-fetcher/internal/dto"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"time"
+)
+
+type UserTokenRepositoryInterface interface {
+	Create(token models.UserToken) error
+	Use(id string, userID int, purpose string, now time.Time) (bool, error)
+	UseAll(userID int, purpose string, now time.Time) error
+}
+
+type AccountServiceInterface interface {
+	SendVerification(userID int) error
+	VerifyEmail(token string) error
+	RequestPasswordReset(email string) error
+	ResetPassword(resetDto dto.ConfirmPasswordResetInput) error
+}
@@ -0,0 +1,29 @@
+package interfaces
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/dto"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+)
+
+type AdminServiceInterface interface {
+	FindUsers(page, limit int) ([]models.User, error)
+	UpdateUserRole(adminID, userID int, roleDto dto.UpdateUserRoleInput) (models.User, error)
+	DisableUser(adminID, userID int) (models.User, error)
+	EnableUser(userID int) (models.User, error)
+	ResetTwoFactor(userID int) (models.User, error)
+	FindSources(page, limit int, sort string) ([]models.Source, error)
+	FetchHealth() (dto.FetchHealthOutput, error)
+	FetchSource(id int) error
+}
+
+type AdminHandlerInterface interface {
+	FindUsers(c *gin.Context)
+	UpdateUserRole(c *gin.Context)
+	DisableUser(c *gin.Context)
+	EnableUser(c *gin.Context)
+	ResetTwoFactor(c *gin.Context)
+	FindSources(c *gin.Context)
+	FetchHealth(c *gin.Context)
+	FetchSource(c *gin.Context)
+}
@@ -0,0 +1,31 @@
+package interfaces
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/auth"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/dto"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"time"
+)
+
+type APIKeyRepositoryInterface interface {
+	FindOne(id int) (models.APIKey, error)
+	FindByHash(keyHash string) (models.APIKey, error)
+	FindByUserId(userID int) ([]models.APIKey, error)
+	Create(key models.APIKey) (models.APIKey, error)
+	UpdateLastUsed(id int, lastUsedAt time.Time) error
+	Revoke(id int, revokedAt time.Time) error
+}
+
+type APIKeyServiceInterface interface {
+	Create(userID int, input dto.CreateAPIKeyInput) (dto.CreateAPIKeyOutput, error)
+	FindByUserId(userID int) ([]models.APIKey, error)
+	Revoke(userID, id int) error
+	Authenticate(key string) (auth.Identity, []string, error)
+}
+
+type APIKeyHandlerInterface interface {
+	FindAll(c *gin.Context)
+	Create(c *gin.Context)
+	Revoke(c *gin.Context)
+}
@@ -4,25 +4,58 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/dto"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"time"
 )
 
 type ArticleRepositoryInterface interface {
-	FindAll(page, limit int, sort string) ([]models.Article, error)
+	FindAll(filter models.ArticleFilter, page, limit int, sort string) ([]models.Article, error)
 	FindOne(id string) (models.Article, error)
 	Create(article models.Article) (models.Article, error)
 	Update(id string, article models.Article) (models.Article, error)
 	Delete(id string) error
 	FindAllBySourceId(sourceID int) ([]models.Article, error)
+	FindFiltered(filter models.ArticleFilter, page, limit int) ([]models.Article, error)
+	Search(filter models.ArticleFilter, sort string, page, limit int) ([]models.ArticleMatch, int, error)
+	Rekey(oldID, newID string) error
+	UpdateExtractedContent(id, extractedContent, plainText string, extractedAt time.Time) error
+	FindWithoutPlainText(afterID string, limit int) ([]models.Article, error)
+	UpdatePlainText(id, plainText string) error
+}
+
+type ArticleRevisionRepositoryInterface interface {
+	Create(revision models.ArticleRevision) (models.ArticleRevision, error)
+	FindByArticleId(articleID string) ([]models.ArticleRevision, error)
+}
+
+type ArticleStateRepositoryInterface interface {
+	FindOne(userID int, articleID string) (models.ArticleState, error)
+	Save(state models.ArticleState) error
+	MarkRead(userID int, filter models.ArticleFilter, readAt time.Time) (int, error)
+	CountUnread(userID int, sourceIDs []int) (map[int]int, error)
 }
 
 type ArticleServiceInterface interface {
-	GenerateArticleID(title, link string) string
-	FindAll(page, limit int, sort string) ([]models.Article, error)
-	FindOne(id string) (dto.FindOneArticleOutput, error)
-	Create(articleDto dto.CreateArticleInput) (dto.CreateArticleOutput, error)
-	Update(id string, articleDto dto.UpdateArticleInput) (dto.UpdateArticleOutput, error)
-	Delete(id string) error
-	FindAllBySourceId(sourceID int) ([]models.Article, error)
+	GenerateArticleID(sourceID int, guid, link, title string) string
+	Save(articleDto dto.CreateArticleInput) (string, error)
+	FindAll(userID int, filter models.ArticleFilter, page, limit int, sort string) ([]models.Article, error)
+	FindOne(userID int, id string) (dto.FindOneArticleOutput, error)
+	Create(userID int, articleDto dto.CreateArticleInput) (dto.CreateArticleOutput, error)
+	Update(userID int, id string, articleDto dto.UpdateArticleInput) (dto.UpdateArticleOutput, error)
+	Delete(userID int, id string) error
+	FindAllBySourceId(userID, sourceID int, filter models.ArticleFilter) ([]models.Article, error)
+	FindRevisions(userID int, id string) ([]models.ArticleRevision, error)
+	Search(filter models.ArticleFilter, sort string, page, limit int) (dto.SearchArticlesOutput, error)
+	SaveExtractedContent(id, content string) error
+	FindState(userID int, id string) (models.ArticleState, error)
+	UpdateState(userID int, id string, stateDto dto.UpdateArticleStateInput) (models.ArticleState, error)
+	MarkRead(userID int, markDto dto.MarkArticlesReadInput) (dto.MarkArticlesReadOutput, error)
+	BackfillPlainText()
+}
+
+// ArticleExtractorInterface fetches the full content of articles whose feed only
+// gives a summary.
+type ArticleExtractorInterface interface {
+	ExtractArticle(id string) error
 }
 
 type ArticleHandlerInterface interface {
@@ -32,4 +65,10 @@ type ArticleHandlerInterface interface {
 	Update(c *gin.Context)
 	Delete(c *gin.Context)
 	FindBySourceID(c *gin.Context)
+	FindRevisions(c *gin.Context)
+	Extract(c *gin.Context)
+	Search(c *gin.Context)
+	FindState(c *gin.Context)
+	UpdateState(c *gin.Context)
+	MarkRead(c *gin.Context)
 }
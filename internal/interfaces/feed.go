@@ -0,0 +1,18 @@
+package interfaces
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/syndication"
+)
+
+type FeedServiceInterface interface {
+	UserFeed(userID int, token string, page, limit int) (syndication.Feed, error)
+	SourceFeed(sourceID int, token string, page, limit int) (syndication.Feed, error)
+	SavedSearchFeed(searchID int, token string, page, limit int) (syndication.Feed, error)
+}
+
+type FeedHandlerInterface interface {
+	UserFeed(c *gin.Context)
+	SourceFeed(c *gin.Context)
+	SavedSearchFeed(c *gin.Context)
+}
@@ -0,0 +1,8 @@
+package interfaces
+
+import "github.com/matheusvidal21/smart-news-fetcher/internal/models"
+
+type FetchRunRepositoryInterface interface {
+	Create(run models.FetchRun) (models.FetchRun, error)
+	FindBySourceId(sourceID, page, limit int) ([]models.FetchRun, error)
+}
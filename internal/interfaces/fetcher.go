@@ -3,12 +3,22 @@ package interfaces
 import (
 	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
 	"github.com/mmcdole/gofeed"
+	"net/http"
+	"net/url"
 )
 
 type FetcherInterface interface {
 	LoadFeed(id int) (*gofeed.Feed, error)
 	StoreFeed(id int, feed *gofeed.Feed)
 	ParseFeed(url string) (*gofeed.Feed, error)
-	FetchFeeds(id int, feed *gofeed.Feed)
-	StartScheduler(source models.Source, feed *gofeed.Feed)
+	FeedValidators(url string) (string, string)
+	DiscoverFeeds(pageURL string) ([]models.FeedCandidate, error)
+	ParseSourceFeed(source models.Source) (*gofeed.Feed, bool, error)
+	FetchFeeds(id int, feed *gofeed.Feed) models.FetchStats
+	FetchSource(source models.Source) (models.FetchStats, error)
+	ExtractContent(link string) (string, error)
+	ExtractArticle(id string) error
+	PostForm(target string, form url.Values) (*http.Response, error)
+	Start()
+	Stop()
 }
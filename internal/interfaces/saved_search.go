@@ -0,0 +1,36 @@
+package interfaces
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/dto"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+)
+
+type SavedSearchRepositoryInterface interface {
+	FindOne(id int) (models.SavedSearch, error)
+	FindByUserId(userID int) ([]models.SavedSearch, error)
+	FindInNewsletter() ([]models.SavedSearch, error)
+	Create(search models.SavedSearch) (models.SavedSearch, error)
+	Update(id int, search models.SavedSearch) (models.SavedSearch, error)
+	Delete(id int) error
+}
+
+type SavedSearchServiceInterface interface {
+	FindByUserId(userID int) ([]models.SavedSearch, error)
+	FindOne(userID, id int) (models.SavedSearch, error)
+	Create(userID int, searchDto dto.CreateSavedSearchInput) (models.SavedSearch, error)
+	Update(userID, id int, searchDto dto.UpdateSavedSearchInput) (models.SavedSearch, error)
+	Delete(userID, id int) error
+	Results(userID, id int, sort string, page, limit int) (dto.SearchArticlesOutput, error)
+	SendDigests()
+	StartDigest()
+}
+
+type SavedSearchHandlerInterface interface {
+	FindAll(c *gin.Context)
+	FindOne(c *gin.Context)
+	Create(c *gin.Context)
+	Update(c *gin.Context)
+	Delete(c *gin.Context)
+	Results(c *gin.Context)
+}
@@ -0,0 +1,12 @@
+package interfaces
+
+import "github.com/matheusvidal21/smart-news-fetcher/internal/models"
+
+type SchedulerInterface interface {
+	Start()
+	Stop()
+	Schedule(source models.Source)
+	Reschedule(source models.Source)
+	FetchNow(source models.Source)
+	Cancel(sourceID int)
+}
@@ -0,0 +1,26 @@
+package interfaces
+
+import (
+	"github.com/matheusvidal21/smart-news-fetcher/internal/auth"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/dto"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"time"
+)
+
+type SessionRepositoryInterface interface {
+	FindOne(id int) (models.Session, error)
+	FindByTokenHash(tokenHash string) (models.Session, error)
+	FindActiveByUserId(userID, tokenVersion int, now time.Time) ([]models.Session, error)
+	Create(session models.Session) (models.Session, error)
+	Rotate(session models.Session, presentedHash string) (bool, error)
+	Revoke(id int, revokedAt time.Time) error
+	DeleteExpired(userID, tokenVersion int, now time.Time) error
+}
+
+type SessionServiceInterface interface {
+	Start(userID int, client dto.SessionClient) (dto.LoginUserOutput, error)
+	Refresh(refreshToken string, client dto.SessionClient) (dto.LoginUserOutput, error)
+	Validate(identity auth.Identity) error
+	FindByUserId(userID, currentSessionID int) ([]models.Session, error)
+	Revoke(userID, id int) error
+}
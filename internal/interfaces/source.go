@@ -4,10 +4,11 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/dto"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"io"
 )
 
 type SourceRepositoryInterface interface {
-	FindAll(page, limit int, sort string) ([]models.Source, error)
+	FindAll(userID, page, limit int, sort string) ([]models.Source, error)
 	FindOne(id int) (models.Source, error)
 	Create(source models.Source) (models.Source, error)
 	Update(id int, source models.Source) (models.Source, error)
@@ -15,29 +16,43 @@ type SourceRepositoryInterface interface {
 	FindByUrl(url string) (models.Source, error)
 	FindByUserId(userId int) ([]models.Source, error)
 	FindAllActive() ([]models.Source, error)
+	FindAllOfEnabledUsers() ([]models.Source, error)
+	UpdateCacheValidators(id int, etag, lastModified string) error
+	UpdateFetchState(id, consecutiveFailures int, disabled bool, fetchStatus, lastError string) error
 }
 
 type SourceServiceInterface interface {
-	FindAll(page, limit int, sort string) ([]models.Source, error)
-	FindOne(id int) (dto.FindOneSourceOutput, error)
+	FindAll(userID, page, limit int, sort string) ([]models.Source, error)
+	FindOne(userID, id int) (dto.FindOneSourceOutput, error)
 	Create(sourceDto dto.CreateSourceInput) (dto.CreateSourceOutput, error)
-	Update(id int, sourceDto dto.UpdateSourceInput) (dto.UpdateSourceOutput, error)
-	Delete(id int) error
-	LoadFeed(id int) error
-	FindByUserId(userId int) ([]models.Source, error)
-	SubscribeToNewsletter(id int) error
-	UnsubscribeFromNewsletter(id int) error
+	Discover(url string) ([]models.FeedCandidate, error)
+	ImportOPML(userID int, document io.Reader) (dto.ImportOPMLOutput, error)
+	ExportOPML(userID int) ([]byte, error)
+	Update(userID, id int, sourceDto dto.UpdateSourceInput) (dto.UpdateSourceOutput, error)
+	Delete(userID, id int) error
+	LoadFeed(userID, id int) error
+	Resume(userID, id int) error
+	FindFetchRuns(userID, id, page, limit int) ([]models.FetchRun, error)
+	FindByUserId(currentUserID, userId int) ([]models.Source, error)
+	SubscribeToNewsletter(userID, id int) error
+	UnsubscribeFromNewsletter(userID, id int) error
 	StartSubscription(source models.Source)
 	InitializeSubscription()
+	InitializeScheduler()
 }
 
 type SourceHandlerInterface interface {
 	FindAll(c *gin.Context)
 	FindOne(c *gin.Context)
 	Create(c *gin.Context)
+	Discover(c *gin.Context)
+	ImportOPML(c *gin.Context)
+	ExportOPML(c *gin.Context)
 	Update(c *gin.Context)
 	Delete(c *gin.Context)
 	LoadFeed(c *gin.Context)
+	Resume(c *gin.Context)
+	FindFetchRuns(c *gin.Context)
 	FindByUserId(c *gin.Context)
 	SubscribeToNewsletter(c *gin.Context)
 	UnsubscribeFromNewsletter(c *gin.Context)
@@ -0,0 +1,31 @@
+package interfaces
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/dto"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"time"
+)
+
+type RecoveryCodeRepositoryInterface interface {
+	Replace(userID int, codeHashes []string, createdAt time.Time) error
+	Use(userID int, codeHash string, usedAt time.Time) (bool, error)
+	DeleteByUserId(userID int) error
+}
+
+type TwoFactorServiceInterface interface {
+	Enroll(userID int) (dto.TwoFactorEnrollOutput, error)
+	Confirm(userID int, input dto.TwoFactorConfirmInput) (dto.RecoveryCodesOutput, error)
+	Disable(userID int, input dto.TwoFactorCodeInput) error
+	RegenerateRecoveryCodes(userID int, input dto.TwoFactorCodeInput) (dto.RecoveryCodesOutput, error)
+	Challenge(user *models.User) (dto.TwoFactorChallengeOutput, error)
+	VerifyChallenge(input dto.TwoFactorLoginInput) (*models.User, error)
+}
+
+type TwoFactorHandlerInterface interface {
+	Enroll(c *gin.Context)
+	Confirm(c *gin.Context)
+	Disable(c *gin.Context)
+	RegenerateRecoveryCodes(c *gin.Context)
+	Login(c *gin.Context)
+}
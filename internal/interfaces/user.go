@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/dto"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"time"
 )
 
 type UserRepositoryInterface interface {
@@ -12,15 +13,32 @@ type UserRepositoryInterface interface {
 	Delete(email string) error
 	Update(user models.User) (*models.User, error)
 	FindById(id int) (*models.User, error)
+	UpdateFeedToken(id int, feedToken string) error
+	FindAll(page, limit int) ([]models.User, error)
+	UpdateRole(id int, role string) error
+	UpdateDisabled(id int, disabled bool) error
+	IncrementTokenVersion(id int) error
+	UpdateEmailVerified(id int, verified bool) error
+	UpdateTwoFactor(id int, secret string, enabled bool) error
+	UseTOTPStep(id int, step int64) (bool, error)
 }
 
 type UserServiceInterface interface {
-	FindByEmail(email string) (dto.FindUserByEmailOutput, error)
+	FindByEmail(currentUserID int, email string) (dto.FindUserByEmailOutput, error)
 	Create(userDto dto.CreateUserInput) (dto.CreateUserOutput, error)
-	Delete(email string) error
-	Login(userDto dto.LoginUserInput) (dto.LoginUserOutput, error)
-	UpdatePassword(userDto dto.UpdateUserPasswordInput) error
-	FindById(id int) (dto.FindUserByIdOutput, error)
+	Delete(currentUserID int, email string) error
+	Authenticate(userDto dto.LoginUserInput) (*models.User, error)
+	UpdatePassword(currentUserID int, userDto dto.UpdateUserPasswordInput) error
+	FindById(currentUserID, id int) (dto.FindUserByIdOutput, error)
+	FeedToken(id int) (dto.FeedTokenOutput, error)
+	RegenerateFeedToken(id int) (dto.FeedTokenOutput, error)
+}
+
+type LoginThrottleInterface interface {
+	Allow(email, ip string) (time.Duration, bool)
+	Failure(email, ip string)
+	Success(email, ip string)
+	Release(email, ip string)
 }
 
 type UserHandlerInterface interface {
@@ -30,4 +48,14 @@ type UserHandlerInterface interface {
 	Login(c *gin.Context)
 	UpdatePassword(c *gin.Context)
 	FindById(c *gin.Context)
+	FeedToken(c *gin.Context)
+	RegenerateFeedToken(c *gin.Context)
+	Refresh(c *gin.Context)
+	Logout(c *gin.Context)
+	FindSessions(c *gin.Context)
+	RevokeSession(c *gin.Context)
+	VerifyEmail(c *gin.Context)
+	ResendVerification(c *gin.Context)
+	RequestPasswordReset(c *gin.Context)
+	ConfirmPasswordReset(c *gin.Context)
 }
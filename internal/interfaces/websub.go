@@ -0,0 +1,32 @@
+package interfaces
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"time"
+)
+
+type WebSubRepositoryInterface interface {
+	FindBySourceId(sourceID int) (models.WebSubSubscription, error)
+	Save(subscription models.WebSubSubscription) error
+	FindRenewable(before time.Time) ([]models.WebSubSubscription, error)
+}
+
+// HubObserver is notified of the WebSub hub advertised by a fetched source feed.
+type HubObserver interface {
+	Observe(source models.Source, hubURL, topicURL string)
+}
+
+type WebSubSubscriberInterface interface {
+	HubObserver
+	Start()
+	Stop()
+	IsActive(sourceID int) bool
+	Verify(sourceID int, token, mode, topic, challenge string, leaseSeconds int) (string, error)
+	Receive(sourceID int, token, signature string, body []byte) (models.FetchStats, error)
+}
+
+type WebSubHandlerInterface interface {
+	Verify(c *gin.Context)
+	Receive(c *gin.Context)
+}
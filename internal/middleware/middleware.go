@@ -3,32 +3,188 @@ package middleware
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/matheusvidal21/smart-news-fetcher/internal/auth"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
 	"net/http"
 	"strings"
 )
 
-func AuthMiddleware(jwtService auth.JWTServiceInterface) gin.HandlerFunc {
+const (
+	UserIDKey    = "user_id"
+	UserEmailKey = "user_email"
+	UserRoleKey  = "user_role"
+	SessionIDKey = "session_id"
+	APIScopesKey = "api_scopes"
+)
+
+// SessionValidator tells whether the session behind a valid access token is
+// still open.
+type SessionValidator interface {
+	Validate(identity auth.Identity) error
+}
+
+// APIKeyAuthenticator resolves an API key into the identity of its user and
+// the scopes the key is limited to.
+type APIKeyAuthenticator interface {
+	Authenticate(key string) (auth.Identity, []string, error)
+}
+
+// CurrentUserID returns the id of the user authenticated by AuthMiddleware.
+func CurrentUserID(context *gin.Context) (int, bool) {
+	userID, ok := context.Get(UserIDKey)
+	if !ok {
+		return 0, false
+	}
+	id, ok := userID.(int)
+	return id, ok
+}
+
+// CurrentUserRole returns the role of the user authenticated by AuthMiddleware.
+func CurrentUserRole(context *gin.Context) (string, bool) {
+	role, ok := context.Get(UserRoleKey)
+	if !ok {
+		return "", false
+	}
+	name, ok := role.(string)
+	return name, ok
+}
+
+// CurrentSessionID returns the session of the access token authenticated by
+// AuthMiddleware.
+func CurrentSessionID(context *gin.Context) (int, bool) {
+	sessionID, ok := context.Get(SessionIDKey)
+	if !ok {
+		return 0, false
+	}
+	id, ok := sessionID.(int)
+	return id, ok
+}
+
+// AuthMiddleware accepts either a Bearer access token of an open session or a
+// personal API key, sent as a Bearer token or in the X-API-Key header.
+func AuthMiddleware(jwtService auth.JWTServiceInterface, sessions SessionValidator, apiKeys APIKeyAuthenticator) gin.HandlerFunc {
 	return func(context *gin.Context) {
-		authHeader := context.GetHeader("Authorization")
-		if authHeader == "" {
-			context.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
+		token := context.GetHeader("X-API-Key")
+		if token == "" {
+			authHeader := context.GetHeader("Authorization")
+			if authHeader == "" {
+				context.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
+				context.Abort()
+				return
+			}
+			token = strings.TrimPrefix(authHeader, "Bearer ")
+			if token == authHeader {
+				token = ""
+			}
+		}
+		if token == "" {
+			context.JSON(http.StatusUnauthorized, gin.H{"error": "Token is required"})
 			context.Abort()
 			return
 		}
 
-		token := strings.Split(authHeader, "Bearer ")[1]
-		if token == "" {
-			context.JSON(http.StatusUnauthorized, gin.H{"error": "Token is required"})
+		if strings.HasPrefix(token, models.APIKeyPrefix) {
+			identity, scopes, err := apiKeys.Authenticate(token)
+			if err != nil {
+				context.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				context.Abort()
+				return
+			}
+			context.Set(UserIDKey, identity.UserID)
+			context.Set(UserEmailKey, identity.Email)
+			context.Set(UserRoleKey, identity.Role)
+			context.Set(APIScopesKey, scopes)
+			context.Next()
+			return
+		}
+
+		validToken, err := jwtService.ValidateToken(token)
+		if err != nil {
+			context.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			context.Abort()
 			return
 		}
 
-		_, err := jwtService.ValidateToken(token)
+		identity, err := auth.TokenIdentity(validToken)
 		if err != nil {
 			context.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			context.Abort()
 			return
 		}
+
+		err = sessions.Validate(identity)
+		if err != nil {
+			context.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			context.Abort()
+			return
+		}
+		context.Set(UserIDKey, identity.UserID)
+		context.Set(UserEmailKey, identity.Email)
+		context.Set(UserRoleKey, identity.Role)
+		context.Set(SessionIDKey, identity.SessionID)
 		context.Next()
 	}
 }
+
+// RequireSession refuses requests authenticated by an API key, for routes that
+// manage the account itself.
+func RequireSession() gin.HandlerFunc {
+	return func(context *gin.Context) {
+		if _, ok := context.Get(APIScopesKey); ok {
+			context.JSON(http.StatusForbidden, gin.H{"error": "API keys cannot access this resource"})
+			context.Abort()
+			return
+		}
+		context.Next()
+	}
+}
+
+// RequireScope only lets through API keys granted one of the scopes, so with
+// no scopes it refuses every limited key. Sessions and keys created without
+// scopes are not limited.
+func RequireScope(scopes ...string) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		value, ok := context.Get(APIScopesKey)
+		if !ok {
+			context.Next()
+			return
+		}
+
+		granted, _ := value.([]string)
+		if len(granted) == 0 {
+			context.Next()
+			return
+		}
+		for _, scope := range scopes {
+			for _, name := range granted {
+				if name == scope {
+					context.Next()
+					return
+				}
+			}
+		}
+		context.JSON(http.StatusForbidden, gin.H{"error": "API key is not allowed to access this resource"})
+		context.Abort()
+	}
+}
+
+// RequireRole only lets through users authenticated by AuthMiddleware with one
+// of the roles.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		role, ok := CurrentUserRole(context)
+		if !ok {
+			context.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			context.Abort()
+			return
+		}
+
+		for _, allowed := range roles {
+			if role == allowed {
+				context.Next()
+				return
+			}
+		}
+		context.JSON(http.StatusForbidden, gin.H{"error": "Access to this resource is forbidden"})
+		context.Abort()
+	}
+}
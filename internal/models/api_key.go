@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// APIKeyPrefix marks API keys so they can be told apart from access tokens in
+// the Authorization header.
+const APIKeyPrefix = "snf_"
+
+const (
+	ScopeArticlesRead  = "articles:read"
+	ScopeSourcesManage = "sources:manage"
+)
+
+// APIKey lets scripts call the API on behalf of a user. A key without scopes
+// can do everything its user can outside of account management; otherwise it
+// is limited to its scopes.
+type APIKey struct {
+	ID         int       `json:"id"`
+	UserID     int       `json:"-"`
+	Name       string    `json:"name"`
+	Prefix     string    `json:"prefix"`
+	KeyHash    string    `json:"-"`
+	Scopes     []string  `json:"scopes"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	RevokedAt  time.Time `json:"-"`
+}
+
+// Active reports whether the key can still be used at the given time.
+func (k APIKey) Active(now time.Time) bool {
+	return k.RevokedAt.IsZero() && (k.ExpiresAt.IsZero() || now.Before(k.ExpiresAt))
+}
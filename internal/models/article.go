@@ -3,12 +3,88 @@ package models
 import "time"
 
 type Article struct {
-	ID          string    `json:"id"`
+	ID               string    `json:"id"`
+	Title            string    `json:"title"`
+	Description      string    `json:"description"`
+	Content          string    `json:"content"`
+	Link             string    `json:"link"`
+	PubDate          time.Time `json:"pub_date"`
+	Author           string    `json:"author"`
+	SourceID         int       `json:"source_id"`
+	Guid             string    `json:"guid"`
+	ContentHash      string    `json:"content_hash"`
+	PubDateSource    string    `json:"pub_date_source"`
+	PlainText        string    `json:"plain_text"`
+	ExtractedContent string    `json:"extracted_content"`
+	ExtractedAt      time.Time `json:"extracted_at"`
+}
+
+// ArticleFilter narrows article queries. Zero values are ignored. Query is
+// matched against the full-text index of the title and plain text. UnreadOnly
+// and Starred look at the state of the articles for ReaderID.
+type ArticleFilter struct {
+	UserID     int
+	SourceID   int
+	SourceIDs  []int
+	Query      string
+	Author     string
+	From       time.Time
+	To         time.Time
+	ReaderID   int
+	UnreadOnly bool
+	Starred    bool
+}
+
+const (
+	ArticleSortRelevance = "relevance"
+	ArticleSortDate      = "date"
+)
+
+// ArticleMatch is an article found by a full-text search.
+type ArticleMatch struct {
+	Article
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet"`
+}
+
+const (
+	ArticleCreated   = "created"
+	ArticleUpdated   = "updated"
+	ArticleUnchanged = "unchanged"
+)
+
+// Sources of Article.PubDate, from the item publish date down to the time the
+// item was first fetched when the feed gives no usable date.
+const (
+	PubDatePublished  = "published"
+	PubDateUpdated    = "updated"
+	PubDateDublinCore = "dublin_core"
+	PubDateFirstSeen  = "first_seen"
+)
+
+// ArticleState is what a user did with an article. Articles without a saved
+// state are unread.
+type ArticleState struct {
+	UserID     int       `json:"user_id"`
+	ArticleID  string    `json:"article_id"`
+	Read       bool      `json:"read"`
+	ReadAt     time.Time `json:"read_at"`
+	Starred    bool      `json:"starred"`
+	StarredAt  time.Time `json:"starred_at"`
+	Archived   bool      `json:"archived"`
+	ArchivedAt time.Time `json:"archived_at"`
+}
+
+// ArticleRevision is a previous version of an article, saved when its content changed.
+type ArticleRevision struct {
+	ID          int       `json:"id"`
+	ArticleID   string    `json:"article_id"`
 	Title       string    `json:"title"`
 	Description string    `json:"description"`
 	Content     string    `json:"content"`
 	Link        string    `json:"link"`
-	PubDate     time.Time `json:"pub_date"`
 	Author      string    `json:"author"`
-	SourceID    int       `json:"source_id"`
+	PubDate     time.Time `json:"pub_date"`
+	ContentHash string    `json:"content_hash"`
+	RevisedAt   time.Time `json:"revised_at"`
 }
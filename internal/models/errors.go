@@ -0,0 +1,35 @@
+package models
+
+import "errors"
+
+var (
+	// Users and authentication. ErrInvalidCredentials is returned for unknown
+	// emails and wrong passwords alike, so logins cannot be used to find out
+	// which emails are registered.
+	ErrUserNotFound          = errors.New("User not found")
+	ErrUserDisabled          = errors.New("User account is disabled")
+	ErrEmailNotVerified      = errors.New("Email address is not verified")
+	ErrInvalidEmailToken     = errors.New("Invalid or expired token")
+	ErrInvalidCredentials    = errors.New("Invalid email or password")
+	ErrTooManyAttempts       = errors.New("Too many failed attempts, try again later")
+	ErrSessionNotFound       = errors.New("Session not found")
+	ErrSessionExpired        = errors.New("Session expired or revoked")
+	ErrInvalidRefreshToken   = errors.New("Invalid refresh token")
+	ErrTwoFactorEnabled      = errors.New("Two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled   = errors.New("Two-factor authentication is not enabled")
+	ErrTwoFactorNotEnrolled  = errors.New("Two-factor authentication enrollment was not started")
+	ErrInvalidTwoFactorCode  = errors.New("Invalid two-factor code")
+	ErrInvalidLoginChallenge = errors.New("Invalid or expired login challenge")
+	ErrAPIKeyNotFound        = errors.New("API key not found")
+	ErrInvalidAPIKey         = errors.New("Invalid or expired API key")
+	ErrAPIKeyExpiry          = errors.New("API key expiry must be in the future")
+	ErrInvalidFeedToken      = errors.New("Invalid feed token")
+
+	// Sources, articles and saved searches. Resources of other users are
+	// reported as not found so their ids cannot be probed. ErrForbidden is
+	// kept for requests naming another user explicitly.
+	ErrSourceNotFound      = errors.New("Source not found")
+	ErrArticleNotFound     = errors.New("Article not found")
+	ErrSavedSearchNotFound = errors.New("Saved search not found")
+	ErrForbidden           = errors.New("Access to this resource is forbidden")
+)
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+type FetchStats struct {
+	New     int `json:"new"`
+	Updated int `json:"updated"`
+	Skipped int `json:"skipped"`
+	Failed  int `json:"failed"`
+}
+
+type FetchRun struct {
+	ID            int       `json:"id"`
+	SourceID      int       `json:"source_id"`
+	StartedAt     time.Time `json:"started_at"`
+	DurationMs    int64     `json:"duration_ms"`
+	HTTPStatus    int       `json:"http_status"`
+	ItemsSeen     int       `json:"items_seen"`
+	ItemsInserted int       `json:"items_inserted"`
+	ItemsUpdated  int       `json:"items_updated"`
+	Error         string    `json:"error"`
+}
+
+type FeedCandidate struct {
+	Url   string `json:"url"`
+	Title string `json:"title"`
+	Type  string `json:"type"`
+}
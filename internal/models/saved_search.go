@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// SavedSearch is an article search kept by a user and followed like a source.
+// Days limits the results to the articles published in the last days.
+type SavedSearch struct {
+	ID           int       `json:"id"`
+	UserID       int       `json:"user_id"`
+	Name         string    `json:"name"`
+	Query        string    `json:"query"`
+	SourceIDs    []int     `json:"source_ids"`
+	Author       string    `json:"author"`
+	Days         int       `json:"days"`
+	InNewsletter bool      `json:"in_newsletter"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Filter returns the article filter of the search as of now.
+func (s SavedSearch) Filter(now time.Time) ArticleFilter {
+	filter := ArticleFilter{
+		UserID:    s.UserID,
+		SourceIDs: s.SourceIDs,
+		Query:     s.Query,
+		Author:    s.Author,
+	}
+	if s.Days > 0 {
+		filter.From = now.AddDate(0, 0, -s.Days)
+	}
+	return filter
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Session is a login of a user on a device. It is kept alive by rotating its
+// refresh token, and ends when revoked, expired or when the user's token
+// version moves past the one it was started with.
+type Session struct {
+	ID                int       `json:"id"`
+	UserID            int       `json:"-"`
+	TokenHash         string    `json:"-"`
+	PreviousTokenHash string    `json:"-"`
+	TokenVersion      int       `json:"-"`
+	UserAgent         string    `json:"user_agent"`
+	IP                string    `json:"ip"`
+	CreatedAt         time.Time `json:"created_at"`
+	LastUsedAt        time.Time `json:"last_used_at"`
+	ExpiresAt         time.Time `json:"expires_at"`
+	RevokedAt         time.Time `json:"-"`
+	Current           bool      `json:"current"`
+}
+
+// Active reports whether the session can still be used at the given time.
+func (s Session) Active(now time.Time) bool {
+	return s.RevokedAt.IsZero() && now.Before(s.ExpiresAt)
+}
@@ -5,11 +5,20 @@ import (
 )
 
 type Source struct {
-	ID             int       `json:"id"`
-	Name           string    `json:"name"`
-	Url            string    `json:"url"`
-	SavedAt        time.Time `json:"saved_at"`
-	UserID         int       `json:"user_id"`
-	UpdateInterval int       `json:"update_interval"`
-	Subscriber     bool      `json:"subscriber"`
+	ID                  int       `json:"id"`
+	Name                string    `json:"name"`
+	Url                 string    `json:"url"`
+	SavedAt             time.Time `json:"saved_at"`
+	UserID              int       `json:"user_id"`
+	UpdateInterval      int       `json:"update_interval"`
+	Category            string    `json:"category"`
+	FullContent         bool      `json:"full_content"`
+	Subscriber          bool      `json:"subscriber"`
+	ETag                string    `json:"-"`
+	LastModified        string    `json:"-"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	Disabled            bool      `json:"disabled"`
+	FetchStatus         string    `json:"fetch_status"`
+	LastError           string    `json:"last_error"`
+	UnreadCount         int       `json:"unread_count"`
 }
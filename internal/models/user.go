@@ -6,11 +6,24 @@ import (
 	"errors"
 )
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
-	ID       int    `json:"id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Password string `json:"-"`
+	ID               int    `json:"id"`
+	Username         string `json:"username"`
+	Email            string `json:"email"`
+	Password         string `json:"-"`
+	FeedToken        string `json:"-"`
+	Role             string `json:"role"`
+	Disabled         bool   `json:"disabled"`
+	TokenVersion     int    `json:"-"`
+	EmailVerified    bool   `json:"email_verified"`
+	TOTPSecret       string `json:"-"`
+	TwoFactorEnabled bool   `json:"two_factor_enabled"`
+	TOTPLastStep     int64  `json:"-"`
 }
 
 func NewUser(username, email, password string) (*User, error) {
@@ -24,6 +37,7 @@ func NewUser(username, email, password string) (*User, error) {
 		Username: username,
 		Email:    email,
 		Password: string(hash),
+		Role:     RoleUser,
 	}, nil
 }
 
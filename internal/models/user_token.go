@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+const (
+	TokenPurposePasswordReset     = "password_reset"
+	TokenPurposeEmailVerification = "email_verification"
+	TokenPurposeLoginChallenge    = "login_challenge"
+)
+
+// UserToken records a token sent to a user by email so it can only be used
+// once. The token itself is signed and carries the id of its record.
+type UserToken struct {
+	ID        string
+	UserID    int
+	Purpose   string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    time.Time
+}
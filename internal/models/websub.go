@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+const (
+	WebSubStatePending      = "pending"
+	WebSubStateActive       = "active"
+	WebSubStateDenied       = "denied"
+	WebSubStateFailed       = "failed"
+	WebSubStateExpired      = "expired"
+	WebSubStateUnsubscribed = "unsubscribed"
+)
+
+type WebSubSubscription struct {
+	SourceID       int       `json:"source_id"`
+	HubURL         string    `json:"hub_url"`
+	TopicURL       string    `json:"topic_url"`
+	Secret         string    `json:"-"`
+	CallbackToken  string    `json:"-"`
+	State          string    `json:"state"`
+	LeaseSeconds   int       `json:"lease_seconds"`
+	LeaseExpiresAt time.Time `json:"lease_expires_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
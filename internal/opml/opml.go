@@ -0,0 +1,141 @@
+package opml
+
+import (
+	"encoding/xml"
+	"errors"
+	"golang.org/x/net/html/charset"
+	"io"
+	"strings"
+	"time"
+)
+
+const categorySeparator = "/"
+
+type Document struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    Head     `xml:"head"`
+	Body    Body     `xml:"body"`
+}
+
+type Head struct {
+	Title       string `xml:"title"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+	OwnerName   string `xml:"ownerName,omitempty"`
+	OwnerEmail  string `xml:"ownerEmail,omitempty"`
+}
+
+type Body struct {
+	Outlines []Outline `xml:"outline"`
+}
+
+type Outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr,omitempty"`
+	Type     string    `xml:"type,attr,omitempty"`
+	XMLURL   string    `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string    `xml:"htmlUrl,attr,omitempty"`
+	Outlines []Outline `xml:"outline"`
+}
+
+// Entry is a feed outline together with the folders it was nested in.
+type Entry struct {
+	Title    string
+	XMLURL   string
+	Category string
+}
+
+// Feed is a subscription written by Export.
+type Feed struct {
+	Title    string
+	XMLURL   string
+	Category string
+}
+
+func Parse(r io.Reader) (*Document, error) {
+	var document Document
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = charset.NewReaderLabel
+	if err := decoder.Decode(&document); err != nil {
+		return nil, errors.New("invalid OPML document: " + err.Error())
+	}
+	return &document, nil
+}
+
+// Entries flattens the outline tree. Outlines without xmlUrl are folders, and
+// their names are joined into the category of the feeds they contain.
+func (d *Document) Entries() []Entry {
+	var entries []Entry
+	var walk func(outlines []Outline, folders []string)
+	walk = func(outlines []Outline, folders []string) {
+		for _, outline := range outlines {
+			title := strings.TrimSpace(outline.Title)
+			if title == "" {
+				title = strings.TrimSpace(outline.Text)
+			}
+
+			if outline.XMLURL == "" {
+				if len(outline.Outlines) == 0 {
+					continue
+				}
+				walk(outline.Outlines, append(folders[:len(folders):len(folders)], title))
+				continue
+			}
+
+			entries = append(entries, Entry{
+				Title:    title,
+				XMLURL:   strings.TrimSpace(outline.XMLURL),
+				Category: strings.Join(folders, categorySeparator),
+			})
+		}
+	}
+	walk(d.Body.Outlines, nil)
+	return entries
+}
+
+// Export builds an OPML 2.0 document, grouping the feeds in one folder per category.
+func Export(title string, feeds []Feed) *Document {
+	document := &Document{
+		Version: "2.0",
+		Head: Head{
+			Title:       title,
+			DateCreated: time.Now().UTC().Format(time.RFC1123Z),
+		},
+	}
+
+	folders := make(map[string]int)
+	for _, feed := range feeds {
+		outline := Outline{
+			Text:   feed.Title,
+			Title:  feed.Title,
+			Type:   "rss",
+			XMLURL: feed.XMLURL,
+		}
+		if feed.Category == "" {
+			document.Body.Outlines = append(document.Body.Outlines, outline)
+			continue
+		}
+
+		index, exists := folders[feed.Category]
+		if !exists {
+			index = len(document.Body.Outlines)
+			folders[feed.Category] = index
+			document.Body.Outlines = append(document.Body.Outlines, Outline{Text: feed.Category, Title: feed.Category})
+		}
+		document.Body.Outlines[index].Outlines = append(document.Body.Outlines[index].Outlines, outline)
+	}
+	return document
+}
+
+func (d *Document) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(d); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,331 @@
+package readability
+
+import (
+	"bytes"
+	"errors"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// minContentLength is the shortest text accepted as the main content of a page.
+const minContentLength = 140
+
+var ErrNoContent = errors.New("no readable content found")
+
+var (
+	unlikelyNames = regexp.MustCompile(`(?i)ad-break|agegate|banner|breadcrumb|combx|comment|community|cookie|disqus|extra|footer|gdpr|header|legends|menu|modal|nav|newsletter|pager|pagination|popup|promo|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe`)
+	maybeNames    = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positiveNames = regexp.MustCompile(`(?i)article|blog|body|content|entry|h-entry|hentry|main|page|post|story|text`)
+	negativeNames = regexp.MustCompile(`(?i)-ad-|banner|combx|comment|com-|contact|foot|footnote|hidden|masthead|media|meta|outbrain|promo|related|scroll|share|shopping|shoutbox|sidebar|skyscraper|sponsor|tags|tool|widget`)
+)
+
+// removedElements never hold the main content of a page.
+var removedElements = map[atom.Atom]bool{
+	atom.Aside:    true,
+	atom.Button:   true,
+	atom.Footer:   true,
+	atom.Form:     true,
+	atom.Iframe:   true,
+	atom.Input:    true,
+	atom.Link:     true,
+	atom.Meta:     true,
+	atom.Nav:      true,
+	atom.Noscript: true,
+	atom.Object:   true,
+	atom.Script:   true,
+	atom.Select:   true,
+	atom.Style:    true,
+	atom.Svg:      true,
+	atom.Template: true,
+	atom.Textarea: true,
+}
+
+var blockElements = map[atom.Atom]bool{
+	atom.Article:    true,
+	atom.Blockquote: true,
+	atom.Div:        true,
+	atom.Dl:         true,
+	atom.Figure:     true,
+	atom.H1:         true,
+	atom.H2:         true,
+	atom.H3:         true,
+	atom.H4:         true,
+	atom.H5:         true,
+	atom.H6:         true,
+	atom.Ol:         true,
+	atom.P:          true,
+	atom.Pre:        true,
+	atom.Section:    true,
+	atom.Table:      true,
+	atom.Ul:         true,
+}
+
+// Extract returns the HTML of the main content of a page, found the way
+// readability tools do: paragraphs give points to their ancestors, weighted by
+// the class and id of the elements and by how much of their text is links, and
+// the best scored element is kept with its related siblings.
+func Extract(page io.Reader) (string, error) {
+	doc, err := html.Parse(page)
+	if err != nil {
+		return "", err
+	}
+	body := findElement(doc, atom.Body)
+	if body == nil {
+		return "", ErrNoContent
+	}
+	prune(body)
+
+	scores := scoreParagraphs(body)
+	top := topCandidate(scores)
+	if top == nil {
+		top = findElement(body, atom.Article)
+	}
+	if top == nil {
+		top = body
+	}
+
+	var buffer bytes.Buffer
+	for _, node := range withSiblings(top, scores) {
+		if err := html.Render(&buffer, node); err != nil {
+			return "", err
+		}
+	}
+
+	// Text within links does not count, so index pages listing other articles
+	// are not taken for content.
+	content := buffer.String()
+	text := float64(len(strings.TrimSpace(innerText(top))))
+	if text*(1-linkDensity(top)) < minContentLength {
+		return "", ErrNoContent
+	}
+	return content, nil
+}
+
+func findElement(node *html.Node, element atom.Atom) *html.Node {
+	if node.Type == html.ElementNode && node.DataAtom == element {
+		return node
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if found := findElement(child, element); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// prune removes the elements unlikely to be part of the content: scripts,
+// navigation, forms and those named like comments, menus or sidebars.
+func prune(node *html.Node) {
+	child := node.FirstChild
+	for child != nil {
+		next := child.NextSibling
+		switch {
+		case child.Type == html.CommentNode:
+			node.RemoveChild(child)
+		case child.Type != html.ElementNode:
+		case removedElements[child.DataAtom] || unlikely(child):
+			node.RemoveChild(child)
+		default:
+			prune(child)
+		}
+		child = next
+	}
+}
+
+func unlikely(node *html.Node) bool {
+	if node.DataAtom == atom.Article || node.DataAtom == atom.Main || node.DataAtom == atom.Body {
+		return false
+	}
+	names := className(node)
+	return names != "" && unlikelyNames.MatchString(names) && !maybeNames.MatchString(names)
+}
+
+func scoreParagraphs(body *html.Node) map[*html.Node]float64 {
+	scores := make(map[*html.Node]float64)
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			if isParagraph(child) {
+				scoreParagraph(child, scores)
+			}
+			walk(child)
+		}
+	}
+	walk(body)
+	return scores
+}
+
+func isParagraph(node *html.Node) bool {
+	switch node.DataAtom {
+	case atom.P, atom.Pre, atom.Td, atom.Blockquote:
+		return true
+	case atom.Div:
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type == html.ElementNode && blockElements[child.DataAtom] {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func scoreParagraph(node *html.Node, scores map[*html.Node]float64) {
+	text := strings.TrimSpace(innerText(node))
+	if len(text) < 25 {
+		return
+	}
+
+	score := 1 + float64(strings.Count(text, ","))
+	if bonus := float64(len(text) / 100); bonus < 3 {
+		score += bonus
+	} else {
+		score += 3
+	}
+
+	ancestor := node.Parent
+	for level := 0; level < 2 && ancestor != nil && ancestor.Type == html.ElementNode; level++ {
+		if _, found := scores[ancestor]; !found {
+			scores[ancestor] = initialScore(ancestor)
+		}
+		if level == 0 {
+			scores[ancestor] += score
+		} else {
+			scores[ancestor] += score / 2
+		}
+		ancestor = ancestor.Parent
+	}
+}
+
+func initialScore(node *html.Node) float64 {
+	score := classWeight(node)
+	switch node.DataAtom {
+	case atom.Article:
+		score += 10
+	case atom.Div, atom.Main, atom.Section:
+		score += 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score += 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li, atom.Form:
+		score -= 3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score -= 5
+	}
+	return score
+}
+
+func classWeight(node *html.Node) float64 {
+	var weight float64
+	for _, name := range []string{attr(node, "class"), attr(node, "id")} {
+		if name == "" {
+			continue
+		}
+		if negativeNames.MatchString(name) {
+			weight -= 25
+		}
+		if positiveNames.MatchString(name) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+func topCandidate(scores map[*html.Node]float64) *html.Node {
+	var top *html.Node
+	var topScore float64
+	for node, score := range scores {
+		score *= 1 - linkDensity(node)
+		scores[node] = score
+		if top == nil || score > topScore {
+			top, topScore = node, score
+		}
+	}
+	return top
+}
+
+// withSiblings adds to the top candidate the siblings that look like they
+// belong to the same content, such as paragraphs split across several blocks.
+func withSiblings(top *html.Node, scores map[*html.Node]float64) []*html.Node {
+	if top.Parent == nil || top.DataAtom == atom.Body {
+		return []*html.Node{top}
+	}
+
+	threshold := scores[top] * 0.2
+	if threshold < 10 {
+		threshold = 10
+	}
+
+	var nodes []*html.Node
+	for sibling := top.Parent.FirstChild; sibling != nil; sibling = sibling.NextSibling {
+		if sibling.Type != html.ElementNode {
+			continue
+		}
+		if sibling == top || scores[sibling] >= threshold || relatedParagraph(sibling) {
+			nodes = append(nodes, sibling)
+		}
+	}
+	return nodes
+}
+
+func relatedParagraph(node *html.Node) bool {
+	if node.DataAtom != atom.P {
+		return false
+	}
+	text := strings.TrimSpace(innerText(node))
+	density := linkDensity(node)
+	if len(text) > 80 {
+		return density < 0.25
+	}
+	return len(text) > 0 && density == 0 && strings.HasSuffix(text, ".")
+}
+
+func linkDensity(node *html.Node) float64 {
+	text := len(innerText(node))
+	if text == 0 {
+		return 0
+	}
+
+	links := 0
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type == html.ElementNode && child.DataAtom == atom.A {
+				links += len(innerText(child))
+				continue
+			}
+			walk(child)
+		}
+	}
+	walk(node)
+	return float64(links) / float64(text)
+}
+
+func innerText(node *html.Node) string {
+	if node.Type == html.TextNode {
+		return node.Data
+	}
+	var builder strings.Builder
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		builder.WriteString(innerText(child))
+	}
+	return builder.String()
+}
+
+func className(node *html.Node) string {
+	return strings.TrimSpace(attr(node, "class") + " " + attr(node, "id"))
+}
+
+func attr(node *html.Node, key string) string {
+	for _, attribute := range node.Attr {
+		if attribute.Key == key {
+			return attribute.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,75 @@
+package readability
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		fixture  string
+		contains []string
+		excludes []string
+		err      error
+	}{
+		{
+			fixture: "blog_post.html",
+			contains: []string{
+				"O plano de mobilidade urbana foi apresentado",
+				"reduzir o tempo de deslocamento",
+				"passará por audiências públicas",
+			},
+			excludes: []string{"Início", "Mais lidas", "Ótimo texto", "direitos reservados", "trackRead", "analytics"},
+		},
+		{
+			fixture: "news_story.html",
+			contains: []string{
+				"A chuva forte que atingiu a região",
+				"Equipes trabalharam durante toda a manhã",
+				"A prefeitura orienta os moradores",
+			},
+			excludes: []string{"Jornal", "Compartilhar", "Leia também"},
+		},
+		{
+			fixture: "link_index.html",
+			err:     ErrNoContent,
+		},
+		{
+			fixture: "short_page.html",
+			err:     ErrNoContent,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.fixture, func(t *testing.T) {
+			page, err := os.Open(filepath.Join("testdata", test.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer page.Close()
+
+			content, err := Extract(page)
+			if err != test.err {
+				t.Fatalf("Extract() error = %v, want %v", err, test.err)
+			}
+			for _, text := range test.contains {
+				if !strings.Contains(content, text) {
+					t.Errorf("content is missing %q:\n%s", text, content)
+				}
+			}
+			for _, text := range test.excludes {
+				if strings.Contains(content, text) {
+					t.Errorf("content keeps %q:\n%s", text, content)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractEmptyPage(t *testing.T) {
+	if _, err := Extract(strings.NewReader("")); err != ErrNoContent {
+		t.Errorf("Extract() error = %v, want %v", err, ErrNoContent)
+	}
+}
@@ -0,0 +1,354 @@
+package sanitize
+
+import (
+	"bytes"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// allowedElements maps the elements kept in sanitized content to the
+// attributes they may carry. Other elements are unwrapped, keeping their text.
+var allowedElements = map[atom.Atom][]string{
+	atom.A:          {"href", "title"},
+	atom.Abbr:       {"title"},
+	atom.B:          nil,
+	atom.Blockquote: {"cite"},
+	atom.Br:         nil,
+	atom.Caption:    nil,
+	atom.Cite:       nil,
+	atom.Code:       nil,
+	atom.Dd:         nil,
+	atom.Del:        {"cite", "datetime"},
+	atom.Div:        nil,
+	atom.Dl:         nil,
+	atom.Dt:         nil,
+	atom.Em:         nil,
+	atom.Figcaption: nil,
+	atom.Figure:     nil,
+	atom.H1:         nil,
+	atom.H2:         nil,
+	atom.H3:         nil,
+	atom.H4:         nil,
+	atom.H5:         nil,
+	atom.H6:         nil,
+	atom.Hr:         nil,
+	atom.I:          nil,
+	atom.Img:        {"src", "alt", "title", "width", "height"},
+	atom.Ins:        {"cite", "datetime"},
+	atom.Kbd:        nil,
+	atom.Li:         nil,
+	atom.Mark:       nil,
+	atom.Ol:         {"start"},
+	atom.P:          nil,
+	atom.Pre:        nil,
+	atom.Q:          {"cite"},
+	atom.S:          nil,
+	atom.Small:      nil,
+	atom.Span:       nil,
+	atom.Strong:     nil,
+	atom.Sub:        nil,
+	atom.Sup:        nil,
+	atom.Table:      nil,
+	atom.Tbody:      nil,
+	atom.Td:         {"colspan", "rowspan"},
+	atom.Tfoot:      nil,
+	atom.Th:         {"colspan", "rowspan", "scope"},
+	atom.Thead:      nil,
+	atom.Time:       {"datetime"},
+	atom.Tr:         nil,
+	atom.U:          nil,
+	atom.Ul:         nil,
+}
+
+// droppedElements are removed together with their content.
+var droppedElements = map[atom.Atom]bool{
+	atom.Applet:   true,
+	atom.Audio:    true,
+	atom.Base:     true,
+	atom.Button:   true,
+	atom.Canvas:   true,
+	atom.Embed:    true,
+	atom.Form:     true,
+	atom.Frame:    true,
+	atom.Frameset: true,
+	atom.Head:     true,
+	atom.Iframe:   true,
+	atom.Input:    true,
+	atom.Link:     true,
+	atom.Math:     true,
+	atom.Meta:     true,
+	atom.Noscript: true,
+	atom.Object:   true,
+	atom.Script:   true,
+	atom.Select:   true,
+	atom.Style:    true,
+	atom.Svg:      true,
+	atom.Template: true,
+	atom.Textarea: true,
+	atom.Title:    true,
+	atom.Video:    true,
+}
+
+var urlAttributes = map[string]bool{
+	"href": true,
+	"src":  true,
+	"cite": true,
+}
+
+// trackerHosts serve the tracking pixels and share buttons feeds append to
+// their items.
+var trackerHosts = []string{
+	"feeds.feedburner.com",
+	"feedproxy.google.com",
+	"pixel.wp.com",
+	"stats.wordpress.com",
+	"pixel.quantserve.com",
+	"www.google-analytics.com",
+	"ad.doubleclick.net",
+}
+
+// blockElements start a new line in the plain text version.
+var blockElements = map[atom.Atom]bool{
+	atom.Blockquote: true,
+	atom.Br:         true,
+	atom.Caption:    true,
+	atom.Dd:         true,
+	atom.Div:        true,
+	atom.Dl:         true,
+	atom.Dt:         true,
+	atom.Figcaption: true,
+	atom.Figure:     true,
+	atom.H1:         true,
+	atom.H2:         true,
+	atom.H3:         true,
+	atom.H4:         true,
+	atom.H5:         true,
+	atom.H6:         true,
+	atom.Hr:         true,
+	atom.Li:         true,
+	atom.Ol:         true,
+	atom.P:          true,
+	atom.Pre:        true,
+	atom.Table:      true,
+	atom.Tr:         true,
+	atom.Ul:         true,
+}
+
+// HTML keeps the allowlisted elements and attributes of an HTML fragment,
+// dropping scripts, embeds, event handlers, styles and tracking pixels, and
+// resolves relative URLs against base when it is not nil.
+func HTML(content string, base *url.URL) string {
+	if strings.TrimSpace(content) == "" {
+		return ""
+	}
+	nodes, err := parseFragment(content)
+	if err != nil {
+		return html.EscapeString(content)
+	}
+
+	var buffer bytes.Buffer
+	for _, node := range nodes {
+		for _, clean := range sanitizeNode(node, base) {
+			if err := html.Render(&buffer, clean); err != nil {
+				return ""
+			}
+		}
+	}
+	return strings.TrimSpace(buffer.String())
+}
+
+// Text returns the readable text of an HTML fragment, one line per block.
+func Text(content string) string {
+	if strings.TrimSpace(content) == "" {
+		return ""
+	}
+	nodes, err := parseFragment(content)
+	if err != nil {
+		return strings.TrimSpace(content)
+	}
+
+	var builder strings.Builder
+	for _, node := range nodes {
+		writeText(&builder, node)
+	}
+	return collapseLines(builder.String())
+}
+
+func parseFragment(content string) ([]*html.Node, error) {
+	context := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	return html.ParseFragment(strings.NewReader(content), context)
+}
+
+// sanitizeNode returns the nodes replacing node: itself cleaned, its cleaned
+// children when it is unwrapped, or nothing when it is dropped.
+func sanitizeNode(node *html.Node, base *url.URL) []*html.Node {
+	switch node.Type {
+	case html.TextNode:
+		return []*html.Node{{Type: html.TextNode, Data: node.Data}}
+	case html.ElementNode:
+	default:
+		return nil
+	}
+
+	if droppedElements[node.DataAtom] {
+		return nil
+	}
+
+	var children []*html.Node
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		children = append(children, sanitizeNode(child, base)...)
+	}
+
+	allowed, ok := allowedElements[node.DataAtom]
+	if !ok {
+		return children
+	}
+
+	clean := &html.Node{Type: html.ElementNode, Data: node.Data, DataAtom: node.DataAtom}
+	for _, attr := range node.Attr {
+		if attr.Namespace != "" || !contains(allowed, attr.Key) {
+			continue
+		}
+		if urlAttributes[attr.Key] {
+			value, ok := safeURL(attr.Val, base, attr.Key == "href")
+			if !ok {
+				continue
+			}
+			attr.Val = value
+		}
+		clean.Attr = append(clean.Attr, html.Attribute{Key: attr.Key, Val: attr.Val})
+	}
+
+	switch node.DataAtom {
+	case atom.Img:
+		if !hasAttr(clean, "src") || isTrackingPixel(clean) {
+			return nil
+		}
+	case atom.A:
+		if hasAttr(clean, "href") {
+			clean.Attr = append(clean.Attr, html.Attribute{Key: "rel", Val: "nofollow noopener noreferrer"})
+		}
+	}
+
+	for _, child := range children {
+		clean.AppendChild(child)
+	}
+	return []*html.Node{clean}
+}
+
+// safeURL resolves value against base and keeps it only for web and, in links,
+// mail addresses. Relative URLs stay relative when there is no base.
+func safeURL(value string, base *url.URL, link bool) (string, bool) {
+	ref, err := url.Parse(strings.TrimSpace(value))
+	if err != nil {
+		return "", false
+	}
+	if base != nil {
+		ref = base.ResolveReference(ref)
+	}
+
+	switch strings.ToLower(ref.Scheme) {
+	case "http", "https", "":
+		return ref.String(), true
+	case "mailto":
+		return ref.String(), link
+	default:
+		return "", false
+	}
+}
+
+func isTrackingPixel(img *html.Node) bool {
+	for _, key := range []string{"width", "height"} {
+		if size, err := strconv.Atoi(strings.TrimSuffix(attr(img, key), "px")); err == nil && size <= 1 {
+			return true
+		}
+	}
+
+	src, err := url.Parse(attr(img, "src"))
+	if err != nil {
+		return true
+	}
+	host := strings.ToLower(src.Hostname())
+	for _, tracker := range trackerHosts {
+		if host == tracker {
+			return true
+		}
+	}
+	return false
+}
+
+func writeText(builder *strings.Builder, node *html.Node) {
+	switch node.Type {
+	case html.TextNode:
+		builder.WriteString(node.Data)
+		return
+	case html.ElementNode:
+		if droppedElements[node.DataAtom] {
+			return
+		}
+	default:
+		return
+	}
+
+	block := blockElements[node.DataAtom]
+	if block {
+		builder.WriteString("\n")
+	}
+	if node.DataAtom == atom.Li {
+		builder.WriteString("- ")
+	}
+	if node.DataAtom == atom.Td || node.DataAtom == atom.Th {
+		builder.WriteString(" ")
+	}
+	if node.DataAtom == atom.Img {
+		builder.WriteString(attr(node, "alt"))
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		writeText(builder, child)
+	}
+	if block {
+		builder.WriteString("\n")
+	}
+}
+
+// collapseLines squeezes the spaces of each line and drops the empty ones.
+func collapseLines(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func attr(node *html.Node, key string) string {
+	for _, attribute := range node.Attr {
+		if attribute.Key == key {
+			return attribute.Val
+		}
+	}
+	return ""
+}
+
+func hasAttr(node *html.Node, key string) bool {
+	for _, attribute := range node.Attr {
+		if attribute.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package sanitize
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestHTML(t *testing.T) {
+	base, _ := url.Parse("https://news.example.com/2024/06/article.html")
+
+	tests := []struct {
+		name    string
+		content string
+		base    *url.URL
+		want    string
+	}{
+		{
+			name:    "drops scripts with their content",
+			content: `<p>Hello<script>alert(1)</script></p><script src="https://evil.example/x.js"></script>`,
+			want:    `<p>Hello</p>`,
+		},
+		{
+			name:    "drops embeds, forms and styles",
+			content: `<iframe src="https://evil.example"></iframe><form><input name="q"></form><style>p{color:red}</style><p>Text</p>`,
+			want:    `<p>Text</p>`,
+		},
+		{
+			name:    "drops event handlers",
+			content: `<p onclick="steal()" onmouseover="steal()">Click</p><img src="https://cdn.example.com/a.png" onerror="steal()">`,
+			want:    `<p>Click</p><img src="https://cdn.example.com/a.png"/>`,
+		},
+		{
+			name:    "unwraps unknown elements keeping their text",
+			content: `<section><custom-tag>Inside</custom-tag></section>`,
+			want:    `Inside`,
+		},
+		{
+			name:    "drops javascript links",
+			content: `<a href="javascript:alert(1)">a</a><a href=" JavaScript:alert(1)">b</a><a href="&#106;avascript:alert(1)">c</a>`,
+			want:    `<a>a</a><a>b</a><a>c</a>`,
+		},
+		{
+			name:    "drops data and vbscript urls",
+			content: `<a href="data:text/html;base64,PHNjcmlwdD4=">a</a><img src="data:image/png;base64,iVBORw0KGgo="><a href="vbscript:msgbox(1)">b</a>`,
+			want:    `<a>a</a><a>b</a>`,
+		},
+		{
+			name:    "drops urls with control characters",
+			content: "<a href=\"java\tscript:alert(1)\">a</a>",
+			want:    `<a>a</a>`,
+		},
+		{
+			name:    "keeps web and mail links",
+			content: `<a href="https://example.com/a" title="A">web</a><a href="mailto:editor@example.com">mail</a><img src="mailto:editor@example.com">`,
+			want:    `<a href="https://example.com/a" title="A" rel="nofollow noopener noreferrer">web</a><a href="mailto:editor@example.com" rel="nofollow noopener noreferrer">mail</a>`,
+		},
+		{
+			name:    "drops style and class attributes",
+			content: `<p style="position:fixed;background:url(javascript:alert(1))" class="promo" id="x">Text</p>`,
+			want:    `<p>Text</p>`,
+		},
+		{
+			name:    "drops one pixel images",
+			content: `<img src="https://example.com/p.gif" width="1" height="1"><img src="https://example.com/q.gif" width="1px"><img src="https://example.com/r.gif" height="0">`,
+			want:    ``,
+		},
+		{
+			name:    "drops images of tracker hosts",
+			content: `<img src="https://feeds.feedburner.com/~r/blog/~4/abc" width="300"><img src="http://pixel.wp.com/g.gif">`,
+			want:    ``,
+		},
+		{
+			name:    "keeps regular images",
+			content: `<img src="https://cdn.example.com/photo.jpg" alt="Photo" width="640" height="480">`,
+			want:    `<img src="https://cdn.example.com/photo.jpg" alt="Photo" width="640" height="480"/>`,
+		},
+		{
+			name:    "resolves relative urls against the base",
+			content: `<a href="/about">About</a><a href="next.html">Next</a><img src="../img/a.png" alt=""><blockquote cite="//quotes.example.com/1">Q</blockquote>`,
+			base:    base,
+			want:    `<a href="https://news.example.com/about" rel="nofollow noopener noreferrer">About</a><a href="https://news.example.com/2024/06/next.html" rel="nofollow noopener noreferrer">Next</a><img src="https://news.example.com/2024/img/a.png" alt=""/><blockquote cite="https://quotes.example.com/1">Q</blockquote>`,
+		},
+		{
+			name:    "keeps relative urls without a base",
+			content: `<a href="/about">About</a>`,
+			want:    `<a href="/about" rel="nofollow noopener noreferrer">About</a>`,
+		},
+		{
+			name:    "resolving does not turn javascript urls into links",
+			content: `<a href="javascript:alert(1)">a</a>`,
+			base:    base,
+			want:    `<a>a</a>`,
+		},
+		{
+			name:    "escapes text",
+			content: `1 &lt; 2 &amp; <b>bold</b>`,
+			want:    `1 &lt; 2 &amp; <b>bold</b>`,
+		},
+		{
+			name:    "empty content",
+			content: "  ",
+			want:    "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := HTML(test.content, test.base); got != test.want {
+				t.Errorf("HTML() =\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "one line per block",
+			content: `<h1>Title</h1><p>First   paragraph</p><p>Second<br>line</p>`,
+			want:    "Title\nFirst paragraph\nSecond\nline",
+		},
+		{
+			name:    "lists and images",
+			content: `<ul><li>One</li><li>Two</li></ul><img src="a.png" alt="A chart">`,
+			want:    "- One\n- Two\nA chart",
+		},
+		{
+			name:    "drops scripts and styles",
+			content: `<p>Text<script>var secret = 1;</script><style>p{}</style></p>`,
+			want:    "Text",
+		},
+		{
+			name:    "decodes entities",
+			content: `Tom &amp; Jerry &eacute;`,
+			want:    "Tom & Jerry é",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Text(test.content); got != test.want {
+				t.Errorf("Text() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package scheduler
+
+import (
+	"container/heap"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"testing"
+	"time"
+)
+
+func TestQueueOrdersByDueTime(t *testing.T) {
+	now := time.Now()
+	var queue jobQueue
+	jobs := map[int]*job{}
+	for id, offset := range map[int]time.Duration{1: 3 * time.Hour, 2: time.Minute, 3: time.Hour, 4: -time.Minute, 5: 2 * time.Hour} {
+		jobs[id] = &job{source: models.Source{ID: id}, next: now.Add(offset)}
+		heap.Push(&queue, jobs[id])
+	}
+
+	// Moving a job updates its place in the queue.
+	jobs[1].next = now.Add(-time.Hour)
+	heap.Fix(&queue, jobs[1].index)
+
+	want := []int{1, 4, 2, 3, 5}
+	for _, id := range want {
+		j := heap.Pop(&queue).(*job)
+		if j.source.ID != id {
+			t.Fatalf("Pop() = source %d, want source %d", j.source.ID, id)
+		}
+		if j.index != -1 {
+			t.Errorf("popped job keeps index %d", j.index)
+		}
+	}
+	if queue.Len() != 0 {
+		t.Errorf("queue keeps %d jobs", queue.Len())
+	}
+}
+
+func TestQueueRemove(t *testing.T) {
+	now := time.Now()
+	var queue jobQueue
+	first := &job{source: models.Source{ID: 1}, next: now}
+	second := &job{source: models.Source{ID: 2}, next: now.Add(time.Minute)}
+	third := &job{source: models.Source{ID: 3}, next: now.Add(time.Hour)}
+	for _, j := range []*job{third, first, second} {
+		heap.Push(&queue, j)
+	}
+
+	heap.Remove(&queue, second.index)
+	for _, want := range []*job{first, third} {
+		if j := heap.Pop(&queue).(*job); j != want {
+			t.Fatalf("Pop() = source %d, want source %d", j.source.ID, want.source.ID)
+		}
+	}
+}
@@ -0,0 +1,407 @@
+package scheduler
+
+import (
+	"container/heap"
+	"github.com/google/logger"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/email"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/fetcher"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWorkers          = 4
+	defaultFailureThreshold = 5
+	defaultBackoffBase      = 5 * time.Minute
+	defaultBackoffMax       = 24 * time.Hour
+	idleWait                = time.Hour
+	defaultInterval         = time.Hour
+	maxLastErrorLength      = 1024
+)
+
+type Options struct {
+	Workers          int
+	FailureThreshold int
+	BackoffBase      time.Duration
+	BackoffMax       time.Duration
+}
+
+type Scheduler struct {
+	fetcher          interfaces.FetcherInterface
+	push             interfaces.WebSubSubscriberInterface
+	sourceRepository interfaces.SourceRepositoryInterface
+	userRepository   interfaces.UserRepositoryInterface
+	emailService     interfaces.EmailService
+	options          Options
+	mu               sync.Mutex
+	jobs             map[int]*job
+	queue            jobQueue
+	work             chan *job
+	wake             chan struct{}
+	quit             chan struct{}
+	wg               sync.WaitGroup
+	started          bool
+}
+
+func NewScheduler(fetcher interfaces.FetcherInterface, push interfaces.WebSubSubscriberInterface, sourceRepository interfaces.SourceRepositoryInterface, userRepository interfaces.UserRepositoryInterface, emailService interfaces.EmailService, options Options) *Scheduler {
+	if options.Workers <= 0 {
+		options.Workers = defaultWorkers
+	}
+	if options.FailureThreshold <= 0 {
+		options.FailureThreshold = defaultFailureThreshold
+	}
+	if options.BackoffBase <= 0 {
+		options.BackoffBase = defaultBackoffBase
+	}
+	if options.BackoffMax < options.BackoffBase {
+		options.BackoffMax = defaultBackoffMax
+	}
+	return &Scheduler{
+		fetcher:          fetcher,
+		push:             push,
+		sourceRepository: sourceRepository,
+		userRepository:   userRepository,
+		emailService:     emailService,
+		options:          options,
+		jobs:             make(map[int]*job),
+		work:             make(chan *job),
+		wake:             make(chan struct{}, 1),
+		quit:             make(chan struct{}),
+	}
+}
+
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+
+	s.wg.Add(s.options.Workers + 1)
+	go s.dispatch()
+	for i := 0; i < s.options.Workers; i++ {
+		go s.worker()
+	}
+	logger.Info("Scheduler started with " + strconv.Itoa(s.options.Workers) + " workers")
+}
+
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	if !s.started {
+		s.mu.Unlock()
+		return
+	}
+	s.started = false
+	s.mu.Unlock()
+
+	close(s.quit)
+	s.wg.Wait()
+	logger.Info("Scheduler stopped")
+}
+
+// Schedule adds the source to the queue to be fetched right away. Sources that
+// are already scheduled keep their current due time.
+func (s *Scheduler) Schedule(source models.Source) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if source.Disabled {
+		logger.Info("Source disabled, not scheduling: " + strconv.Itoa(source.ID))
+		return
+	}
+	if j, exists := s.jobs[source.ID]; exists {
+		j.source = source
+		return
+	}
+	s.add(source, time.Now())
+}
+
+// Reschedule recomputes the due time of the source after its settings changed.
+// A new URL is fetched right away.
+func (s *Scheduler) Reschedule(source models.Source) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, exists := s.jobs[source.ID]
+	if source.Disabled {
+		if exists {
+			s.remove(j)
+		}
+		return
+	}
+	if !exists {
+		s.add(source, time.Now())
+		return
+	}
+
+	urlChanged := j.source.Url != source.Url
+	j.source = source
+	if urlChanged {
+		j.failures = 0
+	}
+	if j.running {
+		j.rerun = j.rerun || urlChanged
+		return
+	}
+
+	if urlChanged || j.lastRun.IsZero() {
+		j.next = time.Now()
+	} else {
+		j.next = j.lastRun.Add(interval(source))
+	}
+	heap.Fix(&s.queue, j.index)
+	s.notify()
+}
+
+// FetchNow moves the source to the front of the queue, fetching it again
+// after the current run when it is already being fetched.
+func (s *Scheduler) FetchNow(source models.Source) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if source.Disabled {
+		logger.Info("Source disabled, not fetching: " + strconv.Itoa(source.ID))
+		return
+	}
+	j, exists := s.jobs[source.ID]
+	if !exists {
+		s.add(source, time.Now())
+		return
+	}
+
+	j.source = source
+	if j.running {
+		j.rerun = true
+		return
+	}
+	j.next = time.Now()
+	heap.Fix(&s.queue, j.index)
+	s.notify()
+}
+
+func (s *Scheduler) Cancel(sourceID int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, exists := s.jobs[sourceID]
+	if !exists {
+		return
+	}
+	s.remove(j)
+	logger.Info("Source unscheduled: " + strconv.Itoa(sourceID))
+}
+
+func (s *Scheduler) add(source models.Source, next time.Time) {
+	j := &job{source: source, next: next, failures: source.ConsecutiveFailures}
+	s.jobs[source.ID] = j
+	heap.Push(&s.queue, j)
+	s.notify()
+	logger.Info("Source scheduled: " + strconv.Itoa(source.ID))
+}
+
+func (s *Scheduler) remove(j *job) {
+	delete(s.jobs, j.source.ID)
+	if !j.running {
+		heap.Remove(&s.queue, j.index)
+	}
+}
+
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Scheduler) dispatch() {
+	defer s.wg.Done()
+	for {
+		s.mu.Lock()
+		var due []*job
+		now := time.Now()
+		for s.queue.Len() > 0 && !s.queue[0].next.After(now) {
+			j := heap.Pop(&s.queue).(*job)
+			j.running = true
+			due = append(due, j)
+		}
+		wait := idleWait
+		if s.queue.Len() > 0 {
+			wait = s.queue[0].next.Sub(now)
+		}
+		s.mu.Unlock()
+
+		for _, j := range due {
+			select {
+			case s.work <- j:
+			case <-s.quit:
+				return
+			}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-s.wake:
+			timer.Stop()
+		case <-s.quit:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+func (s *Scheduler) worker() {
+	defer s.wg.Done()
+	for {
+		select {
+		case j := <-s.work:
+			s.run(j)
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+func (s *Scheduler) run(j *job) {
+	s.mu.Lock()
+	source := j.source
+	previousFailures := j.failures
+	s.mu.Unlock()
+
+	if s.push != nil && s.push.IsActive(source.ID) {
+		logger.Info("Source updated by WebSub, skipping poll: " + strconv.Itoa(source.ID))
+		s.complete(j, source.FetchStatus, nil)
+		return
+	}
+
+	_, err := s.fetcher.FetchSource(source)
+	if err != nil {
+		logger.Errorf("Failed to fetch source %d: %v", source.ID, err)
+	}
+
+	status := fetcher.ErrorKind(err)
+	failures, disabled := s.complete(j, status, err)
+	if err == nil && previousFailures == 0 && source.FetchStatus == status {
+		return
+	}
+	s.recordState(source, failures, disabled, status, err)
+}
+
+func (s *Scheduler) complete(j *job, status string, err error) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	j.running = false
+	j.lastRun = now
+	j.source.FetchStatus = status
+
+	// A host asking to slow down is not a failure of the source: it is only
+	// polled again once the host allows it.
+	retryAt, rateLimited := fetcher.RetryAfter(err)
+	switch {
+	case rateLimited:
+	case err != nil:
+		j.failures++
+	default:
+		j.failures = 0
+	}
+
+	if current, exists := s.jobs[j.source.ID]; !exists || current != j {
+		return j.failures, false
+	}
+
+	if j.failures >= s.options.FailureThreshold {
+		delete(s.jobs, j.source.ID)
+		return j.failures, true
+	}
+
+	switch {
+	case rateLimited:
+		j.rerun = false
+		j.next = retryAt
+		if j.next.Before(now) {
+			j.next = now
+		}
+	case j.rerun:
+		j.rerun = false
+		j.next = now
+	case err != nil:
+		j.next = now.Add(s.backoff(j.failures))
+	default:
+		j.next = now.Add(interval(j.source))
+	}
+	heap.Push(&s.queue, j)
+	s.notify()
+	return j.failures, false
+}
+
+func (s *Scheduler) recordState(source models.Source, failures int, disabled bool, status string, fetchErr error) {
+	var lastError string
+	if fetchErr != nil {
+		lastError = fetchErr.Error()
+		if len(lastError) > maxLastErrorLength {
+			lastError = lastError[:maxLastErrorLength]
+		}
+	}
+
+	err := s.sourceRepository.UpdateFetchState(source.ID, failures, disabled, status, lastError)
+	if err != nil {
+		logger.Errorf("Failed to update fetch state of source %d: %v", source.ID, err)
+	}
+
+	if disabled {
+		logger.Info("Source disabled after " + strconv.Itoa(failures) + " consecutive failures: " + strconv.Itoa(source.ID))
+		s.notifyDisabled(source, failures, lastError)
+	}
+}
+
+func (s *Scheduler) notifyDisabled(source models.Source, failures int, lastError string) {
+	user, err := s.userRepository.FindById(source.UserID)
+	if err != nil {
+		logger.Errorf("Failed to find owner of source %d: %v", source.ID, err)
+		return
+	}
+
+	sourceID := strconv.Itoa(source.ID)
+	message := email.Message{
+		ToEmail: user.Email,
+		Subject: "Smart News Fetcher - Source paused",
+		PlainTextContent: "Your source " + source.Name + " (" + source.Url + ") was paused after " + strconv.Itoa(failures) +
+			" consecutive failed fetches.\nLast error: " + lastError +
+			"\n\nOnce the feed is reachable again, resume it with POST /sources/" + sourceID + "/resume.",
+		HtmlContent: "<p>Your source <b>" + source.Name + "</b> (<a>" + source.Url + "</a>) was paused after " + strconv.Itoa(failures) +
+			" consecutive failed fetches.</p><p>Last error: " + lastError + "</p>" +
+			"<p>Once the feed is reachable again, resume it with <code>POST /sources/" + sourceID + "/resume</code>.</p>",
+	}
+
+	err = s.emailService.Send(message)
+	if err != nil {
+		logger.Errorf("Failed to send email: %v", err)
+	}
+}
+
+func (s *Scheduler) backoff(failures int) time.Duration {
+	delay := s.options.BackoffBase
+	for i := 1; i < failures && delay < s.options.BackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > s.options.BackoffMax {
+		delay = s.options.BackoffMax
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func interval(source models.Source) time.Duration {
+	if source.UpdateInterval <= 0 {
+		return defaultInterval
+	}
+	return time.Duration(source.UpdateInterval) * time.Hour
+}
@@ -0,0 +1,251 @@
+package scheduler
+
+import (
+	"container/heap"
+	"errors"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/fetcher"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type stubFetcher struct {
+	interfaces.FetcherInterface
+	onFetch func(source models.Source)
+	err     error
+}
+
+func (f *stubFetcher) FetchSource(source models.Source) (models.FetchStats, error) {
+	if f.onFetch != nil {
+		f.onFetch(source)
+	}
+	return models.FetchStats{}, f.err
+}
+
+type stubSourceRepository struct {
+	interfaces.SourceRepositoryInterface
+	states map[int]string
+}
+
+func (r *stubSourceRepository) UpdateFetchState(id int, failures int, disabled bool, status string, lastError string) error {
+	r.states[id] = status
+	return nil
+}
+
+func newTestScheduler(f interfaces.FetcherInterface) *Scheduler {
+	repository := &stubSourceRepository{states: map[int]string{}}
+	return NewScheduler(f, nil, repository, nil, nil, Options{BackoffBase: time.Minute, BackoffMax: time.Hour})
+}
+
+// take hands the first job of the queue to the caller the way dispatch hands
+// it to a worker.
+func take(s *Scheduler) *job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j := heap.Pop(&s.queue).(*job)
+	j.running = true
+	return j
+}
+
+func delay(s *Scheduler, id int, next time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j := s.jobs[id]
+	j.next = next
+	heap.Fix(&s.queue, j.index)
+}
+
+func TestScheduleKeepsDueTime(t *testing.T) {
+	s := newTestScheduler(&stubFetcher{})
+	s.Schedule(models.Source{ID: 1, Name: "Old name"})
+	next := time.Now().Add(time.Hour)
+	delay(s, 1, next)
+
+	s.Schedule(models.Source{ID: 1, Name: "New name"})
+	if len(s.jobs) != 1 || s.queue.Len() != 1 {
+		t.Fatalf("scheduled %d jobs with %d queued, want 1 and 1", len(s.jobs), s.queue.Len())
+	}
+	j := s.jobs[1]
+	if !j.next.Equal(next) {
+		t.Errorf("next = %s, want the due time kept at %s", j.next, next)
+	}
+	if j.source.Name != "New name" {
+		t.Errorf("source name = %q, want the source updated", j.source.Name)
+	}
+
+	s.Schedule(models.Source{ID: 2, Disabled: true})
+	if _, exists := s.jobs[2]; exists {
+		t.Errorf("disabled source was scheduled")
+	}
+}
+
+func TestReschedule(t *testing.T) {
+	s := newTestScheduler(&stubFetcher{})
+	source := models.Source{ID: 1, Url: "https://example.com/feed", UpdateInterval: 1}
+	s.Schedule(source)
+	j := s.jobs[1]
+	lastRun := time.Now().Add(-30 * time.Minute)
+	j.lastRun = lastRun
+	j.failures = 2
+
+	source.UpdateInterval = 3
+	s.Reschedule(source)
+	if want := lastRun.Add(3 * time.Hour); !j.next.Equal(want) {
+		t.Errorf("next = %s after the interval changed, want %s", j.next, want)
+	}
+	if j.failures != 2 {
+		t.Errorf("failures = %d after the interval changed, want 2", j.failures)
+	}
+
+	source.Url = "https://example.com/other"
+	s.Reschedule(source)
+	if j.next.After(time.Now()) {
+		t.Errorf("next = %s after the URL changed, want now", j.next)
+	}
+	if j.failures != 0 {
+		t.Errorf("failures = %d after the URL changed, want 0", j.failures)
+	}
+	if len(s.jobs) != 1 || s.queue.Len() != 1 {
+		t.Errorf("scheduled %d jobs with %d queued, want 1 and 1", len(s.jobs), s.queue.Len())
+	}
+
+	running := take(s)
+	source.Url = "https://example.com/feed"
+	s.Reschedule(source)
+	if !running.rerun {
+		t.Errorf("running job not marked to run again after the URL changed")
+	}
+	if s.queue.Len() != 0 {
+		t.Errorf("running job was queued again")
+	}
+
+	s.complete(running, fetcher.FetchStatusOK, nil)
+	source.Disabled = true
+	s.Reschedule(source)
+	if len(s.jobs) != 0 || s.queue.Len() != 0 {
+		t.Errorf("disabled source keeps %d jobs with %d queued", len(s.jobs), s.queue.Len())
+	}
+}
+
+func TestCancelWhileRunning(t *testing.T) {
+	f := &stubFetcher{}
+	s := newTestScheduler(f)
+	f.onFetch = func(source models.Source) {
+		s.Cancel(source.ID)
+	}
+	s.Schedule(models.Source{ID: 1})
+
+	s.run(take(s))
+	if len(s.jobs) != 0 || s.queue.Len() != 0 {
+		t.Errorf("canceled source keeps %d jobs with %d queued", len(s.jobs), s.queue.Len())
+	}
+
+	// A source scheduled again while its old job runs keeps only the new job.
+	s.Schedule(models.Source{ID: 2})
+	old := take(s)
+	s.Cancel(2)
+	s.Schedule(models.Source{ID: 2})
+	s.complete(old, fetcher.FetchStatusOK, nil)
+	if s.queue.Len() != 1 || s.jobs[2] == old {
+		t.Errorf("old job of a rescheduled source was queued again")
+	}
+}
+
+func TestFetchNowPreemptsQueuedJob(t *testing.T) {
+	s := newTestScheduler(&stubFetcher{})
+	s.Schedule(models.Source{ID: 1})
+	s.Schedule(models.Source{ID: 2})
+	delay(s, 1, time.Now().Add(time.Hour))
+	delay(s, 2, time.Now().Add(time.Minute))
+
+	s.FetchNow(models.Source{ID: 1})
+	if first := s.queue[0]; first.source.ID != 1 || first.next.After(time.Now()) {
+		t.Errorf("queue starts with source %d due at %s, want source 1 due now", first.source.ID, first.next)
+	}
+	if s.queue.Len() != 2 {
+		t.Errorf("queued %d jobs, want 2", s.queue.Len())
+	}
+
+	running := take(s)
+	s.FetchNow(models.Source{ID: 1})
+	if !running.rerun || s.queue.Len() != 1 {
+		t.Fatalf("running job not marked to run again")
+	}
+	s.complete(running, fetcher.FetchStatusOK, nil)
+	if running.next.After(time.Now()) || s.queue[0] != running {
+		t.Errorf("job fetched while running was not queued to run again right away")
+	}
+}
+
+func TestCompleteBacksOffFailures(t *testing.T) {
+	s := newTestScheduler(&stubFetcher{})
+	s.Schedule(models.Source{ID: 1})
+
+	for failures := 1; failures < s.options.FailureThreshold; failures++ {
+		j := take(s)
+		before := time.Now()
+		count, disabled := s.complete(j, fetcher.ErrorKindNetwork, errors.New("connection refused"))
+		if count != failures || disabled {
+			t.Fatalf("complete() = %d, %t, want %d, false", count, disabled, failures)
+		}
+		if j.next.Before(before) {
+			t.Errorf("failed job due at %s, want a backoff", j.next)
+		}
+	}
+
+	count, disabled := s.complete(take(s), fetcher.ErrorKindNetwork, errors.New("connection refused"))
+	if count != s.options.FailureThreshold || !disabled {
+		t.Errorf("complete() = %d, %t, want %d, true", count, disabled, s.options.FailureThreshold)
+	}
+	if len(s.jobs) != 0 || s.queue.Len() != 0 {
+		t.Errorf("disabled source keeps %d jobs with %d queued", len(s.jobs), s.queue.Len())
+	}
+}
+
+func TestCompleteWaitsForRateLimitedHost(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	f, err := fetcher.NewFetcher(nil, nil, nil, nil, fetcher.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, fetchErr := f.ParseFeed(server.URL)
+	retryAt, rateLimited := fetcher.RetryAfter(fetchErr)
+	if !rateLimited {
+		t.Fatalf("ParseFeed() error = %v, want a rate limited error", fetchErr)
+	}
+
+	s := newTestScheduler(&stubFetcher{})
+	s.Schedule(models.Source{ID: 1})
+	j := take(s)
+	j.failures = 2
+	count, disabled := s.complete(j, fetcher.ErrorKind(fetchErr), fetchErr)
+	if count != 2 || disabled {
+		t.Errorf("complete() = %d, %t, want the failures kept at 2", count, disabled)
+	}
+	if !j.next.Equal(retryAt) {
+		t.Errorf("next = %s, want the Retry-After deadline %s", j.next, retryAt)
+	}
+}
+
+func TestBackoffIsCapped(t *testing.T) {
+	s := newTestScheduler(&stubFetcher{})
+	for failures := 1; failures <= 64; failures++ {
+		limit := s.options.BackoffMax
+		if failures < 7 {
+			limit = s.options.BackoffBase << (failures - 1)
+		}
+		for i := 0; i < 20; i++ {
+			if got := s.backoff(failures); got < limit/2 || got > limit {
+				t.Fatalf("backoff(%d) = %s, want between %s and %s", failures, got, limit/2, limit)
+			}
+		}
+	}
+}
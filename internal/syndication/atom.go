@@ -0,0 +1,104 @@
+package syndication
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// entryIDPrefix turns article ids into the IRIs required by Atom.
+const entryIDPrefix = "urn:smart-news-fetcher:article:"
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"feed"`
+	XMLNS    string      `xml:"xmlns,attr"`
+	ID       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr,omitempty"`
+	Value string `xml:",chardata"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Links     []atomLink  `xml:"link"`
+	Published string      `xml:"published,omitempty"`
+	Updated   string      `xml:"updated"`
+	Author    *atomAuthor `xml:"author,omitempty"`
+	Summary   *atomText   `xml:"summary,omitempty"`
+	Content   *atomText   `xml:"content,omitempty"`
+}
+
+func newAtom(feed Feed) atomFeed {
+	updated := feed.updated()
+	document := atomFeed{
+		XMLNS:    atomNamespace,
+		ID:       feed.SelfURL,
+		Title:    feed.Title,
+		Subtitle: feed.Description,
+		Updated:  updated.Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: feed.SelfURL, Rel: "self", Type: "application/atom+xml"},
+		},
+	}
+	if feed.FirstURL != "" {
+		document.ID = feed.FirstURL
+	}
+	if feed.HomeURL != "" {
+		document.Links = append(document.Links, atomLink{Href: feed.HomeURL, Rel: "alternate"})
+	}
+	if feed.FirstURL != "" {
+		document.Links = append(document.Links, atomLink{Href: feed.FirstURL, Rel: "first"})
+	}
+	if feed.PrevURL != "" {
+		document.Links = append(document.Links, atomLink{Href: feed.PrevURL, Rel: "previous"})
+	}
+	if feed.NextURL != "" {
+		document.Links = append(document.Links, atomLink{Href: feed.NextURL, Rel: "next"})
+	}
+
+	for _, item := range feed.Items {
+		entryUpdated := updated
+		if !item.Published.IsZero() {
+			entryUpdated = item.Published.UTC()
+		}
+		entry := atomEntry{
+			ID:      entryIDPrefix + item.ID,
+			Title:   item.Title,
+			Updated: entryUpdated.Format(time.RFC3339),
+		}
+		if !item.Published.IsZero() {
+			entry.Published = entryUpdated.Format(time.RFC3339)
+		}
+		if item.Link != "" {
+			entry.Links = []atomLink{{Href: item.Link, Rel: "alternate"}}
+		}
+		if item.Author != "" {
+			entry.Author = &atomAuthor{Name: item.Author}
+		}
+		if item.Description != "" {
+			entry.Summary = &atomText{Type: "html", Value: item.Description}
+		}
+		if item.Content != "" {
+			entry.Content = &atomText{Type: "html", Value: item.Content}
+		}
+		document.Entries = append(document.Entries, entry)
+	}
+	return document
+}
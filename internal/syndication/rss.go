@@ -0,0 +1,95 @@
+package syndication
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+const atomNamespace = "http://www.w3.org/2005/Atom"
+
+type rss struct {
+	XMLName   xml.Name   `xml:"rss"`
+	Version   string     `xml:"version,attr"`
+	AtomNS    string     `xml:"xmlns:atom,attr"`
+	DublinNS  string     `xml:"xmlns:dc,attr"`
+	ContentNS string     `xml:"xmlns:content,attr"`
+	Channel   rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string     `xml:"title"`
+	Link          string     `xml:"link"`
+	Description   string     `xml:"description"`
+	LastBuildDate string     `xml:"lastBuildDate"`
+	Links         []rssLink  `xml:"atom:link"`
+	Items         []rssEntry `xml:"item"`
+}
+
+type rssLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEntry struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link,omitempty"`
+	Description string  `xml:"description,omitempty"`
+	Content     *cdata  `xml:"content:encoded,omitempty"`
+	Creator     string  `xml:"dc:creator,omitempty"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate,omitempty"`
+}
+
+type cdata struct {
+	Value string `xml:",cdata"`
+}
+
+func newRSS(feed Feed) rss {
+	channel := rssChannel{
+		Title:         feed.Title,
+		Link:          feed.HomeURL,
+		Description:   feed.Description,
+		LastBuildDate: feed.updated().Format(time.RFC1123Z),
+		Links:         []rssLink{{Href: feed.SelfURL, Rel: "self", Type: "application/rss+xml"}},
+	}
+	if feed.FirstURL != "" {
+		channel.Links = append(channel.Links, rssLink{Href: feed.FirstURL, Rel: "first"})
+	}
+	if feed.PrevURL != "" {
+		channel.Links = append(channel.Links, rssLink{Href: feed.PrevURL, Rel: "previous"})
+	}
+	if feed.NextURL != "" {
+		channel.Links = append(channel.Links, rssLink{Href: feed.NextURL, Rel: "next"})
+	}
+
+	for _, item := range feed.Items {
+		entry := rssEntry{
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: item.Description,
+			Creator:     item.Author,
+			GUID:        rssGUID{IsPermaLink: "false", Value: item.ID},
+		}
+		if item.Content != "" {
+			entry.Content = &cdata{Value: item.Content}
+		}
+		if !item.Published.IsZero() {
+			entry.PubDate = item.Published.UTC().Format(time.RFC1123Z)
+		}
+		channel.Items = append(channel.Items, entry)
+	}
+
+	return rss{
+		Version:   "2.0",
+		AtomNS:    atomNamespace,
+		DublinNS:  "http://purl.org/dc/elements/1.1/",
+		ContentNS: "http://purl.org/rss/1.0/modules/content/",
+		Channel:   channel,
+	}
+}
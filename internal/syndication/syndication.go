@@ -0,0 +1,97 @@
+package syndication
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"time"
+)
+
+const (
+	FormatRSS  = "rss"
+	FormatAtom = "atom"
+	FormatJSON = "json"
+)
+
+var ErrUnknownFormat = errors.New("unknown feed format")
+
+// Feed is a page of articles re-published by the API. Next and Prev are empty
+// on the last and first pages.
+type Feed struct {
+	Title       string
+	Description string
+	HomeURL     string
+	SelfURL     string
+	FirstURL    string
+	NextURL     string
+	PrevURL     string
+	Updated     time.Time
+	Items       []Item
+}
+
+type Item struct {
+	ID          string
+	Title       string
+	Link        string
+	Description string
+	Content     string
+	Author      string
+	Published   time.Time
+}
+
+func ContentType(format string) (string, error) {
+	switch format {
+	case FormatRSS:
+		return "application/rss+xml; charset=utf-8", nil
+	case FormatAtom:
+		return "application/atom+xml; charset=utf-8", nil
+	case FormatJSON:
+		return "application/feed+json; charset=utf-8", nil
+	default:
+		return "", ErrUnknownFormat
+	}
+}
+
+func Write(w io.Writer, format string, feed Feed) error {
+	switch format {
+	case FormatRSS:
+		return writeXML(w, newRSS(feed))
+	case FormatAtom:
+		return writeXML(w, newAtom(feed))
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		return encoder.Encode(newJSONFeed(feed))
+	default:
+		return ErrUnknownFormat
+	}
+}
+
+func writeXML(w io.Writer, document interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// updated returns the feed date, falling back to its most recent item.
+func (f Feed) updated() time.Time {
+	updated := f.Updated
+	for _, item := range f.Items {
+		if item.Published.After(updated) {
+			updated = item.Published
+		}
+	}
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	return updated.UTC()
+}
@@ -0,0 +1,410 @@
+package websub
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"github.com/google/logger"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"github.com/matheusvidal21/smart-news-fetcher/pkg/utils"
+	"github.com/mmcdole/gofeed"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultLeaseDuration = 10 * 24 * time.Hour
+	defaultRenewInterval = 5 * time.Minute
+	maxRenewWindow       = 24 * time.Hour
+	retryInterval        = time.Hour
+	secretSize           = 32
+	callbackTokenSize    = 32
+	queueSize            = 256
+	CallbackPath         = "/websub/callback/"
+)
+
+var (
+	ErrUnknownSubscription = errors.New("unknown subscription")
+	ErrInvalidSignature    = errors.New("invalid X-Hub-Signature")
+)
+
+type Options struct {
+	CallbackBaseURL string
+	// LeaseDuration is the lease requested from hubs, and the longest lease
+	// accepted from them.
+	LeaseDuration time.Duration
+	RenewInterval time.Duration
+}
+
+type subscribeRequest struct {
+	sourceID int
+	hubURL   string
+	topicURL string
+}
+
+// Subscriber subscribes sources to the WebSub hubs advertised by their feeds and
+// ingests the content pushed by the hubs. It is disabled when no callback base
+// URL is configured, leaving every source to the polling scheduler.
+type Subscriber struct {
+	repository interfaces.WebSubRepositoryInterface
+	fetcher    interfaces.FetcherInterface
+	options    Options
+	requests   chan subscribeRequest
+	mu         sync.Mutex
+	queued     map[int]bool
+	quit       chan struct{}
+	wg         sync.WaitGroup
+	started    bool
+}
+
+func NewSubscriber(repository interfaces.WebSubRepositoryInterface, fetcher interfaces.FetcherInterface, options Options) *Subscriber {
+	if options.LeaseDuration <= 0 {
+		options.LeaseDuration = defaultLeaseDuration
+	}
+	if options.RenewInterval <= 0 {
+		options.RenewInterval = defaultRenewInterval
+	}
+	options.CallbackBaseURL = strings.TrimRight(options.CallbackBaseURL, "/")
+	return &Subscriber{
+		repository: repository,
+		fetcher:    fetcher,
+		options:    options,
+		requests:   make(chan subscribeRequest, queueSize),
+		queued:     make(map[int]bool),
+		quit:       make(chan struct{}),
+	}
+}
+
+func (s *Subscriber) enabled() bool {
+	return s.options.CallbackBaseURL != ""
+}
+
+func (s *Subscriber) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.enabled() || s.started {
+		return
+	}
+	s.started = true
+
+	s.wg.Add(2)
+	go s.renewLoop()
+	go s.subscribeLoop()
+	logger.Info("WebSub subscriber started with callback " + s.options.CallbackBaseURL + CallbackPath)
+}
+
+func (s *Subscriber) Stop() {
+	s.mu.Lock()
+	if !s.started {
+		s.mu.Unlock()
+		return
+	}
+	s.started = false
+	s.mu.Unlock()
+
+	close(s.quit)
+	s.wg.Wait()
+	logger.Info("WebSub subscriber stopped")
+}
+
+// Observe subscribes the source to its hub unless a subscription for the same
+// hub and topic is already active or was attempted recently.
+func (s *Subscriber) Observe(source models.Source, hubURL, topicURL string) {
+	if !s.enabled() {
+		return
+	}
+
+	current, err := s.repository.FindBySourceId(source.ID)
+	if err != nil && err != sql.ErrNoRows {
+		logger.Errorf("Failed to find WebSub subscription of source %d: %v", source.ID, err)
+		return
+	}
+	if err == nil && current.HubURL == hubURL && current.TopicURL == topicURL {
+		switch current.State {
+		case models.WebSubStateActive:
+			if time.Until(current.LeaseExpiresAt) > renewWindow(current) {
+				return
+			}
+		case models.WebSubStatePending, models.WebSubStateFailed, models.WebSubStateDenied:
+			if time.Since(current.UpdatedAt) < retryInterval {
+				return
+			}
+		}
+	}
+
+	s.enqueue(source.ID, hubURL, topicURL)
+}
+
+// enqueue hands the subscription request to the subscribe loop, so a slow hub
+// never holds up the fetch that found it. Requests for a source already queued
+// are dropped, and so are requests past a full queue: the next fetch asks again.
+func (s *Subscriber) enqueue(sourceID int, hubURL, topicURL string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.queued[sourceID] {
+		return
+	}
+
+	select {
+	case s.requests <- subscribeRequest{sourceID: sourceID, hubURL: hubURL, topicURL: topicURL}:
+		s.queued[sourceID] = true
+	default:
+		logger.Errorf("WebSub subscription queue is full, dropping request of source %d", sourceID)
+	}
+}
+
+func (s *Subscriber) subscribeLoop() {
+	defer s.wg.Done()
+	for {
+		select {
+		case request := <-s.requests:
+			s.subscribe(request.sourceID, request.hubURL, request.topicURL)
+			s.mu.Lock()
+			delete(s.queued, request.sourceID)
+			s.mu.Unlock()
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+func (s *Subscriber) subscribe(sourceID int, hubURL, topicURL string) {
+	secret, err := utils.RandomToken(secretSize)
+	if err != nil {
+		logger.Errorf("Failed to generate WebSub secret: %v", err)
+		return
+	}
+
+	subscription := models.WebSubSubscription{
+		SourceID: sourceID,
+		HubURL:   hubURL,
+		TopicURL: topicURL,
+		Secret:   secret,
+		State:    models.WebSubStatePending,
+	}
+	current, err := s.repository.FindBySourceId(sourceID)
+	if err == nil {
+		// Keep the callback so the hub renews its subscription instead of
+		// adding one, and keep the current lease so polling stays paused
+		// while a renewal is pending.
+		subscription.CallbackToken = current.CallbackToken
+		if current.State == models.WebSubStateActive && current.HubURL == hubURL && current.TopicURL == topicURL {
+			subscription.LeaseSeconds = current.LeaseSeconds
+			subscription.LeaseExpiresAt = current.LeaseExpiresAt
+		}
+	}
+	if subscription.CallbackToken == "" {
+		subscription.CallbackToken, err = utils.RandomToken(callbackTokenSize)
+		if err != nil {
+			logger.Errorf("Failed to generate WebSub callback token: %v", err)
+			return
+		}
+	}
+
+	err = s.repository.Save(subscription)
+	if err != nil {
+		logger.Errorf("Failed to save WebSub subscription of source %d: %v", sourceID, err)
+		return
+	}
+
+	form := url.Values{}
+	form.Set("hub.mode", "subscribe")
+	form.Set("hub.topic", topicURL)
+	form.Set("hub.callback", s.callbackURL(subscription))
+	form.Set("hub.secret", secret)
+	form.Set("hub.lease_seconds", strconv.Itoa(int(s.options.LeaseDuration.Seconds())))
+
+	// The request goes through the fetcher, so it uses the configured proxy,
+	// timeouts and User-Agent and respects the limits of the hub's host.
+	resp, err := s.fetcher.PostForm(hubURL, form)
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			err = errors.New("hub answered " + resp.Status)
+		}
+	}
+	if err != nil {
+		logger.Errorf("Failed to subscribe source %d to hub %s: %v", sourceID, hubURL, err)
+		subscription.State = models.WebSubStateFailed
+		if saveErr := s.repository.Save(subscription); saveErr != nil {
+			logger.Errorf("Failed to save WebSub subscription of source %d: %v", sourceID, saveErr)
+		}
+		return
+	}
+	logger.Info("WebSub subscription requested for source " + strconv.Itoa(sourceID) + " at " + hubURL)
+}
+
+// callbackURL carries the random token of the subscription, so only the hub
+// it was sent to can verify or push to it.
+func (s *Subscriber) callbackURL(subscription models.WebSubSubscription) string {
+	return s.options.CallbackBaseURL + CallbackPath + strconv.Itoa(subscription.SourceID) + "/" + subscription.CallbackToken
+}
+
+// findSubscription returns the subscription of the source when the callback
+// token matches it.
+func (s *Subscriber) findSubscription(sourceID int, token string) (models.WebSubSubscription, error) {
+	subscription, err := s.repository.FindBySourceId(sourceID)
+	if err != nil || subscription.CallbackToken == "" ||
+		subtle.ConstantTimeCompare([]byte(subscription.CallbackToken), []byte(token)) != 1 {
+		return models.WebSubSubscription{}, ErrUnknownSubscription
+	}
+	return subscription, nil
+}
+
+// IsActive reports whether the source receives pushes, in which case polling is
+// not needed. It turns false as soon as the lease ends.
+func (s *Subscriber) IsActive(sourceID int) bool {
+	if !s.enabled() {
+		return false
+	}
+	subscription, err := s.repository.FindBySourceId(sourceID)
+	if err != nil {
+		return false
+	}
+	return !subscription.LeaseExpiresAt.IsZero() && time.Now().Before(subscription.LeaseExpiresAt) &&
+		(subscription.State == models.WebSubStateActive || subscription.State == models.WebSubStatePending)
+}
+
+// Verify answers the intent verification of a hub, returning the challenge to
+// echo when the request matches the subscription. Only the answer to a pending
+// subscription request is accepted, since no unsubscription is ever requested,
+// and the lease granted is capped at the one requested.
+func (s *Subscriber) Verify(sourceID int, token, mode, topic, challenge string, leaseSeconds int) (string, error) {
+	subscription, err := s.findSubscription(sourceID, token)
+	if err != nil || subscription.TopicURL != topic || subscription.State != models.WebSubStatePending {
+		return "", ErrUnknownSubscription
+	}
+
+	switch mode {
+	case "subscribe":
+		maxLeaseSeconds := int(s.options.LeaseDuration.Seconds())
+		if leaseSeconds <= 0 || leaseSeconds > maxLeaseSeconds {
+			leaseSeconds = maxLeaseSeconds
+		}
+		subscription.State = models.WebSubStateActive
+		subscription.LeaseSeconds = leaseSeconds
+		subscription.LeaseExpiresAt = time.Now().Add(time.Duration(leaseSeconds) * time.Second)
+	case "denied":
+		subscription.State = models.WebSubStateDenied
+		subscription.LeaseExpiresAt = time.Time{}
+	default:
+		return "", errors.New("invalid hub.mode: " + mode)
+	}
+
+	err = s.repository.Save(subscription)
+	if err != nil {
+		return "", errors.New("failed to save subscription: " + err.Error())
+	}
+	logger.Info("WebSub " + mode + " verified for source " + strconv.Itoa(sourceID))
+	return challenge, nil
+}
+
+// Receive validates the signature of pushed content and stores its items
+// through the same pipeline used by polling.
+func (s *Subscriber) Receive(sourceID int, token, signature string, body []byte) (models.FetchStats, error) {
+	subscription, err := s.findSubscription(sourceID, token)
+	if err != nil || subscription.State != models.WebSubStateActive {
+		return models.FetchStats{}, ErrUnknownSubscription
+	}
+	if !validSignature(subscription.Secret, signature, body) {
+		return models.FetchStats{}, ErrInvalidSignature
+	}
+
+	feed, err := gofeed.NewParser().Parse(bytes.NewReader(body))
+	if err != nil {
+		return models.FetchStats{}, errors.New("failed to parse pushed content: " + err.Error())
+	}
+
+	stats := s.fetcher.FetchFeeds(sourceID, feed)
+	logger.Infof("Pushed content of source %d: %d new, %d updated, %d skipped, %d failed", sourceID, stats.New, stats.Updated, stats.Skipped, stats.Failed)
+	return stats, nil
+}
+
+func validSignature(secret, signature string, body []byte) bool {
+	algorithm, digest, found := strings.Cut(signature, "=")
+	if !found {
+		return false
+	}
+
+	var newHash func() hash.Hash
+	switch strings.ToLower(algorithm) {
+	case "sha1":
+		newHash = sha1.New
+	case "sha256":
+		newHash = sha256.New
+	case "sha384":
+		newHash = sha512.New384
+	case "sha512":
+		newHash = sha512.New
+	default:
+		return false
+	}
+
+	expected, err := hex.DecodeString(digest)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+func (s *Subscriber) renewLoop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.options.RenewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.renew()
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// renew resubscribes the leases about to end. Lapsed leases are marked expired
+// first so the scheduler polls the source until the hub verifies again.
+func (s *Subscriber) renew() {
+	subscriptions, err := s.repository.FindRenewable(time.Now().Add(maxRenewWindow))
+	if err != nil {
+		logger.Errorf("Failed to find WebSub subscriptions to renew: %v", err)
+		return
+	}
+
+	for _, subscription := range subscriptions {
+		if time.Until(subscription.LeaseExpiresAt) > renewWindow(subscription) {
+			continue
+		}
+		if !time.Now().Before(subscription.LeaseExpiresAt) {
+			subscription.State = models.WebSubStateExpired
+			if err := s.repository.Save(subscription); err != nil {
+				logger.Errorf("Failed to save WebSub subscription of source %d: %v", subscription.SourceID, err)
+			}
+			logger.Info("WebSub lease expired for source " + strconv.Itoa(subscription.SourceID))
+		}
+		s.enqueue(subscription.SourceID, subscription.HubURL, subscription.TopicURL)
+	}
+}
+
+// renewWindow is how long before the end of the lease it is renewed: a tenth of
+// the lease, at most a day.
+func renewWindow(subscription models.WebSubSubscription) time.Duration {
+	window := time.Duration(subscription.LeaseSeconds) * time.Second / 10
+	if window > maxRenewWindow {
+		window = maxRenewWindow
+	}
+	return window
+}
@@ -0,0 +1,363 @@
+package websub
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/interfaces"
+	"github.com/matheusvidal21/smart-news-fetcher/internal/models"
+	"github.com/mmcdole/gofeed"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testCallbackBase = "http://app.test"
+	testTopic        = "https://blog.test/feed.xml"
+	testFeed         = `<?xml version="1.0"?><rss version="2.0"><channel><title>Blog</title><item><title>Post</title><link>https://blog.test/post</link></item></channel></rss>`
+)
+
+type memoryRepository struct {
+	mu            sync.Mutex
+	subscriptions map[int]models.WebSubSubscription
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{subscriptions: make(map[int]models.WebSubSubscription)}
+}
+
+func (r *memoryRepository) FindBySourceId(sourceID int) (models.WebSubSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	subscription, ok := r.subscriptions[sourceID]
+	if !ok {
+		return models.WebSubSubscription{}, sql.ErrNoRows
+	}
+	return subscription, nil
+}
+
+func (r *memoryRepository) Save(subscription models.WebSubSubscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	subscription.UpdatedAt = time.Now()
+	r.subscriptions[subscription.SourceID] = subscription
+	return nil
+}
+
+func (r *memoryRepository) FindRenewable(before time.Time) ([]models.WebSubSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var subscriptions []models.WebSubSubscription
+	for _, subscription := range r.subscriptions {
+		if subscription.State == models.WebSubStateActive && subscription.LeaseExpiresAt.Before(before) {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	return subscriptions, nil
+}
+
+type recordingFetcher struct {
+	interfaces.FetcherInterface
+	mu     sync.Mutex
+	pushed []int
+	posted []string
+}
+
+func (f *recordingFetcher) PostForm(target string, form url.Values) (*http.Response, error) {
+	f.mu.Lock()
+	f.posted = append(f.posted, target)
+	f.mu.Unlock()
+	return http.PostForm(target, form)
+}
+
+func (f *recordingFetcher) FetchFeeds(id int, feed *gofeed.Feed) models.FetchStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pushed = append(f.pushed, id)
+	return models.FetchStats{New: len(feed.Items)}
+}
+
+// testHub stands in for a WebSub hub, recording the subscription requests it
+// receives and answering them with the given status.
+type testHub struct {
+	server   *httptest.Server
+	requests chan url.Values
+	status   int
+}
+
+func newTestHub(t *testing.T, status int) *testHub {
+	hub := &testHub{requests: make(chan url.Values, 10), status: status}
+	hub.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("hub received an invalid form: %v", err)
+		}
+		w.WriteHeader(hub.status)
+		hub.requests <- r.PostForm
+	}))
+	t.Cleanup(hub.server.Close)
+	return hub
+}
+
+func (h *testHub) nextRequest(t *testing.T) url.Values {
+	t.Helper()
+	select {
+	case form := <-h.requests:
+		return form
+	case <-time.After(5 * time.Second):
+		t.Fatal("hub received no subscription request")
+		return nil
+	}
+}
+
+func newTestSubscriber(t *testing.T, repository *memoryRepository, fetcher interfaces.FetcherInterface) *Subscriber {
+	subscriber := NewSubscriber(repository, fetcher, Options{
+		CallbackBaseURL: testCallbackBase,
+		LeaseDuration:   time.Hour,
+		RenewInterval:   time.Hour,
+	})
+	subscriber.Start()
+	t.Cleanup(subscriber.Stop)
+	return subscriber
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func callbackToken(t *testing.T, form url.Values, sourceID string) string {
+	t.Helper()
+	prefix := testCallbackBase + CallbackPath + sourceID + "/"
+	callback := form.Get("hub.callback")
+	if !strings.HasPrefix(callback, prefix) || len(callback) == len(prefix) {
+		t.Fatalf("callback %q does not carry a token under %q", callback, prefix)
+	}
+	return strings.TrimPrefix(callback, prefix)
+}
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyOnlyAcceptsPendingRequests(t *testing.T) {
+	hub := newTestHub(t, http.StatusAccepted)
+	repository := newMemoryRepository()
+	fetcher := &recordingFetcher{}
+	subscriber := newTestSubscriber(t, repository, fetcher)
+
+	subscriber.Observe(models.Source{ID: 1, Url: testTopic}, hub.server.URL, testTopic)
+	form := hub.nextRequest(t)
+	if form.Get("hub.mode") != "subscribe" || form.Get("hub.topic") != testTopic || form.Get("hub.secret") == "" {
+		t.Fatalf("unexpected subscription request: %v", form)
+	}
+	fetcher.mu.Lock()
+	if len(fetcher.posted) != 1 || fetcher.posted[0] != hub.server.URL {
+		t.Errorf("subscription requests sent through the fetcher = %v, want the hub", fetcher.posted)
+	}
+	fetcher.mu.Unlock()
+	token := callbackToken(t, form, "1")
+	waitFor(t, func() bool {
+		subscription, err := repository.FindBySourceId(1)
+		return err == nil && subscription.State == models.WebSubStatePending
+	})
+
+	tests := []struct {
+		name  string
+		token string
+		mode  string
+		topic string
+	}{
+		{"missing token", "", "subscribe", testTopic},
+		{"wrong token", strings.Repeat("0", len(token)), "subscribe", testTopic},
+		{"wrong topic", token, "subscribe", "https://other.test/feed.xml"},
+		{"unsolicited unsubscribe", token, "unsubscribe", testTopic},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := subscriber.Verify(1, tt.token, tt.mode, tt.topic, "challenge", 60); err == nil {
+				t.Fatal("verification accepted")
+			}
+			if subscriber.IsActive(1) {
+				t.Fatal("subscription became active")
+			}
+		})
+	}
+
+	challenge, err := subscriber.Verify(1, token, "subscribe", testTopic, "challenge", int((30 * 24 * time.Hour).Seconds()))
+	if err != nil || challenge != "challenge" {
+		t.Fatalf("Verify() = %q, %v", challenge, err)
+	}
+	subscription, _ := repository.FindBySourceId(1)
+	if subscription.State != models.WebSubStateActive || subscription.LeaseSeconds != int(time.Hour.Seconds()) {
+		t.Fatalf("subscription = %+v, want active with the lease capped at an hour", subscription)
+	}
+	if !subscriber.IsActive(1) {
+		t.Fatal("IsActive() = false after verification")
+	}
+
+	// Nothing is pending anymore, so replays and denials are refused.
+	if _, err := subscriber.Verify(1, token, "subscribe", testTopic, "again", 60); err == nil {
+		t.Fatal("replayed verification accepted")
+	}
+	if _, err := subscriber.Verify(1, token, "denied", testTopic, "", 0); err == nil {
+		t.Fatal("unsolicited denial accepted")
+	}
+	if !subscriber.IsActive(1) {
+		t.Fatal("refused requests changed the subscription")
+	}
+}
+
+func TestReceiveRejectsInvalidSignatures(t *testing.T) {
+	repository := newMemoryRepository()
+	fetcher := &recordingFetcher{}
+	subscriber := newTestSubscriber(t, repository, fetcher)
+	repository.Save(models.WebSubSubscription{
+		SourceID:       1,
+		TopicURL:       testTopic,
+		Secret:         "secret",
+		CallbackToken:  "token",
+		State:          models.WebSubStateActive,
+		LeaseSeconds:   3600,
+		LeaseExpiresAt: time.Now().Add(time.Hour),
+	})
+	body := []byte(testFeed)
+
+	tests := []struct {
+		name      string
+		token     string
+		signature string
+		want      error
+	}{
+		{"missing signature", "token", "", ErrInvalidSignature},
+		{"wrong secret", "token", sign("other", body), ErrInvalidSignature},
+		{"unknown algorithm", "token", "md5=" + strings.Repeat("0", 32), ErrInvalidSignature},
+		{"wrong token", "other", sign("secret", body), ErrUnknownSubscription},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := subscriber.Receive(1, tt.token, tt.signature, body); err != tt.want {
+				t.Fatalf("Receive() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if len(fetcher.pushed) != 0 {
+		t.Fatalf("rejected content was stored: %v", fetcher.pushed)
+	}
+
+	stats, err := subscriber.Receive(1, "token", sign("secret", body), body)
+	if err != nil || stats.New != 1 || len(fetcher.pushed) != 1 {
+		t.Fatalf("Receive() = %+v, %v, pushed %v", stats, err, fetcher.pushed)
+	}
+}
+
+func TestRenewKeepsLeaseAndCallback(t *testing.T) {
+	hub := newTestHub(t, http.StatusAccepted)
+	repository := newMemoryRepository()
+	subscriber := newTestSubscriber(t, repository, &recordingFetcher{})
+	lease := time.Now().Add(time.Minute)
+	repository.Save(models.WebSubSubscription{
+		SourceID:       1,
+		HubURL:         hub.server.URL,
+		TopicURL:       testTopic,
+		Secret:         "secret",
+		CallbackToken:  "token",
+		State:          models.WebSubStateActive,
+		LeaseSeconds:   3600,
+		LeaseExpiresAt: lease,
+	})
+
+	subscriber.renew()
+	form := hub.nextRequest(t)
+	if token := callbackToken(t, form, "1"); token != "token" {
+		t.Fatalf("renewal changed the callback token to %q", token)
+	}
+	waitFor(t, func() bool {
+		subscription, _ := repository.FindBySourceId(1)
+		return subscription.State == models.WebSubStatePending
+	})
+
+	subscription, _ := repository.FindBySourceId(1)
+	if !subscription.LeaseExpiresAt.Equal(lease) || !subscriber.IsActive(1) {
+		t.Fatalf("renewal dropped the current lease: %+v", subscription)
+	}
+	if _, err := subscriber.Verify(1, "token", "subscribe", testTopic, "challenge", 3600); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	subscription, _ = repository.FindBySourceId(1)
+	if !subscription.LeaseExpiresAt.After(lease) {
+		t.Fatalf("lease was not extended: %+v", subscription)
+	}
+}
+
+func TestFallbackToPolling(t *testing.T) {
+	t.Run("hub refuses the subscription", func(t *testing.T) {
+		hub := newTestHub(t, http.StatusInternalServerError)
+		repository := newMemoryRepository()
+		subscriber := newTestSubscriber(t, repository, &recordingFetcher{})
+
+		subscriber.Observe(models.Source{ID: 1, Url: testTopic}, hub.server.URL, testTopic)
+		hub.nextRequest(t)
+		waitFor(t, func() bool {
+			subscription, _ := repository.FindBySourceId(1)
+			return subscription.State == models.WebSubStateFailed
+		})
+		if subscriber.IsActive(1) {
+			t.Fatal("IsActive() = true after the hub refused")
+		}
+	})
+
+	t.Run("lease lapses", func(t *testing.T) {
+		hub := newTestHub(t, http.StatusInternalServerError)
+		repository := newMemoryRepository()
+		subscriber := newTestSubscriber(t, repository, &recordingFetcher{})
+		repository.Save(models.WebSubSubscription{
+			SourceID:       1,
+			HubURL:         hub.server.URL,
+			TopicURL:       testTopic,
+			CallbackToken:  "token",
+			State:          models.WebSubStateActive,
+			LeaseSeconds:   3600,
+			LeaseExpiresAt: time.Now().Add(-time.Second),
+		})
+		if subscriber.IsActive(1) {
+			t.Fatal("IsActive() = true with a lapsed lease")
+		}
+
+		subscriber.renew()
+		hub.nextRequest(t)
+		waitFor(t, func() bool {
+			subscription, _ := repository.FindBySourceId(1)
+			return subscription.State == models.WebSubStateFailed
+		})
+		if subscriber.IsActive(1) {
+			t.Fatal("IsActive() = true after a failed renewal")
+		}
+	})
+
+	t.Run("subscriber disabled", func(t *testing.T) {
+		repository := newMemoryRepository()
+		subscriber := NewSubscriber(repository, &recordingFetcher{}, Options{})
+		repository.Save(models.WebSubSubscription{
+			SourceID:       1,
+			State:          models.WebSubStateActive,
+			LeaseExpiresAt: time.Now().Add(time.Hour),
+		})
+		if subscriber.IsActive(1) {
+			t.Fatal("IsActive() = true without a callback URL")
+		}
+	})
+}
@@ -1,13 +1,40 @@
 package utils
 
-import "time"
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
 
 type date []byte
 
+// ParseTime parses a DATETIME or TIMESTAMP column. NULL and zero dates give the
+// zero time.
 func ParseTime(d date) (time.Time, error) {
+	if len(d) == 0 || strings.HasPrefix(string(d), "0000-00-00") {
+		return time.Time{}, nil
+	}
 	data, err := time.Parse("2006-01-02 15:04:05", string(d))
 	if err != nil {
-		return time.Time{}, nil
+		return time.Time{}, err
 	}
 	return data, nil
 }
+
+// RandomToken returns a hex encoded random token of the given number of bytes.
+func RandomToken(size int) (string, error) {
+	buffer := make([]byte, size)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buffer), nil
+}
+
+// HashToken returns the hex encoded SHA-256 of a token, to store secrets that
+// are looked up but never shown again.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
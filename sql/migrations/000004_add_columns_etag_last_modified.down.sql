@@ -0,0 +1,2 @@
+ALTER TABLE sources DROP COLUMN etag;
+ALTER TABLE sources DROP COLUMN last_modified;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE sources ADD COLUMN etag VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE sources ADD COLUMN last_modified VARCHAR(64) NOT NULL DEFAULT '';
\ No newline at end of file
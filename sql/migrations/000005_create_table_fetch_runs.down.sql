@@ -0,0 +1 @@
+DROP TABLE IF EXISTS fetch_runs;
\ No newline at end of file
@@ -0,0 +1,13 @@
+CREATE TABLE fetch_runs (
+                        id INT AUTO_INCREMENT PRIMARY KEY,
+                        source_id INT NOT NULL,
+                        started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                        duration_ms INT NOT NULL DEFAULT 0,
+                        http_status INT NOT NULL DEFAULT 0,
+                        items_seen INT NOT NULL DEFAULT 0,
+                        items_inserted INT NOT NULL DEFAULT 0,
+                        items_updated INT NOT NULL DEFAULT 0,
+                        error_message VARCHAR(1024) NOT NULL DEFAULT '',
+                        INDEX idx_fetch_runs_source_started (source_id, started_at),
+                        FOREIGN KEY (source_id) REFERENCES sources(id) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+ALTER TABLE sources DROP COLUMN consecutive_failures;
+ALTER TABLE sources DROP COLUMN disabled;
+ALTER TABLE sources DROP COLUMN last_error;
\ No newline at end of file
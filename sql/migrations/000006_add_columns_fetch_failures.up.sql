@@ -0,0 +1,3 @@
+ALTER TABLE sources ADD COLUMN consecutive_failures INT NOT NULL DEFAULT 0;
+ALTER TABLE sources ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE sources ADD COLUMN last_error VARCHAR(1024) NOT NULL DEFAULT '';
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE sources DROP COLUMN fetch_status;
\ No newline at end of file
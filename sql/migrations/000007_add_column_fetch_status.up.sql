@@ -0,0 +1 @@
+ALTER TABLE sources ADD COLUMN fetch_status VARCHAR(32) NOT NULL DEFAULT '';
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE sources DROP COLUMN category;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE sources ADD COLUMN category VARCHAR(255) NOT NULL DEFAULT '';
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN feed_token VARCHAR(64) NOT NULL DEFAULT '';
\ No newline at end of file
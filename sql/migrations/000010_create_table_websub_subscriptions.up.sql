@@ -0,0 +1,12 @@
+CREATE TABLE websub_subscriptions (
+                        source_id INT PRIMARY KEY,
+                        hub_url TEXT NOT NULL,
+                        topic_url TEXT NOT NULL,
+                        secret VARCHAR(64) NOT NULL,
+                        state VARCHAR(20) NOT NULL DEFAULT 'pending',
+                        lease_seconds INT NOT NULL DEFAULT 0,
+                        lease_expires_at TIMESTAMP NULL DEFAULT NULL,
+                        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                        INDEX idx_websub_subscriptions_state_lease (state, lease_expires_at),
+                        FOREIGN KEY (source_id) REFERENCES sources(id) ON DELETE CASCADE
+);
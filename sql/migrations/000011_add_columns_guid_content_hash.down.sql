@@ -0,0 +1 @@
+ALTER TABLE articles DROP COLUMN guid, DROP COLUMN content_hash;
@@ -0,0 +1 @@
+ALTER TABLE articles ADD COLUMN guid VARCHAR(1024) NOT NULL DEFAULT '', ADD COLUMN content_hash CHAR(64) NOT NULL DEFAULT '';
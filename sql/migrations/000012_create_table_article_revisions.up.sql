@@ -0,0 +1,14 @@
+CREATE TABLE article_revisions (
+                        id INT AUTO_INCREMENT PRIMARY KEY,
+                        article_id VARCHAR(36) NOT NULL,
+                        title TEXT NOT NULL,
+                        description TEXT,
+                        content TEXT,
+                        link TEXT NOT NULL,
+                        author TEXT,
+                        pub_date TIMESTAMP NULL DEFAULT NULL,
+                        content_hash CHAR(64) NOT NULL DEFAULT '',
+                        revised_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                        INDEX idx_article_revisions_article_revised (article_id, revised_at),
+                        FOREIGN KEY (article_id) REFERENCES articles(id) ON DELETE CASCADE ON UPDATE CASCADE
+);
@@ -0,0 +1 @@
+ALTER TABLE articles DROP COLUMN pub_date_source;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE articles ADD COLUMN pub_date_source VARCHAR(16) NOT NULL DEFAULT '';
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE articles DROP COLUMN plain_text;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE articles ADD COLUMN plain_text MEDIUMTEXT NOT NULL;
\ No newline at end of file
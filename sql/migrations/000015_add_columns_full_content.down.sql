@@ -0,0 +1,3 @@
+ALTER TABLE articles DROP COLUMN extracted_at;
+ALTER TABLE articles DROP COLUMN extracted_content;
+ALTER TABLE sources DROP COLUMN full_content;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE sources ADD COLUMN full_content BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE articles ADD COLUMN extracted_content MEDIUMTEXT NOT NULL;
+ALTER TABLE articles ADD COLUMN extracted_at TIMESTAMP NULL;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE articles DROP INDEX ft_articles_search;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE articles ADD FULLTEXT INDEX ft_articles_search (title, plain_text);
\ No newline at end of file
@@ -0,0 +1,13 @@
+CREATE TABLE saved_searches (
+                        id INT AUTO_INCREMENT PRIMARY KEY,
+                        user_id INT NOT NULL,
+                        name VARCHAR(100) NOT NULL,
+                        query VARCHAR(255) NOT NULL DEFAULT '',
+                        source_ids VARCHAR(255) NOT NULL DEFAULT '',
+                        author VARCHAR(255) NOT NULL DEFAULT '',
+                        days INT NOT NULL DEFAULT 0,
+                        in_newsletter BOOLEAN NOT NULL DEFAULT FALSE,
+                        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                        INDEX idx_saved_searches_user (user_id),
+                        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
\ No newline at end of file
@@ -0,0 +1,14 @@
+CREATE TABLE user_article_state (
+                        user_id INT NOT NULL,
+                        article_id VARCHAR(36) NOT NULL,
+                        is_read BOOLEAN NOT NULL DEFAULT FALSE,
+                        read_at TIMESTAMP NULL DEFAULT NULL,
+                        is_starred BOOLEAN NOT NULL DEFAULT FALSE,
+                        starred_at TIMESTAMP NULL DEFAULT NULL,
+                        is_archived BOOLEAN NOT NULL DEFAULT FALSE,
+                        archived_at TIMESTAMP NULL DEFAULT NULL,
+                        PRIMARY KEY (user_id, article_id),
+                        INDEX idx_user_article_state_article (article_id),
+                        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+                        FOREIGN KEY (article_id) REFERENCES articles(id) ON DELETE CASCADE ON UPDATE CASCADE
+);
@@ -0,0 +1,2 @@
+DROP TABLE user_sessions;
+ALTER TABLE users DROP COLUMN token_version;
\ No newline at end of file
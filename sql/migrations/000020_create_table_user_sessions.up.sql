@@ -0,0 +1,18 @@
+ALTER TABLE users ADD COLUMN token_version INT NOT NULL DEFAULT 0;
+CREATE TABLE user_sessions (
+                        id INT AUTO_INCREMENT PRIMARY KEY,
+                        user_id INT NOT NULL,
+                        token_hash CHAR(64) NOT NULL,
+                        previous_token_hash CHAR(64) NOT NULL DEFAULT '',
+                        token_version INT NOT NULL,
+                        user_agent VARCHAR(255) NOT NULL DEFAULT '',
+                        ip VARCHAR(45) NOT NULL DEFAULT '',
+                        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                        last_used_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                        expires_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                        revoked_at TIMESTAMP NULL DEFAULT NULL,
+                        UNIQUE INDEX idx_user_sessions_token (token_hash),
+                        INDEX idx_user_sessions_previous_token (previous_token_hash),
+                        INDEX idx_user_sessions_user (user_id),
+                        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
\ No newline at end of file
@@ -0,0 +1,11 @@
+ALTER TABLE users ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE TABLE user_tokens (
+                        id CHAR(32) PRIMARY KEY,
+                        user_id INT NOT NULL,
+                        purpose VARCHAR(32) NOT NULL,
+                        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                        expires_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                        used_at TIMESTAMP NULL DEFAULT NULL,
+                        INDEX idx_user_tokens_user (user_id, purpose),
+                        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
\ No newline at end of file
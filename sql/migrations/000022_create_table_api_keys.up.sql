@@ -0,0 +1,15 @@
+CREATE TABLE api_keys (
+                        id INT AUTO_INCREMENT PRIMARY KEY,
+                        user_id INT NOT NULL,
+                        name VARCHAR(100) NOT NULL,
+                        prefix VARCHAR(16) NOT NULL,
+                        key_hash CHAR(64) NOT NULL,
+                        scopes VARCHAR(255) NOT NULL DEFAULT '',
+                        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                        expires_at TIMESTAMP NULL DEFAULT NULL,
+                        last_used_at TIMESTAMP NULL DEFAULT NULL,
+                        revoked_at TIMESTAMP NULL DEFAULT NULL,
+                        UNIQUE INDEX idx_api_keys_hash (key_hash),
+                        INDEX idx_api_keys_user (user_id),
+                        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
\ No newline at end of file
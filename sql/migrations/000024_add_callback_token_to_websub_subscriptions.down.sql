@@ -0,0 +1 @@
+ALTER TABLE websub_subscriptions DROP COLUMN callback_token;
\ No newline at end of file
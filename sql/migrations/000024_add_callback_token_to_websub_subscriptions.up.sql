@@ -0,0 +1 @@
+ALTER TABLE websub_subscriptions ADD COLUMN callback_token VARCHAR(64) NOT NULL DEFAULT '';
\ No newline at end of file